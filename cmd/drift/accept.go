@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/comparator"
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func acceptCmd() *cobra.Command {
+	var items []string
+	var reason string
+	var expires string
+
+	cmd := &cobra.Command{
+		Use:   "accept <report>",
+		Short: "Mark drifts from a report as expected",
+		Long: `Record drifts from a JSON drift report as accepted. compare, diff and
+daemon then report them as accepted instead of active, until the acceptance
+expires or the value changes again.
+
+Items are given by their ID (or the start of it) or as category:name, for
+example
+  --item 3f2a9c1b --item package:dpkg:openssl --item file:/etc/hosts`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(items) == 0 {
+				return fmt.Errorf("at least one --item is required")
+			}
+			if reason == "" {
+				return fmt.Errorf("--reason is required")
+			}
+
+			var until time.Time
+			if expires != "" {
+				var err error
+				if until, err = parseExpiry(expires, time.Now()); err != nil {
+					return err
+				}
+			}
+
+			config := loadConfig()
+			report, err := loadReport(args[0])
+			if err != nil {
+				return fmt.Errorf("loading report: %w", err)
+			}
+
+			accepted, err := loadAcceptances(config)
+			if err != nil {
+				return err
+			}
+
+			by := ""
+			if u, err := user.Current(); err == nil {
+				by = u.Username
+			}
+
+			for _, item := range items {
+				found := false
+				for _, drift := range report.Drifts {
+					if !matchesItem(drift, item) {
+						continue
+					}
+					found = true
+
+					a := comparator.NewAcceptance(drift)
+					a.Reason = reason
+					a.AcceptedBy = by
+					a.AcceptedAt = time.Now().UTC()
+					a.Expires = until
+					accepted = replaceAcceptance(accepted, a)
+
+					fmt.Printf("Accepted %s %s %s (%s)\n", drift.Type, drift.Category, drift.Name, reason)
+				}
+				if !found {
+					return fmt.Errorf("no drift %q in %s", item, args[0])
+				}
+			}
+
+			return saveAcceptances(config, accepted)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&items, "item", nil, "drift to accept, by ID or as category:name (repeatable)")
+	cmd.Flags().StringVar(&reason, "reason", "", "why the drift is expected, such as a change ticket")
+	cmd.Flags().StringVar(&expires, "expires", "", "date (2026-12-01) or age (30d) after which the drift is active again")
+
+	return cmd
+}
+
+// matchesItem reports whether drift is the one an --item names
+func matchesItem(drift models.DriftItem, item string) bool {
+	if category, name, ok := strings.Cut(item, ":"); ok {
+		return drift.Category == category && drift.Name == name
+	}
+	return drift.ID != "" && strings.HasPrefix(drift.ID, item)
+}
+
+// parseExpiry takes a date, or an age counted from now
+func parseExpiry(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.UTC(), nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q, expected a date (2006-01-02) or an age (30d)", s)
+	}
+	return now.Add(age).UTC(), nil
+}
+
+// replaceAcceptance adds a, dropping an older acceptance of the same drift
+func replaceAcceptance(accepted []models.Acceptance, a models.Acceptance) []models.Acceptance {
+	kept := accepted[:0]
+	for _, old := range accepted {
+		if old.Category != a.Category || old.Name != a.Name || old.Type != a.Type {
+			kept = append(kept, old)
+		}
+	}
+	return append(kept, a)
+}
+
+func loadReport(path string) (*models.DriftReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.DriftReport
+	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		err = yaml.Unmarshal(data, &report)
+	} else {
+		err = json.Unmarshal(data, &report)
+	}
+	return &report, err
+}
+
+type acceptanceFile struct {
+	Accepted []models.Acceptance `yaml:"accepted"`
+}
+
+// loadAcceptances reads the acceptance file; a missing one accepts nothing
+func loadAcceptances(config *Config) ([]models.Acceptance, error) {
+	if config.Acceptance.Path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(config.Acceptance.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file acceptanceFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", config.Acceptance.Path, err)
+	}
+	return file.Accepted, nil
+}
+
+func saveAcceptances(config *Config, accepted []models.Acceptance) error {
+	path := config.Acceptance.Path
+	if path == "" {
+		return fmt.Errorf("acceptance.path is not configured")
+	}
+
+	data, err := yaml.Marshal(acceptanceFile{Accepted: accepted})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
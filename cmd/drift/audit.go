@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/AshitomW/Drifty/internal/audit"
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/reporter"
+	"github.com/spf13/cobra"
+)
+
+func auditCmd() *cobra.Command {
+	var suppress []string
+	var list bool
+	var root string
+	var ociImage string
+
+	cmd := &cobra.Command{
+		Use:   "audit [snapshot]",
+		Short: "Run the built-in hardening checks",
+		Long: `Run the bundled Linux hardening checks against a snapshot, given as a file
+path or store reference, or against the current host when none is given.
+
+Checks are suppressed by ID, or a pattern such as "sysctl.*", with
+--suppress or audit.suppress in the configuration file. --list shows them
+all. The exit code is 2 when a critical check fails and 1 when any fails.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return listAuditChecks()
+			}
+
+			config := loadConfig()
+			if root != "" {
+				config.Collector.Root = root
+			}
+
+			var snapshot *models.EnvironmentSnapshot
+			var err error
+			if len(args) == 1 {
+				snapshot, err = resolveSnapshot(config, args[0])
+				if err != nil {
+					return fmt.Errorf("loading snapshot: %w", err)
+				}
+			} else {
+				snapshot, err = collectSnapshot(context.Background(), config, "current", ociImage)
+				if err != nil {
+					return err
+				}
+			}
+
+			report, err := audit.Run(snapshot, append(config.Audit.Suppress, suppress...))
+			if err != nil {
+				return err
+			}
+
+			rep := reporter.New(reporter.Format(outputFormat), os.Stdout)
+			if err := rep.Generate(report); err != nil {
+				return err
+			}
+
+			if report.Summary.CriticalCount > 0 {
+				os.Exit(2)
+			}
+			if report.HasDrift {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&suppress, "suppress", nil, "check ID or pattern to skip (repeatable)")
+	cmd.Flags().BoolVar(&list, "list", false, "list the checks and exit")
+	cmd.Flags().StringVar(&root, "root", "", "audit a filesystem tree mounted here instead of the running host")
+	cmd.Flags().StringVar(&ociImage, "oci-image", "", "audit a saved image (OCI layout or docker save archive)")
+	cmd.MarkFlagsMutuallyExclusive("root", "oci-image")
+
+	return cmd
+}
+
+func listAuditChecks() error {
+	fmt.Printf("Hardening checks v%s\n\n", audit.Version)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tSEVERITY\tCHECK\n")
+	for _, check := range audit.Checks() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.ID, check.Severity, check.Title)
+	}
+	return w.Flush()
+}
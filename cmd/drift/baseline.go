@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/fleet"
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func baselineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Build baselines from the snapshots of many hosts",
+	}
+
+	cmd.AddCommand(baselineBuildCmd())
+
+	return cmd
+}
+
+func baselineBuildCmd() *cobra.Command {
+	var from []string
+	var strategy string
+	var exclude []string
+	var name string
+	var outputPath string
+	var format string
+	var save bool
+	var labels []string
+
+	cmd := &cobra.Command{
+		Use:   "build --from <snapshot> --from <snapshot>...",
+		Short: "Build a snapshot of what a group of hosts agree on",
+		Long: `Build a new snapshot holding the state a group of hosts share, to compare
+hosts against with diff or daemon instead of picking one server as the good
+one. Snapshots are given with --from or as arguments, as file paths or store
+references.
+
+With --strategy majority an item is kept with the value more than half of
+the hosts have. With --strategy intersection it is kept only when every host
+has it the same.
+
+Hostnames, MAC and IP addresses are never kept, nor are items that differ on
+every host by design, such as /etc/machine-id. More can be left out with
+--exclude or baseline.exclude in the configuration file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := loadConfig()
+
+			refs := append(append([]string(nil), from...), args...)
+			hosts := make([]fleet.Host, 0, len(refs))
+			for _, ref := range refs {
+				snapshot, err := resolveSnapshot(config, ref)
+				if err != nil {
+					return fmt.Errorf("loading snapshot %s: %w", ref, err)
+				}
+				hosts = append(hosts, fleet.Host{Snapshot: snapshot})
+			}
+			nameHosts(hosts, refs)
+
+			snapshotLabels, err := parseLabels(labels)
+			if err != nil {
+				return err
+			}
+
+			exclusions := append(fleet.DefaultExclusions(), config.Baseline.Exclude...)
+			for _, e := range exclude {
+				exclusions = append(exclusions, parseExclusion(e))
+			}
+
+			baseline, stats, err := fleet.Baseline(hosts, strategy, exclusions)
+			if err != nil {
+				return err
+			}
+			baseline.Name = name
+			if len(snapshotLabels) > 0 {
+				baseline.Labels = snapshotLabels
+			}
+			fmt.Fprintf(os.Stderr, "Baseline from %d hosts: %d items kept, %d left out because the hosts disagree, %d excluded\n",
+				len(hosts), stats.Kept, stats.Dropped, stats.Excluded)
+
+			if save {
+				st, err := openStore(config)
+				if err != nil {
+					return err
+				}
+				entry, err := st.Save(baseline)
+				if err != nil {
+					return fmt.Errorf("saving baseline: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Saved snapshot %s\n", entry.ID)
+
+				if outputPath == "" {
+					return nil
+				}
+			}
+
+			output := os.Stdout
+			if outputPath != "" {
+				f, err := os.Create(outputPath)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				output = f
+			}
+
+			return writeSnapshot(baseline, format, output)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&from, "from", nil, "snapshot to build from (repeatable)")
+	cmd.Flags().StringVar(&strategy, "strategy", fleet.Majority, "which items to keep (majority, intersection)")
+	cmd.Flags().StringArrayVar(&exclude, "exclude", nil, "item to leave out, as name or category:name, names may be patterns (repeatable)")
+	cmd.Flags().StringVarP(&name, "name", "n", "baseline", "snapshot name")
+	cmd.Flags().StringVarP(&outputPath, "file", "f", "", "output file path")
+	cmd.Flags().StringVarP(&format, "format", "F", "json", "output format (json, yaml, table)")
+	cmd.Flags().BoolVarP(&save, "save", "s", false, "save the baseline to the configured store")
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "label the baseline (key=value, repeatable)")
+
+	return cmd
+}
+
+// parseExclusion reads "category:name", or a bare name for any category.
+// Names hold colons too, like "sysctl:vm.swappiness", so only a known
+// category counts as one.
+func parseExclusion(s string) models.Exclusion {
+	category, name, ok := strings.Cut(s, ":")
+	if !ok || !fleet.IsCategory(category) {
+		return models.Exclusion{Name: s}
+	}
+	return models.Exclusion{Category: category, Name: name}
+}
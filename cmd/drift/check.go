@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/policy"
+	"github.com/AshitomW/Drifty/internal/reporter"
+	"github.com/spf13/cobra"
+)
+
+func checkCmd() *cobra.Command {
+	var policyFile string
+
+	cmd := &cobra.Command{
+		Use:   "check --policy <policy> <snapshot>",
+		Short: "Check a snapshot against a policy",
+		Long: `Check a snapshot, given as a file path or store reference, against the
+desired state declared in a policy file. Every check is reported as a pass or
+a fail; the exit code is 2 when a critical check fails and 1 when any fails.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := loadConfig()
+
+			p, err := policy.Load(policyFile)
+			if err != nil {
+				return fmt.Errorf("loading policy: %w", err)
+			}
+
+			snapshot, err := resolveSnapshot(config, args[0])
+			if err != nil {
+				return fmt.Errorf("loading snapshot: %w", err)
+			}
+
+			report := p.Evaluate(snapshot, time.Now())
+
+			rep := reporter.New(reporter.Format(outputFormat), os.Stdout)
+			if err := rep.Generate(report); err != nil {
+				return err
+			}
+
+			if report.Summary.CriticalCount > 0 {
+				os.Exit(2)
+			}
+			if report.HasDrift {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&policyFile, "policy", "p", "", "policy file")
+	cmd.MarkFlagRequired("policy")
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AshitomW/Drifty/internal/export"
+	"github.com/spf13/cobra"
+)
+
+func exportCmd() *cobra.Command {
+	var as string
+	var outputPath string
+
+	cmd := &cobra.Command{
+		Use:   "export --as <format> <snapshot>",
+		Short: "Write a snapshot out as a desired-state definition",
+		Long: `Turn a snapshot, given as a file path or store reference, into a definition
+new hosts can be built from: an Ansible playbook, a Salt state file, or a
+policy for drift check. It covers packages at their versions, services,
+users and groups, cron jobs, and file permissions and owners. File content
+isn't in a snapshot, so files are only adjusted, never written.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := loadConfig()
+
+			snapshot, err := resolveSnapshot(config, args[0])
+			if err != nil {
+				return fmt.Errorf("loading snapshot: %w", err)
+			}
+
+			state := export.From(snapshot)
+
+			var write func(io.Writer) error
+			switch as {
+			case "ansible":
+				write = state.Ansible
+			case "salt":
+				write = state.Salt
+			case "policy":
+				write = state.Policy
+			default:
+				return fmt.Errorf("unknown format %q, expected ansible, salt or policy", as)
+			}
+
+			output := os.Stdout
+			if outputPath != "" {
+				f, err := os.Create(outputPath)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				output = f
+			}
+
+			return write(output)
+		},
+	}
+
+	cmd.Flags().StringVar(&as, "as", "", "definition to write (ansible, salt, policy)")
+	cmd.Flags().StringVarP(&outputPath, "file", "f", "", "output file path")
+	cmd.MarkFlagRequired("as")
+
+	return cmd
+}
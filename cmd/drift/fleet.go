@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/fleet"
+	"github.com/AshitomW/Drifty/internal/reporter"
+	"github.com/spf13/cobra"
+)
+
+func fleetCmd() *cobra.Command {
+	var ignore []string
+	var minScore float64
+
+	cmd := &cobra.Command{
+		Use:   "fleet <snapshot> <snapshot>...",
+		Short: "Compare the snapshots of many hosts at once",
+		Long: `Line up every package, service, file, environment variable, cron job,
+certificate, user, group, setting and network interface across the snapshots
+of hosts that should be the same, given as file paths or store references.
+Items the hosts disagree on are listed with the hosts that differ from the
+majority, and each category gets a score: the share of its items that are
+the same on every host.
+
+Items that differ on every host by design, such as /etc/machine-id, are
+left out, as are names matching --ignore or fleet.ignore in the
+configuration file. The exit code is 1 when a category scores below
+--min-score.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := loadConfig()
+
+			hosts := make([]fleet.Host, 0, len(args))
+			for _, ref := range args {
+				snapshot, err := resolveSnapshot(config, ref)
+				if err != nil {
+					return fmt.Errorf("loading snapshot %s: %w", ref, err)
+				}
+				hosts = append(hosts, fleet.Host{Snapshot: snapshot})
+			}
+			nameHosts(hosts, args)
+
+			patterns := append(append(append([]string(nil), fleet.DefaultIgnore...), config.Fleet.Ignore...), ignore...)
+			report, err := fleet.Compare(hosts, patterns)
+			if err != nil {
+				return err
+			}
+
+			rep := reporter.New(reporter.Format(outputFormat), os.Stdout)
+			if err := rep.GenerateFleet(report); err != nil {
+				return err
+			}
+
+			for _, cat := range report.Categories {
+				if cat.Score < minScore {
+					os.Exit(1)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&ignore, "ignore", nil, "item name or pattern to leave out (repeatable)")
+	cmd.Flags().Float64Var(&minScore, "min-score", 100, "lowest consistency score, in percent, a category may have before the exit code is 1")
+
+	return cmd
+}
+
+// nameHosts names each host after its hostname, or after the file or
+// reference it was loaded from when hostnames are missing or repeat
+func nameHosts(hosts []fleet.Host, refs []string) {
+	seen := make(map[string]int)
+	for i := range hosts {
+		hosts[i].Name = hosts[i].Snapshot.Hostname
+		seen[hosts[i].Name]++
+	}
+	for i, h := range hosts {
+		if h.Name != "" && seen[h.Name] == 1 {
+			continue
+		}
+		hosts[i].Name = strings.TrimSuffix(filepath.Base(refs[i]), filepath.Ext(refs[i]))
+	}
+}
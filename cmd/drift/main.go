@@ -10,6 +10,7 @@ import (
 
 	"github.com/AshitomW/Drifty/internal/collector"
 	"github.com/AshitomW/Drifty/internal/comparator"
+	"github.com/AshitomW/Drifty/internal/image"
 	"github.com/AshitomW/Drifty/internal/models"
 	"github.com/AshitomW/Drifty/internal/reporter"
 	"github.com/spf13/cobra"
@@ -30,13 +31,21 @@ func main() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file path")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "output format (json, yaml, table, text)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "output format (json, yaml, table, text, html)")
 
 	// Commands
 	rootCmd.AddCommand(snapshotCmd())
 	rootCmd.AddCommand(compareCmd())
 	rootCmd.AddCommand(diffCmd())
 	rootCmd.AddCommand(daemonCmd())
+	rootCmd.AddCommand(snapshotsCmd())
+	rootCmd.AddCommand(acceptCmd())
+	rootCmd.AddCommand(checkCmd())
+	rootCmd.AddCommand(auditCmd())
+	rootCmd.AddCommand(remediateCmd())
+	rootCmd.AddCommand(exportCmd())
+	rootCmd.AddCommand(fleetCmd())
+	rootCmd.AddCommand(baselineCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -48,6 +57,10 @@ func snapshotCmd() *cobra.Command {
 	var name string
 	var outputPath string
 	var format string
+	var save bool
+	var labels []string
+	var root string
+	var ociImage string
 
 	cmd := &cobra.Command{
 		Use:   "snapshot",
@@ -56,13 +69,37 @@ func snapshotCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := loadConfig()
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-			defer cancel()
+			snapshotLabels, err := parseLabels(labels)
+			if err != nil {
+				return err
+			}
+			if root != "" {
+				config.Collector.Root = root
+			}
 
-			c := collector.New(config.Collector)
-			snapshot, err := c.Collect(ctx, name)
+			snapshot, err := collectSnapshot(context.Background(), config, name, ociImage)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return err
+			}
+			if len(snapshotLabels) > 0 {
+				snapshot.Labels = snapshotLabels
+			}
+
+			if save {
+				st, err := openStore(config)
+				if err != nil {
+					return err
+				}
+				entry, err := st.Save(snapshot)
+				if err != nil {
+					return fmt.Errorf("saving snapshot: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Saved snapshot %s\n", entry.ID)
+
+				// with --save the store is the output unless a file is asked for too
+				if outputPath == "" {
+					return nil
+				}
 			}
 
 			// Output snapshot
@@ -76,24 +113,18 @@ func snapshotCmd() *cobra.Command {
 				output = f
 			}
 
-			switch format {
-			case "yaml", "yml":
-				encoder := yaml.NewEncoder(output)
-				encoder.SetIndent(2)
-				return encoder.Encode(snapshot)
-			case "table":
-				return generateSnapshotTable(snapshot, output)
-			default:
-				encoder := json.NewEncoder(output)
-				encoder.SetIndent("", "  ")
-				return encoder.Encode(snapshot)
-			}
+			return writeSnapshot(snapshot, format, output)
 		},
 	}
 
 	cmd.Flags().StringVarP(&name, "name", "n", "default", "snapshot name")
 	cmd.Flags().StringVarP(&outputPath, "file", "f", "", "output file path")
 	cmd.Flags().StringVarP(&format, "format", "F", "json", "output format (json, yaml, table)")
+	cmd.Flags().BoolVarP(&save, "save", "s", false, "save the snapshot to the configured store")
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "label the snapshot (key=value, repeatable)")
+	cmd.Flags().StringVar(&root, "root", "", "collect from a filesystem tree mounted here instead of the running host")
+	cmd.Flags().StringVar(&ociImage, "oci-image", "", "collect from a saved image (OCI layout or docker save archive)")
+	cmd.MarkFlagsMutuallyExclusive("root", "oci-image")
 
 	return cmd
 }
@@ -101,29 +132,26 @@ func snapshotCmd() *cobra.Command {
 func compareCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "compare <source-snapshot> <target-snapshot>",
-		Short: "Compare two snapshot files",
+		Short: "Compare two snapshots",
+		Long:  `Compare two snapshots, given as file paths or store references (@latest, @name~1, ID prefix)`,
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			sourceFile := args[0]
-			targetFile := args[1]
+			config := loadConfig()
 
-			source, err := loadSnapshot(sourceFile)
+			source, err := resolveSnapshot(config, args[0])
 			if err != nil {
 				return fmt.Errorf("loading source snapshot: %w", err)
 			}
 
-			target, err := loadSnapshot(targetFile)
+			target, err := resolveSnapshot(config, args[1])
 			if err != nil {
 				return fmt.Errorf("loading target snapshot: %w", err)
 			}
 
-			config := loadConfig()
-			comp := comparator.New(comparator.SeverityRules{
-				CriticalPackages: config.SeverityRules.CriticalPackages,
-				CriticalServices: config.SeverityRules.CriticalServices,
-				CriticalFiles:    config.SeverityRules.CriticalFiles,
-				CriticalEnvVars:  config.SeverityRules.CriticalEnvVars,
-			})
+			comp, err := newComparator(config)
+			if err != nil {
+				return err
+			}
 
 			report := comp.Compare(source, target)
 
@@ -139,35 +167,35 @@ func compareCmd() *cobra.Command {
 
 func diffCmd() *cobra.Command {
 	var snapshotFile string
+	var root string
+	var ociImage string
 
 	cmd := &cobra.Command{
 		Use:   "diff",
 		Short: "Compare current environment against a snapshot",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			config := loadConfig()
+			if root != "" {
+				config.Collector.Root = root
+			}
+
 			// Load baseline snapshot
-			baseline, err := loadSnapshot(snapshotFile)
+			baseline, err := resolveSnapshot(config, snapshotFile)
 			if err != nil {
 				return fmt.Errorf("loading baseline snapshot: %w", err)
 			}
 
 			// Collect current state
-			config := loadConfig()
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-			defer cancel()
-
-			c := collector.New(config.Collector)
-			current, err := c.Collect(ctx, "current")
+			current, err := collectSnapshot(context.Background(), config, "current", ociImage)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return err
 			}
 
 			// Compare
-			comp := comparator.New(comparator.SeverityRules{
-				CriticalPackages: config.SeverityRules.CriticalPackages,
-				CriticalServices: config.SeverityRules.CriticalServices,
-				CriticalFiles:    config.SeverityRules.CriticalFiles,
-				CriticalEnvVars:  config.SeverityRules.CriticalEnvVars,
-			})
+			comp, err := newComparator(config)
+			if err != nil {
+				return err
+			}
 
 			report := comp.Compare(baseline, current)
 
@@ -190,30 +218,143 @@ func diffCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&snapshotFile, "baseline", "b", "", "baseline snapshot file")
+	cmd.Flags().StringVarP(&snapshotFile, "baseline", "b", "", "baseline snapshot file or store reference")
 	cmd.MarkFlagRequired("baseline")
+	cmd.Flags().StringVar(&root, "root", "", "check a filesystem tree mounted here instead of the running host")
+	cmd.Flags().StringVar(&ociImage, "oci-image", "", "check a saved image (OCI layout or docker save archive)")
+	cmd.MarkFlagsMutuallyExclusive("root", "oci-image")
 
 	return cmd
 }
 
+// collectSnapshot collects from the running host, the configured root or,
+// when ociImage is set, a saved image. Collector errors are only warnings;
+// whatever was collected is still returned. There's no deadline on the
+// whole run, each collector is held to its own timeout.
+func collectSnapshot(ctx context.Context, config *Config, name, ociImage string) (*models.EnvironmentSnapshot, error) {
+	if ociImage == "" {
+		snapshot, err := collector.New(config.Collector).Collect(ctx, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		return snapshot, nil
+	}
+
+	img, err := image.Load(ociImage)
+	if err != nil {
+		return nil, fmt.Errorf("loading image: %w", err)
+	}
+
+	snapshot, err := collector.NewFromFS(config.Collector, img.FS, ociImage).Collect(ctx, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	img.Annotate(snapshot)
+	return snapshot, nil
+}
+
 type Config struct {
-	Collector     models.CollectorConfig `yaml:"collector"`
+	Collector models.CollectorConfig `yaml:"collector"`
+
+	// Rules rate drifts, checked top to bottom before the built-in ones
+	Rules []comparator.Rule `yaml:"rules"`
+
+	// Normalize rewrites what differs between environments by design before
+	// snapshots are compared, "staging." and "prod." alike
+	Normalize []comparator.Normalization `yaml:"normalize"`
+
+	// SeverityRules is the older way of marking things critical, still read
+	// and turned into rules after the ones above
 	SeverityRules struct {
 		CriticalPackages []string `yaml:"critical_packages"`
 		CriticalServices []string `yaml:"critical_services"`
 		CriticalFiles    []string `yaml:"critical_files"`
 		CriticalEnvVars  []string `yaml:"critical_env_vars"`
+
+		// files changed by a package upgrade in the same comparison are
+		// reported as info instead of their usual severity
+		DowngradePackageFiles bool `yaml:"downgrade_package_files"`
 	} `yaml:"severity_rules"`
+	Storage struct {
+		Type string `yaml:"type"`
+		Path string `yaml:"path"`
+	} `yaml:"storage"`
+
+	// Acceptance is where "drift accept" records expected drifts
+	Acceptance struct {
+		Path string `yaml:"path"`
+	} `yaml:"acceptance"`
+
+	Audit struct {
+		Suppress []string `yaml:"suppress"` // hardening check IDs or patterns not to run
+	} `yaml:"audit"`
+
+	Fleet struct {
+		Ignore []string `yaml:"ignore"` // item names "drift fleet" doesn't line up, on top of the built-in ones
+	} `yaml:"fleet"`
+
+	Baseline struct {
+		Exclude []models.Exclusion `yaml:"exclude"` // items "drift baseline build" leaves out, on top of the built-in ones
+	} `yaml:"baseline"`
+}
+
+func newComparator(config *Config) (*comparator.Comparator, error) {
+	comp, err := comparator.New(config.rules())
+	if err != nil {
+		return nil, fmt.Errorf("severity rules: %w", err)
+	}
+
+	if err := comp.Normalize(config.Normalize); err != nil {
+		return nil, fmt.Errorf("normalize: %w", err)
+	}
+
+	accepted, err := loadAcceptances(config)
+	if err != nil {
+		return nil, err
+	}
+	comp.Accept(accepted)
+	return comp, nil
+}
+
+// rules gives the configured rules followed by the severity_rules lists
+func (config *Config) rules() []comparator.Rule {
+	rules := append([]comparator.Rule(nil), config.Rules...)
+	legacy := config.SeverityRules
+
+	if legacy.DowngradePackageFiles {
+		rules = append(rules, comparator.Rule{Category: "file", CausedBy: "*", Severity: "info"})
+	}
+	for _, name := range legacy.CriticalFiles {
+		rules = append(rules, comparator.Rule{Category: "file", Name: name, Severity: "critical"})
+	}
+	for _, name := range legacy.CriticalEnvVars {
+		rules = append(rules, comparator.Rule{Category: "envvar", Name: name, Severity: "critical"})
+	}
+	for _, name := range legacy.CriticalServices {
+		rules = append(rules, comparator.Rule{Category: "service", Name: name, Severity: "critical"})
+	}
+	// package names are listed without the manager, "openssl" for
+	// "dpkg:openssl"
+	for _, name := range legacy.CriticalPackages {
+		rules = append(rules,
+			comparator.Rule{Category: "package", Package: "*:" + name, Severity: "critical"},
+			comparator.Rule{Category: "package_file", Package: "*:" + name, Severity: "critical"})
+	}
+	return rules
 }
 
 func loadConfig() *Config {
 	config := &Config{
 		Collector: models.CollectorConfig{
+			DefaultTimeout: 2 * time.Minute,
 			Files: models.FileCollectorConfig{
 				Enabled:  true,
 				Paths:    []string{"/etc"},
 				HashAlgo: "sha256",
 				MaxDepth: 10,
+				CaptureContent: models.ContentCaptureConfig{
+					MaskSecrets: true,
+				},
 			},
 			EnvVars: models.EnvVarCollectorConfig{
 				Enabled:     true,
@@ -262,16 +403,31 @@ func loadConfig() *Config {
 				SudoRules: true,
 			},
 			Packages: models.PackageCollectorConfig{
-				Enabled:  true,
-				Managers: []string{"dpkg", "pip"},
+				Enabled:     true,
+				Managers:    []string{"dpkg", "pip"},
+				History:     true,
+				HistoryDays: 90,
+			},
+			PackageIntegrity: models.PackageIntegrityCollectorConfig{
+				Enabled:  false,
+				Timeout:  10 * time.Minute,
+				Managers: []string{"dpkg", "rpm"},
 			},
 			Services: models.ServiceCollectorConfig{
 				Enabled:  true,
 				InitType: "systemd",
 			},
+			Settings: models.SettingsCollectorConfig{
+				Enabled: true,
+				SSHD:    true,
+			},
 		},
 	}
 
+	config.Storage.Type = "file"
+	config.Storage.Path = "/var/lib/drift-detector/snapshots"
+	config.Acceptance.Path = "/var/lib/drift-detector/accepted.yaml"
+
 	if configFile != "" {
 		data, err := os.ReadFile(configFile)
 		if err == nil {
@@ -303,6 +459,21 @@ func loadSnapshot(path string) (*models.EnvironmentSnapshot, error) {
 	return &snapshot, nil
 }
 
+func writeSnapshot(snapshot *models.EnvironmentSnapshot, format string, output *os.File) error {
+	switch format {
+	case "yaml", "yml":
+		encoder := yaml.NewEncoder(output)
+		encoder.SetIndent(2)
+		return encoder.Encode(snapshot)
+	case "table":
+		return generateSnapshotTable(snapshot, output)
+	default:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	}
+}
+
 func generateSnapshotTable(snapshot *models.EnvironmentSnapshot, output *os.File) error {
 	fmt.Fprintf(output, "\nEnvironment Snapshot\n")
 	fmt.Fprintf(output, "%s\n", strings.Repeat("=", 60))
@@ -310,9 +481,25 @@ func generateSnapshotTable(snapshot *models.EnvironmentSnapshot, output *os.File
 	fmt.Fprintf(output, "Name:      %s\n", snapshot.Name)
 	fmt.Fprintf(output, "Hostname:  %s\n", snapshot.Hostname)
 	fmt.Fprintf(output, "Timestamp: %s\n", snapshot.Timestamp.Format("2006-01-02 15:04:05"))
+	if len(snapshot.Labels) > 0 {
+		fmt.Fprintf(output, "Labels:    %s\n", formatLabels(snapshot.Labels))
+	}
 	fmt.Fprintf(output, "OS:        %s %s (%s)\n", snapshot.OS.Name, snapshot.OS.Version, snapshot.OS.Arch)
 	fmt.Fprintf(output, "Kernel:    %s\n\n", snapshot.OS.Kernel)
 
+	if len(snapshot.Collectors) > 0 {
+		fmt.Fprintf(output, "Collectors (%d)\n", len(snapshot.Collectors))
+		fmt.Fprintf(output, "%s\n", strings.Repeat("-", 60))
+		for _, name := range sortedKeys(snapshot.Collectors) {
+			status := snapshot.Collectors[name]
+			fmt.Fprintf(output, "  %-20s : %-8s %6dms %6d items\n", name, status.Status, status.DurationMs, status.ItemCount)
+			for _, e := range status.Errors {
+				fmt.Fprintf(output, "      ! %s\n", e)
+			}
+		}
+		fmt.Fprintln(output)
+	}
+
 	if len(snapshot.EnvVars) > 0 {
 		fmt.Fprintf(output, "Environment Variables (%d)\n", len(snapshot.EnvVars))
 		fmt.Fprintf(output, "%s\n", strings.Repeat("-", 60))
@@ -358,6 +545,16 @@ func generateSnapshotTable(snapshot *models.EnvironmentSnapshot, output *os.File
 		fmt.Fprintln(output)
 	}
 
+	if len(snapshot.PackageIntegrity.Issues) > 0 {
+		fmt.Fprintf(output, "Modified Package Files (%d)\n", len(snapshot.PackageIntegrity.Issues))
+		fmt.Fprintf(output, "%s\n", strings.Repeat("-", 60))
+		for _, path := range sortedKeys(snapshot.PackageIntegrity.Issues) {
+			issue := snapshot.PackageIntegrity.Issues[path]
+			fmt.Fprintf(output, "  %-40s : %-20s (%s)\n", path, strings.Join(issue.Problems, ","), issue.Package)
+		}
+		fmt.Fprintln(output)
+	}
+
 	if len(snapshot.Services) > 0 {
 		fmt.Fprintf(output, "Services (%d)\n", len(snapshot.Services))
 		fmt.Fprintf(output, "%s\n", strings.Repeat("-", 60))
@@ -473,6 +670,10 @@ func sortedKeys(m interface{}) []string {
 		for k := range v {
 			keys = append(keys, k)
 		}
+	case map[string]models.CollectorStatus:
+		for k := range v {
+			keys = append(keys, k)
+		}
 	}
 
 	for i := 0; i < len(keys); i++ {
@@ -517,15 +718,23 @@ func daemonCmd() *cobra.Command {
 			fmt.Printf("Output directory: %s\n", outputDir)
 			fmt.Printf("Press Ctrl+C to stop\n\n")
 
-			baseline, err := loadSnapshot(baselineFile)
+			baseline, err := resolveSnapshot(config, baselineFile)
 			if err != nil {
 				return fmt.Errorf("loading baseline: %w", err)
 			}
 
+			comp, err := newComparator(config)
+			if err != nil {
+				return err
+			}
+
 			ticker := time.NewTicker(interval)
 			defer ticker.Stop()
 
 			runCount := 0
+			// drift IDs seen in the previous run, so a drift that persists
+			// isn't announced again every interval
+			var seen map[string]bool
 
 			for {
 				select {
@@ -542,18 +751,30 @@ func daemonCmd() *cobra.Command {
 						fmt.Printf("Warning: %v\n", err)
 					}
 
-					comp := comparator.New(comparator.SeverityRules{
-						CriticalPackages: config.SeverityRules.CriticalPackages,
-						CriticalServices: config.SeverityRules.CriticalServices,
-						CriticalFiles:    config.SeverityRules.CriticalFiles,
-						CriticalEnvVars:  config.SeverityRules.CriticalEnvVars,
-					})
+					// pick up drifts accepted while the daemon runs
+					if accepted, err := loadAcceptances(config); err != nil {
+						fmt.Printf("Warning: %v\n", err)
+					} else {
+						comp.Accept(accepted)
+					}
 
 					report := comp.Compare(baseline, current)
 
-					fmt.Printf("Total drifts: %d (Critical: %d, Warning: %d, Info: %d)\n",
+					fmt.Printf("Total drifts: %d (Critical: %d, Warning: %d, Info: %d, Accepted: %d)\n",
 						report.Summary.TotalDrifts, report.Summary.CriticalCount,
-						report.Summary.WarningCount, report.Summary.InfoCount)
+						report.Summary.WarningCount, report.Summary.InfoCount, report.Summary.AcceptedCount)
+
+					active := make(map[string]bool, len(report.Drifts))
+					for _, drift := range report.Drifts {
+						if drift.Accepted != nil {
+							continue
+						}
+						active[drift.ID] = true
+						if seen != nil && !seen[drift.ID] {
+							fmt.Printf("New: [%s] %s %s %s (%s)\n", drift.Severity, drift.Type, drift.Category, drift.Name, drift.ID)
+						}
+					}
+					seen = active
 
 					if report.HasDrift {
 						fmt.Printf("Drift detected!\n")
@@ -581,7 +802,7 @@ func daemonCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&baselineFile, "baseline", "b", "", "baseline snapshot file to compare against")
+	cmd.Flags().StringVarP(&baselineFile, "baseline", "b", "", "baseline snapshot file or store reference to compare against")
 	cmd.MarkFlagRequired("baseline")
 	cmd.Flags().StringVarP(&intervalStr, "interval", "i", "5m", "interval between checks (e.g., 5m, 1h)")
 	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "output directory for reports")
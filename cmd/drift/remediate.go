@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AshitomW/Drifty/internal/plan"
+	"github.com/spf13/cobra"
+)
+
+func remediateCmd() *cobra.Command {
+	var as string
+	var outputPath string
+
+	cmd := &cobra.Command{
+		Use:   "remediate <report>",
+		Short: "Write a script that reverts the drifts in a report",
+		Long: `Turn a JSON drift report, such as one from "drift diff -b baseline.json -o json",
+into a shell script or Ansible playbook that takes the target back toward the
+source: package versions, service states, file permissions and owners, and
+cron lines. Running it more than once is safe.
+
+Drifts that can't be reverted this way, such as changed file content, are
+listed at the top of the output for manual action. Accepted drifts are left
+alone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := loadReport(args[0])
+			if err != nil {
+				return fmt.Errorf("loading report: %w", err)
+			}
+
+			p := plan.Build(report)
+
+			output := os.Stdout
+			if outputPath != "" {
+				mode := os.FileMode(0644)
+				if as == "shell" {
+					mode = 0755
+				}
+				f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				output = f
+			}
+
+			switch as {
+			case "shell":
+				err = p.Shell(output)
+			case "ansible":
+				err = p.Ansible(output)
+			default:
+				return fmt.Errorf("unknown format %q, expected shell or ansible", as)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "%d step(s), %d drift(s) need manual action\n", len(p.Steps), len(p.Manual))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&as, "as", "shell", "output as a shell script or an Ansible playbook (shell, ansible)")
+	cmd.Flags().StringVarP(&outputPath, "file", "f", "", "output file path")
+
+	return cmd
+}
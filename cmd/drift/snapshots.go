@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/store"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func snapshotsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshots",
+		Short: "Manage stored snapshots",
+		Long: `List, show, remove and prune snapshots in the configured store.
+
+Stored snapshots can be referenced anywhere a snapshot file is accepted:
+  @latest        newest snapshot
+  @web1          newest snapshot named web1 (or taken on host web1)
+  @env=prod      newest snapshot labelled env=prod
+  @web1~1        the one before that
+  3f2a9c         snapshot ID prefix`,
+	}
+
+	cmd.AddCommand(snapshotsListCmd())
+	cmd.AddCommand(snapshotsShowCmd())
+	cmd.AddCommand(snapshotsRmCmd())
+	cmd.AddCommand(snapshotsPruneCmd())
+
+	return cmd
+}
+
+func snapshotsListCmd() *cobra.Command {
+	var labels []string
+
+	cmd := &cobra.Command{
+		Use:   "list [selector]",
+		Short: "List stored snapshots",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore(loadConfig())
+			if err != nil {
+				return err
+			}
+
+			entries, err := st.List()
+			if err != nil {
+				return err
+			}
+
+			want, err := parseLabels(labels)
+			if err != nil {
+				return err
+			}
+
+			var shown []store.Entry
+			for _, e := range entries {
+				if len(args) == 1 && e.Name != args[0] && e.Hostname != args[0] {
+					continue
+				}
+				if !hasLabels(e, want) {
+					continue
+				}
+				shown = append(shown, e)
+			}
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(shown)
+			case "yaml", "yml":
+				encoder := yaml.NewEncoder(os.Stdout)
+				encoder.SetIndent(2)
+				return encoder.Encode(shown)
+			}
+
+			fmt.Printf("%-8s  %-20s  %-20s  %-19s  %s\n", "ID", "NAME", "HOSTNAME", "TIMESTAMP", "LABELS")
+			for _, e := range shown {
+				fmt.Printf("%-8s  %-20s  %-20s  %-19s  %s\n",
+					shortID(e.ID), e.Name, e.Hostname, e.Timestamp.Local().Format("2006-01-02 15:04:05"), formatLabels(e.Labels))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "only show snapshots with this label (key=value, repeatable)")
+
+	return cmd
+}
+
+func snapshotsShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <ref>",
+		Short: "Show a stored snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshot, err := resolveSnapshot(loadConfig(), args[0])
+			if err != nil {
+				return err
+			}
+			return writeSnapshot(snapshot, outputFormat, os.Stdout)
+		},
+	}
+}
+
+func snapshotsRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <ref>...",
+		Short: "Remove stored snapshots",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore(loadConfig())
+			if err != nil {
+				return err
+			}
+
+			// resolve everything first so a bad reference removes nothing
+			var ids []string
+			for _, ref := range args {
+				entry, err := st.Resolve(ref)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, entry.ID)
+			}
+
+			if err := st.Remove(ids...); err != nil {
+				return err
+			}
+			for _, id := range ids {
+				fmt.Printf("Removed %s\n", id)
+			}
+			return nil
+		},
+	}
+}
+
+func snapshotsPruneCmd() *cobra.Command {
+	var keepLast int
+	var olderThan string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old snapshots",
+		Long: `Delete old snapshots from the store. Snapshots are grouped by name and
+hostname; --keep-last always keeps the newest N of each group, and
+--older-than only deletes snapshots older than the given age (e.g. 30d, 12h).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var age time.Duration
+			if olderThan != "" {
+				var err error
+				if age, err = parseAge(olderThan); err != nil {
+					return err
+				}
+			}
+
+			st, err := openStore(loadConfig())
+			if err != nil {
+				return err
+			}
+
+			pruned, err := st.Prune(keepLast, age, time.Now(), dryRun)
+			if err != nil {
+				return err
+			}
+
+			verb := "Removed"
+			if dryRun {
+				verb = "Would remove"
+			}
+			for _, e := range pruned {
+				fmt.Printf("%s %s  %s@%s  %s\n", verb, shortID(e.ID), e.Name, e.Hostname, e.Timestamp.Local().Format("2006-01-02 15:04:05"))
+			}
+			fmt.Printf("%s %d snapshot(s)\n", verb, len(pruned))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keepLast, "keep-last", 0, "keep the newest N snapshots of each name/hostname")
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "only delete snapshots older than this (e.g. 30d, 12h)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted")
+
+	return cmd
+}
+
+func openStore(config *Config) (*store.Store, error) {
+	if config.Storage.Type != "" && config.Storage.Type != "file" {
+		return nil, fmt.Errorf("unsupported storage type %q", config.Storage.Type)
+	}
+	return store.Open(config.Storage.Path)
+}
+
+// resolveSnapshot loads a snapshot from a file path or a store reference.
+// An existing file always wins, so plain paths keep working as before.
+func resolveSnapshot(config *Config, ref string) (*models.EnvironmentSnapshot, error) {
+	if !strings.HasPrefix(ref, "@") {
+		if _, err := os.Stat(ref); err == nil {
+			return loadSnapshot(ref)
+		}
+	}
+
+	st, err := openStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := st.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return st.Load(entry.ID)
+}
+
+// parseAge is time.ParseDuration plus day and week units
+func parseAge(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func parseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+func hasLabels(e store.Entry, want map[string]string) bool {
+	for k, v := range want {
+		if e.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,123 @@
+// Package audit holds the bundled hardening checks run by "drift audit".
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+	"github.com/AshitomW/Drifty/internal/policy"
+	"github.com/google/uuid"
+)
+
+// Version identifies the check set. It goes up whenever a check is added or
+// removed or changes what it flags, so results from different releases can
+// be told apart.
+const Version = "1"
+
+// Check is one hardening check
+type Check struct {
+	ID       string
+	Title    string
+	Severity string
+
+	// run gives a finding per offending item, or why the snapshot can't
+	// answer the check
+	run func(s *models.EnvironmentSnapshot) (findings []finding, skipped string)
+}
+
+type finding struct {
+	item    string // the offending user, file, setting...; "" if the check is about one thing
+	value   interface{}
+	message string
+}
+
+// Checks lists the bundled checks
+func Checks() []Check {
+	return append([]Check(nil), checks...)
+}
+
+// Run audits snapshot with every check whose ID doesn't match one of the
+// suppress patterns. Checks give "pass" or "fail" findings in the audit
+// category; checks that were suppressed, or need data the snapshot doesn't
+// have, are listed in the notes.
+func Run(snapshot *models.EnvironmentSnapshot, suppress []string) (*models.DriftReport, error) {
+	var suppressed []func(string) bool
+	for _, p := range suppress {
+		match, err := pattern.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("suppress: %w", err)
+		}
+		suppressed = append(suppressed, match)
+	}
+
+	report := &models.DriftReport{
+		ID:             uuid.New().String(),
+		Timestamp:      time.Now().UTC(),
+		SourceEnv:      "hardening checks v" + Version,
+		TargetEnv:      snapshot.Name,
+		TargetSnapshot: snapshot.ID,
+		Drifts:         make([]models.DriftItem, 0, len(checks)),
+		Summary: models.DriftSummary{
+			ByCategory: make(map[string]int),
+			ByType:     make(map[string]int),
+		},
+	}
+
+	var skipped []string
+	for _, check := range checks {
+		if matchesAny(suppressed, check.ID) {
+			skipped = append(skipped, check.ID)
+			continue
+		}
+
+		findings, reason := check.run(snapshot)
+		if reason != "" {
+			report.Notes = append(report.Notes, fmt.Sprintf("%s not checked: %s", check.ID, reason))
+			continue
+		}
+
+		if len(findings) == 0 {
+			report.Drifts = append(report.Drifts, models.DriftItem{
+				Type:     "pass",
+				Category: "audit",
+				Name:     check.ID,
+				Message:  check.Title,
+			})
+			continue
+		}
+
+		for _, f := range findings {
+			name := check.ID
+			if f.item != "" {
+				name += " " + f.item
+			}
+			report.Drifts = append(report.Drifts, models.DriftItem{
+				Type:      "fail",
+				Category:  "audit",
+				Name:      name,
+				TargetVal: f.value,
+				Severity:  check.Severity,
+				Message:   f.message,
+			})
+		}
+	}
+
+	if len(skipped) > 0 {
+		report.Notes = append(report.Notes, "suppressed: "+strings.Join(skipped, ", "))
+	}
+
+	policy.Finish(report)
+	return report, nil
+}
+
+func matchesAny(matchers []func(string) bool, s string) bool {
+	for _, match := range matchers {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+var checks = []Check{
+	{"accounts.empty-password", "No account has an empty password", "critical", emptyPasswords},
+	{"accounts.duplicate-uid0", "Only root has UID 0", "critical", duplicateRoot},
+	{"sudo.nopasswd", "No sudo rule skips the password", "warning", sudoNoPassword},
+	{"files.etc-world-writable", "Nothing under /etc is world-writable", "critical", etcWorldWritable},
+	{"services.telnet", "No telnet server is enabled or running", "critical",
+		servicesOff("telnet", "telnetd", "telnet.socket", "inetutils-telnetd")},
+	{"services.rsh", "No rsh, rlogin or rexec server is enabled or running", "critical",
+		servicesOff("rsh", "rshd", "rsh.socket", "rlogin", "rlogin.socket", "rexec", "rexec.socket")},
+
+	// sshd settings, with OpenSSH's defaults for the ones not set
+	{"ssh.permit-root-login", "sshd doesn't allow root to log in", "warning",
+		sshdSetting("permitrootlogin", "prohibit-password", equals("no"))},
+	{"ssh.permit-empty-passwords", "sshd refuses empty passwords", "critical",
+		sshdSetting("permitemptypasswords", "no", equals("no"))},
+	{"ssh.hostbased-authentication", "sshd doesn't trust other hosts", "warning",
+		sshdSetting("hostbasedauthentication", "no", equals("no"))},
+	{"ssh.ignore-rhosts", "sshd ignores .rhosts files", "warning",
+		sshdSetting("ignorerhosts", "yes", equals("yes"))},
+	{"ssh.x11-forwarding", "sshd doesn't forward X11", "info",
+		sshdSetting("x11forwarding", "no", equals("no"))},
+	{"ssh.max-auth-tries", "sshd allows at most 4 authentication attempts", "warning",
+		sshdSetting("maxauthtries", "6", atMost(4))},
+
+	// kernel settings
+	{"sysctl.ip-forward", "IP forwarding is off", "warning",
+		sysctl("net.ipv4.ip_forward", "0")},
+	{"sysctl.accept-redirects", "ICMP redirects are ignored", "warning",
+		sysctl("net.ipv4.conf.all.accept_redirects", "0")},
+	{"sysctl.send-redirects", "ICMP redirects are not sent", "warning",
+		sysctl("net.ipv4.conf.all.send_redirects", "0")},
+	{"sysctl.source-route", "Source routed packets are refused", "warning",
+		sysctl("net.ipv4.conf.all.accept_source_route", "0")},
+	{"sysctl.syncookies", "TCP SYN cookies are on", "warning",
+		sysctl("net.ipv4.tcp_syncookies", "1")},
+	{"sysctl.aslr", "Address space layout randomization is fully on", "critical",
+		sysctl("kernel.randomize_va_space", "2")},
+	{"sysctl.suid-dumpable", "setuid programs don't dump core", "warning",
+		sysctl("fs.suid_dumpable", "0")},
+}
+
+// collected reports whether a collector ran and produced data. Snapshots
+// from before collector statuses were recorded are taken as complete.
+func collected(s *models.EnvironmentSnapshot, collector string) bool {
+	if s.Collectors == nil {
+		return true
+	}
+	status, ok := s.Collectors[collector]
+	return ok && (status.Status == "ok" || status.Status == "partial")
+}
+
+func sortedUsers(users map[string]models.UserInfo) []string {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func emptyPasswords(s *models.EnvironmentSnapshot) ([]finding, string) {
+	users := s.UserGroupConfig.Users
+	known := false
+	for _, u := range users {
+		if u.Password != "" {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, "password status not collected, reading /etc/shadow needs root"
+	}
+
+	var findings []finding
+	for _, name := range sortedUsers(users) {
+		if users[name].Password == "empty" {
+			findings = append(findings, finding{name, users[name], fmt.Sprintf("user %s has an empty password", name)})
+		}
+	}
+	return findings, ""
+}
+
+func duplicateRoot(s *models.EnvironmentSnapshot) ([]finding, string) {
+	users := s.UserGroupConfig.Users
+	if !collected(s, "users_groups") || len(users) == 0 {
+		return nil, "users not collected"
+	}
+
+	var findings []finding
+	for _, name := range sortedUsers(users) {
+		if users[name].UID == 0 && name != "root" {
+			findings = append(findings, finding{name, users[name], fmt.Sprintf("user %s has UID 0", name)})
+		}
+	}
+	return findings, ""
+}
+
+func sudoNoPassword(s *models.EnvironmentSnapshot) ([]finding, string) {
+	if !collected(s, "users_groups") {
+		return nil, "sudo rules not collected"
+	}
+
+	var findings []finding
+	for _, rule := range s.UserGroupConfig.SudoRules {
+		if strings.Contains(rule.Commands, "NOPASSWD") {
+			findings = append(findings, finding{rule.User, rule, fmt.Sprintf("sudo rule for %s needs no password: %s", rule.User, rule.Commands)})
+		}
+	}
+	return findings, ""
+}
+
+func etcWorldWritable(s *models.EnvironmentSnapshot) ([]finding, string) {
+	var paths []string
+	for path := range s.Files {
+		if strings.HasPrefix(path, "/etc/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, "no files under /etc/ collected"
+	}
+	sort.Strings(paths)
+
+	var findings []finding
+	for _, path := range paths {
+		f := s.Files[path]
+		// symlinks always show rwxrwxrwx; the write bit for others is the
+		// second to last character of the mode
+		if len(f.Mode) >= 10 && f.Mode[0] != 'L' && f.Mode[len(f.Mode)-2] == 'w' {
+			findings = append(findings, finding{path, f, fmt.Sprintf("%s is world-writable (%s)", path, f.Mode)})
+		}
+	}
+	return findings, ""
+}
+
+func servicesOff(names ...string) func(s *models.EnvironmentSnapshot) ([]finding, string) {
+	return func(s *models.EnvironmentSnapshot) ([]finding, string) {
+		if !collected(s, "services") {
+			return nil, "services not collected"
+		}
+
+		var findings []finding
+		for _, name := range names {
+			svc, ok := s.Services[name]
+			if ok && (svc.Enabled || svc.Status == "running") {
+				findings = append(findings, finding{name, svc, fmt.Sprintf("service %s is %s (enabled: %v)", name, svc.Status, svc.Enabled)})
+			}
+		}
+		return findings, ""
+	}
+}
+
+func equals(want string) func(string) (bool, string) {
+	return func(v string) (bool, string) {
+		return strings.EqualFold(v, want), want
+	}
+}
+
+func atMost(limit int) func(string) (bool, string) {
+	return func(v string) (bool, string) {
+		n, err := strconv.Atoi(v)
+		return err == nil && n <= limit, fmt.Sprintf("%d or less", limit)
+	}
+}
+
+func sshdSetting(keyword, def string, ok func(string) (bool, string)) func(s *models.EnvironmentSnapshot) ([]finding, string) {
+	return func(s *models.EnvironmentSnapshot) ([]finding, string) {
+		found := false
+		for key := range s.Settings {
+			if strings.HasPrefix(key, "sshd:") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, "no sshd_config settings collected"
+		}
+
+		value, set := s.Settings["sshd:"+keyword]
+		if !set {
+			value = def
+		}
+		// a keyword can take several values, the first one counts
+		if fields := strings.Fields(value); len(fields) > 0 {
+			value = fields[0]
+		}
+
+		if pass, want := ok(value); !pass {
+			msg := fmt.Sprintf("sshd %s is %s, should be %s", keyword, value, want)
+			if !set {
+				msg += " (not set, the default applies)"
+			}
+			return []finding{{"", value, msg}}, ""
+		}
+		return nil, ""
+	}
+}
+
+func sysctl(key, want string) func(s *models.EnvironmentSnapshot) ([]finding, string) {
+	return func(s *models.EnvironmentSnapshot) ([]finding, string) {
+		value, ok := s.Settings["sysctl:"+key]
+		if !ok {
+			return nil, key + " not collected"
+		}
+		if value != want {
+			return []finding{{"", value, fmt.Sprintf("%s is %s, should be %s", key, value, want)}}, ""
+		}
+		return nil, ""
+	}
+}
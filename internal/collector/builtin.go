@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// builtin wraps one of the Runner's collect methods as a registered collector
+func builtin[T any](name string, section func(models.CollectorConfig) (bool, time.Duration), collect func(*Runner, context.Context) (T, error), merge func(*models.EnvironmentSnapshot, T)) Collector {
+	return funcCollector[T]{
+		name: name,
+		enabled: func(cfg models.CollectorConfig) bool {
+			enabled, _ := section(cfg)
+			return enabled
+		},
+		timeout: func(cfg models.CollectorConfig) time.Duration {
+			_, timeout := section(cfg)
+			return timeout
+		},
+		collect: collect,
+		merge:   merge,
+	}
+}
+
+// liveOnly wraps a collector that can only inspect the running host, so it is
+// skipped when collecting from another root
+func liveOnly[T any](collect func(*Runner, context.Context) (T, error)) func(*Runner, context.Context) (T, error) {
+	return func(c *Runner, ctx context.Context) (T, error) {
+		if err := c.requireLive(); err != nil {
+			var zero T
+			return zero, err
+		}
+		return collect(c, ctx)
+	}
+}
+
+func init() {
+	Register(builtin("files",
+		func(cfg models.CollectorConfig) (bool, time.Duration) { return cfg.Files.Enabled, cfg.Files.Timeout },
+		(*Runner).collectFiles,
+		func(s *models.EnvironmentSnapshot, v map[string]models.FileInfo) { s.Files = v }))
+
+	Register(builtin("env_vars",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.EnvVars.Enabled, cfg.EnvVars.Timeout
+		},
+		liveOnly((*Runner).collectEnvVars),
+		func(s *models.EnvironmentSnapshot, v map[string]models.EnvVar) { s.EnvVars = v }))
+
+	Register(builtin("process_env_vars",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.ProcessEnvVars.Enabled, cfg.ProcessEnvVars.Timeout
+		},
+		liveOnly((*Runner).collectProcessEnvVars),
+		func(s *models.EnvironmentSnapshot, v map[int]models.ProcessEnvVar) { s.ProcessEnvVars = v }))
+
+	Register(builtin("packages",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Packages.Enabled, cfg.Packages.Timeout
+		},
+		(*Runner).collectPackages,
+		func(s *models.EnvironmentSnapshot, v map[string]models.PackageInfo) { s.Packages = v }))
+
+	Register(builtin("package_history",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Packages.Enabled && cfg.Packages.History, cfg.Packages.Timeout
+		},
+		(*Runner).collectPackageHistory,
+		func(s *models.EnvironmentSnapshot, v []models.PackageTransaction) { s.PackageHistory = v }))
+
+	Register(builtin("package_integrity",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.PackageIntegrity.Enabled, cfg.PackageIntegrity.Timeout
+		},
+		(*Runner).collectPackageIntegrity,
+		func(s *models.EnvironmentSnapshot, v models.PackageIntegrity) { s.PackageIntegrity = v }))
+
+	Register(builtin("services",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Services.Enabled, cfg.Services.Timeout
+		},
+		liveOnly((*Runner).collectServices),
+		func(s *models.EnvironmentSnapshot, v map[string]models.ServiceInfo) { s.Services = v }))
+
+	Register(builtin("network",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Network.Enabled, cfg.Network.Timeout
+		},
+		(*Runner).collectNetworkConfig,
+		func(s *models.EnvironmentSnapshot, v models.NetworkConfig) { s.NetworkConfig = v }))
+
+	Register(builtin("docker",
+		func(cfg models.CollectorConfig) (bool, time.Duration) { return cfg.Docker.Enabled, cfg.Docker.Timeout },
+		liveOnly((*Runner).collectDockerConfig),
+		func(s *models.EnvironmentSnapshot, v models.DockerConfig) { s.DockerConfig = v }))
+
+	Register(builtin("system_resources",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.SystemResources.Enabled, cfg.SystemResources.Timeout
+		},
+		liveOnly((*Runner).collectSystemResources),
+		func(s *models.EnvironmentSnapshot, v models.SystemResources) { s.SystemResources = v }))
+
+	Register(builtin("scheduled_tasks",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.ScheduledTasks.Enabled, cfg.ScheduledTasks.Timeout
+		},
+		(*Runner).collectScheduledTasks,
+		func(s *models.EnvironmentSnapshot, v models.ScheduledTasks) { s.ScheduledTasks = v }))
+
+	Register(builtin("certificates",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Certificates.Enabled, cfg.Certificates.Timeout
+		},
+		(*Runner).collectCertificates,
+		func(s *models.EnvironmentSnapshot, v map[string]models.Certificate) { s.Certificates = v }))
+
+	Register(builtin("users_groups",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.UsersGroups.Enabled, cfg.UsersGroups.Timeout
+		},
+		(*Runner).collectUserGroupConfig,
+		func(s *models.EnvironmentSnapshot, v models.UserGroupConfig) { s.UserGroupConfig = v }))
+
+	Register(builtin("settings",
+		func(cfg models.CollectorConfig) (bool, time.Duration) {
+			return cfg.Settings.Enabled, cfg.Settings.Timeout
+		},
+		(*Runner).collectSettings,
+		func(s *models.EnvironmentSnapshot, v map[string]string) { s.Settings = v }))
+}
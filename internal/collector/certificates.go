@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,7 +17,7 @@ import (
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectCertificates(ctx context.Context) (map[string]models.Certificate, error) {
+func (c *Runner) collectCertificates(ctx context.Context) (map[string]models.Certificate, error) {
 	certificates := make(map[string]models.Certificate)
 
 	if !c.config.Certificates.Enabled {
@@ -30,7 +31,9 @@ func (c *Collector) collectCertificates(ctx context.Context) (map[string]models.
 			"/etc/letsencrypt",
 			"/etc/kubernetes",
 			"/usr/local/share/ca-certificates",
-			os.Getenv("HOME") + "/.ssh",
+		}
+		if c.offline == "" {
+			paths = append(paths, os.Getenv("HOME")+"/.ssh")
 		}
 	}
 
@@ -40,7 +43,10 @@ func (c *Collector) collectCertificates(ctx context.Context) (map[string]models.
 	}
 
 	for _, path := range paths {
-		err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		err := walk(c.fs, path, func(filePath string, info fs.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err != nil {
 				return nil
 			}
@@ -62,7 +68,7 @@ func (c *Collector) collectCertificates(ctx context.Context) (map[string]models.
 				return nil
 			}
 
-			data, err := os.ReadFile(filePath)
+			data, err := readFile(c.fs, filePath)
 			if err != nil {
 				return nil
 			}
@@ -2,9 +2,12 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,249 +15,330 @@ import (
 	"github.com/google/uuid"
 )
 
-type Collector struct {
+// Runner drives every registered collector and assembles the snapshot
+type Runner struct {
 	config  models.CollectorConfig
 	workers int
+	fs      FS
+
+	// offline names what is being collected from when it isn't the running
+	// host; collectors that need the live system are skipped
+	offline  string
+	owners   ownerNames
+	packages packageFiles
 }
 
-// Creating a new Coollcter instance
-func New(config models.CollectorConfig) *Collector {
+// Creating a new Runner instance
+func New(config models.CollectorConfig) *Runner {
 	workers := runtime.NumCPU()
 	if workers < 2 {
 		workers = 2
 	}
 
-	return &Collector{
+	runner := &Runner{
 		config:  config,
 		workers: workers,
+		fs:      hostFS{},
+	}
+	if config.Root != "" {
+		runner.fs = rootFS{root: config.Root}
+		runner.offline = config.Root
 	}
 
+	return runner
+
+}
+
+// NewFromFS creates a Runner that collects from fsys instead of the running
+// host, such as a flattened container image. source describes fsys in
+// statuses and snapshot metadata.
+func NewFromFS(config models.CollectorConfig, fsys FS, source string) *Runner {
+	runner := New(config)
+	runner.fs = fsys
+	runner.offline = source
+	return runner
+}
+
+// Config returns the collector configuration the runner was created with.
+// Third-party collectors use it to read their own extension settings.
+func (c *Runner) Config() models.CollectorConfig {
+	return c.config
+}
+
+// FS returns the filesystem collectors should read host files from. It is
+// the root given in the configuration when there is one.
+func (c *Runner) FS() FS {
+	return c.fs
+}
+
+// requireLive fails with ErrNotApplicable when the runner isn't looking at
+// the running host, for collectors that can only ask the live system
+func (c *Runner) requireLive() error {
+	if c.offline != "" {
+		return fmt.Errorf("%w: needs the running host, collecting from %s", ErrNotApplicable, c.offline)
+	}
+	return nil
+}
+
+// image reports whether the runner reads a tree that only exists in memory,
+// where external tools can't be pointed at the files
+func (c *Runner) image() bool {
+	return c.offline != "" && c.config.Root == ""
+}
+
+func (c *Runner) hostname() string {
+	if c.offline == "" {
+		hostname, _ := os.Hostname()
+		return hostname
+	}
+
+	data, err := readFile(c.fs, "/etc/hostname")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
 }
 
 // Collect will gather complete environment snapshot
 
-func (c *Collector) Collect(ctx context.Context, name string) (*models.EnvironmentSnapshot, error) {
+func (c *Runner) Collect(ctx context.Context, name string) (*models.EnvironmentSnapshot, error) {
 
-	hostname, _ := os.Hostname()
+	hostname := c.hostname()
 
 	snapshot := &models.EnvironmentSnapshot{
 		ID:             uuid.New().String(),
 		Name:           name,
 		Hostname:       hostname,
 		Timestamp:      time.Now().UTC(),
-		OS:             c.collectOSInfo(),
+		OS:             c.collectOSInfo(ctx),
 		Files:          make(map[string]models.FileInfo),
 		EnvVars:        make(map[string]models.EnvVar),
 		ProcessEnvVars: make(map[int]models.ProcessEnvVar),
 		Packages:       make(map[string]models.PackageInfo),
 		Services:       make(map[string]models.ServiceInfo),
 		Metadata:       make(map[string]string),
+		Collectors:     make(map[string]models.CollectorStatus),
+	}
+	if c.offline != "" {
+		snapshot.Metadata["source"] = c.offline
 	}
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	var errs []error
+
+	// Every enabled collector runs concurrently; results are merged under
+	// the lock so collectors never touch the snapshot themselves.
+	for _, col := range Registered() {
+		if !col.Enabled(c.config) {
+			snapshot.Collectors[col.Name()] = models.CollectorStatus{
+				Name:    col.Name(),
+				Status:  "skipped",
+				Message: "disabled in configuration",
+			}
+			continue
+		}
 
-	errChan := make(chan error, 12)
-
-	// Collect Files Concurrently
-	if c.config.Files.Enabled {
 		wg.Add(1)
-		go func() {
+		go func(col Collector) {
 			defer wg.Done()
-			files, err := c.collectFiles(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("File Collection: %w", err)
-				return
+			started := time.Now()
+			result, err, timedOut := c.run(ctx, col)
+			if timedOut && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+				err = nil // reported by markTimedOut instead
+			}
+			status := newStatus(col.Name(), started, result, err)
+			if timedOut {
+				markTimedOut(&status, c.timeout(col))
 			}
 
 			mu.Lock()
-			snapshot.Files = files
-			mu.Unlock()
-		}()
-	}
-
-	// Collect Environment Variables
-
-	if c.config.EnvVars.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			envVars, err := c.collectEnvVars(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("env var collection: %w", err)
-				return
+			defer mu.Unlock()
+			snapshot.Collectors[col.Name()] = status
+			if (status.Status == "failed" || status.Status == "partial") && !onlyNotApplicable(err) {
+				errs = append(errs, fmt.Errorf("%s collection: %s", col.Name(), strings.Join(status.Errors, "; ")))
 			}
-			mu.Lock()
-			snapshot.EnvVars = envVars
-			mu.Unlock()
-		}()
-	}
-
-	if c.config.ProcessEnvVars.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			processEnvVars, err := c.collectProcessEnvVars(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("process env var collection: %w", err)
-				return
+			if status.Status != "failed" && result != nil {
+				col.Merge(snapshot, result)
 			}
-			mu.Lock()
-			snapshot.ProcessEnvVars = processEnvVars
-			mu.Unlock()
-		}()
+		}(col)
 	}
 
-	// Collect Packages
+	wg.Wait()
 
-	if c.config.Packages.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			packages, err := c.collectPackages(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("package collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.Packages = packages
-			mu.Unlock()
-		}()
+	if len(errs) > 0 {
+		return snapshot, fmt.Errorf("collection errors: %v", errs)
 	}
 
-	// Collect Servicess
+	return snapshot, nil
+}
 
-	if c.config.Services.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			services, err := c.collectServices(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("service collection: %w", err)
-				return
-			}
+// abandonGrace is how long a collector may keep running after its deadline to
+// hand back what it gathered so far before the runner stops waiting for it
+const abandonGrace = 2 * time.Second
 
-			mu.Lock()
-			snapshot.Services = services
-			mu.Unlock()
+func (c *Runner) timeout(col Collector) time.Duration {
+	if tc, ok := col.(TimeoutCollector); ok {
+		if timeout := tc.Timeout(c.config); timeout > 0 {
+			return timeout
+		}
+	}
+	return c.config.DefaultTimeout
+}
 
-		}()
+// run executes a single collector within its time budget. A collector that
+// ignores cancellation is abandoned after the grace period, so one stuck
+// command can't hold up the whole snapshot.
+func (c *Runner) run(ctx context.Context, col Collector) (interface{}, error, bool) {
+	var cancel context.CancelFunc
+	if timeout := c.timeout(col); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
 	}
+	defer cancel()
 
-	if c.config.Network.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			network, err := c.collectNetworkConfig(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("network collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.NetworkConfig = network
-			mu.Unlock()
-		}()
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := col.Collect(ctx, c)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err, ctx.Err() != nil
+	case <-ctx.Done():
 	}
 
-	if c.config.Docker.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			docker, err := c.collectDockerConfig(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("docker collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.DockerConfig = docker
-			mu.Unlock()
-		}()
+	select {
+	case o := <-done:
+		return o.result, o.err, true
+	case <-time.After(abandonGrace):
+		return nil, ctx.Err(), true
 	}
+}
 
-	if c.config.SystemResources.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			resources, err := c.collectSystemResources(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("system resources collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.SystemResources = resources
-			mu.Unlock()
-		}()
+// markTimedOut downgrades a status whose collector ran out of time. Whatever
+// it returned is kept, but the data is known to be incomplete.
+func markTimedOut(status *models.CollectorStatus, timeout time.Duration) {
+	status.TimedOut = true
+	msg := "collection cancelled"
+	if timeout > 0 {
+		msg = fmt.Sprintf("timed out after %s", timeout)
 	}
+	status.Errors = append(status.Errors, msg)
 
-	if c.config.ScheduledTasks.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			tasks, err := c.collectScheduledTasks(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("scheduled tasks collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.ScheduledTasks = tasks
-			mu.Unlock()
-		}()
+	switch {
+	case status.ItemCount == 0:
+		status.Status = "failed"
+	case status.Status == "ok":
+		status.Status = "partial"
 	}
+}
 
-	if c.config.Certificates.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			certs, err := c.collectCertificates(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("certificates collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.Certificates = certs
-			mu.Unlock()
-		}()
+// newStatus classifies the outcome of a single collector run
+func newStatus(name string, started time.Time, result interface{}, err error) models.CollectorStatus {
+	status := models.CollectorStatus{
+		Name:       name,
+		Status:     "ok",
+		StartedAt:  started.UTC(),
+		DurationMs: time.Since(started).Milliseconds(),
+		ItemCount:  countItems(result),
 	}
 
-	if c.config.UsersGroups.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			users, err := c.collectUserGroupConfig(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("user/group collection: %w", err)
-				return
-			}
-			mu.Lock()
-			snapshot.UserGroupConfig = users
-			mu.Unlock()
-		}()
+	if err == nil {
+		return status
 	}
 
-	wg.Wait()
-	close(errChan)
+	// PartialError unwraps to its parts' errors, so it has to be checked
+	// before ErrNotApplicable or one inapplicable part would skip them all
+	var partial *PartialError
+	switch {
+	case errors.As(err, &partial):
+		status.Status = "partial"
+		status.FailedParts = partial.Parts
+		notApplicable := 0
+		for i, e := range partial.Errors {
+			status.Errors = append(status.Errors, fmt.Sprintf("%s: %v", partial.Parts[i], e))
+			if errors.Is(e, ErrNotApplicable) {
+				notApplicable++
+			}
+		}
+		// nothing usable came back, the parts that failed were all there was
+		if status.ItemCount == 0 {
+			status.Status = "failed"
+			if notApplicable == len(partial.Errors) {
+				status.Status = "skipped"
+				status.Message = partial.Error()
+				status.Errors = nil
+			}
+		}
+	case errors.Is(err, ErrNotApplicable):
+		status.Status = "skipped"
+		status.Message = err.Error()
+	default:
+		status.Status = "failed"
+		status.Errors = []string{err.Error()}
+	}
 
-	// Collect errors
+	return status
+}
 
-	var errors []error
-	for err := range errChan {
-		errors = append(errors, err)
+// countItems gives a rough item count for a collector result: the length of
+// maps and slices, summed over struct fields for composite results.
+func countItems(result interface{}) int {
+	v := reflect.ValueOf(result)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
 	}
 
-	if len(errChan) > 0 {
-		return snapshot, fmt.Errorf("collection errors: %v", errors)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len()
+	case reflect.Struct:
+		count := 0
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			switch field.Kind() {
+			case reflect.Map, reflect.Slice:
+				count += field.Len()
+			case reflect.Struct:
+				if !field.IsZero() {
+					count++
+				}
+			}
+		}
+		return count
+	case reflect.Invalid:
+		return 0
+	default:
+		return 1
 	}
-
-	return snapshot, nil
 }
 
 // Collecting OS Information
 
-func (c *Collector) collectOSInfo() models.OSInfo {
+func (c *Runner) collectOSInfo(ctx context.Context) models.OSInfo {
+	if c.offline != "" {
+		return models.OSInfo{
+			Name:    runtime.GOOS,
+			Arch:    runtime.GOARCH,
+			Version: readOSRelease(c.fs),
+			Kernel:  installedKernel(c.fs),
+		}
+	}
+
 	return models.OSInfo{
 		Name:    runtime.GOOS,
 		Arch:    runtime.GOARCH,
-		Version: getOSVersion(),
-		Kernel:  getKernelVersion(),
+		Version: getOSVersion(ctx),
+		Kernel:  getKernelVersion(ctx),
 	}
 }
@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+)
+
+const defaultCaptureSize = 64 * 1024
+
+// settingLine splits "key = value", "key: value" and "Key value" lines. The
+// key may be quoted, as in JSON, or follow "export", as in .env files.
+var settingLine = regexp.MustCompile(`^(\s*(?:export\s+)?["']?)([A-Za-z0-9_.\-]+)(["']?\s*[=:]\s*|["']?\s+)(.*?)(\s*)$`)
+
+// contentMatcher tells which files have their text kept, nil when none do.
+// Bad patterns are skipped like bad exclude patterns.
+func (c *Runner) contentMatcher() func(string) bool {
+	capture := c.config.Files.CaptureContent
+	if !capture.Enabled {
+		return nil
+	}
+
+	var matchers []func(string) bool
+	for _, p := range capture.Paths {
+		match, err := pattern.Compile(p)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, match)
+	}
+	if len(matchers) == 0 {
+		return nil
+	}
+
+	return func(path string) bool {
+		for _, match := range matchers {
+			if match(path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// captureContent reads a small text file for FileInfo.Content and tells
+// whether secrets were masked in it. Binary files, files over the size limit
+// and ones that can't be read are left out.
+func (c *Runner) captureContent(path string, size int64) (string, bool) {
+	limit := c.config.Files.CaptureContent.MaxSize
+	if limit <= 0 {
+		limit = defaultCaptureSize
+	}
+	if size > limit {
+		return "", false
+	}
+
+	data, err := readFile(c.fs, path)
+	if err != nil || int64(len(data)) > limit || bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", false
+	}
+
+	text := string(data)
+	masked := false
+	if c.config.Files.CaptureContent.MaskSecrets {
+		text = maskContent(text)
+		masked = text != string(data)
+	}
+
+	content, err := models.CompressContent(text)
+	if err != nil {
+		return "", false
+	}
+	return content, masked
+}
+
+// maskContent hides private keys and the values of settings named like
+// secrets. Switches and numbers are kept, they don't give anything away and
+// a diff of sshd_config would be unreadable without them.
+func maskContent(text string) string {
+	lines := strings.Split(text, "\n")
+	inKey := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "-----BEGIN") && strings.Contains(trimmed, "PRIVATE KEY"):
+			inKey = true
+			continue
+		case inKey && strings.HasPrefix(trimmed, "-----END"):
+			inKey = false
+			continue
+		case inKey:
+			lines[i] = "****"
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+			continue
+		}
+
+		m := settingLine.FindStringSubmatch(line)
+		if m == nil || !isSecretVar(m[2]) {
+			continue
+		}
+		open, value, close := splitValue(m[4])
+		if value == "" || plainValue(value) {
+			continue
+		}
+		lines[i] = m[1] + m[2] + m[3] + open + maskValue(value) + close + m[5]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitValue takes the quotes and the trailing comma of a JSON value, or
+// the quotes of a .env one, off the value itself
+func splitValue(value string) (open, inner, close string) {
+	if v, ok := strings.CutSuffix(value, ","); ok {
+		value = strings.TrimRight(v, " \t")
+		close = v[len(value):] + ","
+	}
+	if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+		return value[:1], value[1 : n-1], value[n-1:] + close
+	}
+	return "", value, close
+}
+
+func plainValue(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "no", "true", "false", "on", "off", "none", "null", "":
+		return true
+	}
+	// a section opening, like "secrets": {, holds no value itself
+	if value == "{" || value == "[" {
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
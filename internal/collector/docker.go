@@ -17,7 +17,16 @@ func formatTimestamp(timestamp float64) string {
 	return time.Unix(int64(timestamp), 0).Format(time.RFC3339)
 }
 
-func (c *Collector) collectDockerConfig(ctx context.Context) (models.DockerConfig, error) {
+// dockerGet issues an API request that is abandoned when ctx is cancelled
+func dockerGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+func (c *Runner) collectDockerConfig(ctx context.Context) (models.DockerConfig, error) {
 	config := models.DockerConfig{
 		Containers: make(map[string]models.Container),
 		Images:     make(map[string]models.Image),
@@ -31,21 +40,26 @@ func (c *Collector) collectDockerConfig(ctx context.Context) (models.DockerConfi
 	}
 
 	if _, err := os.Stat(socketPath); os.IsNotExist(err) {
-		return config, nil
+		return config, fmt.Errorf("%w: docker socket %s not found", ErrNotApplicable, socketPath)
 	}
 
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-			return net.Dial("unix", socketPath)
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
 		},
 	}
 	client := &http.Client{Transport: transport}
 	baseURL := "http://localhost"
 
+	var partial PartialError
+
 	if c.config.Docker.Containers {
 		containers, err := c.collectDockerContainers(ctx, client, baseURL)
 		if err == nil {
 			config.Containers = containers
+		} else {
+			partial.Add("containers", err)
 		}
 	}
 
@@ -53,6 +67,8 @@ func (c *Collector) collectDockerConfig(ctx context.Context) (models.DockerConfi
 		images, err := c.collectDockerImages(ctx, client, baseURL)
 		if err == nil {
 			config.Images = images
+		} else {
+			partial.Add("images", err)
 		}
 	}
 
@@ -60,6 +76,8 @@ func (c *Collector) collectDockerConfig(ctx context.Context) (models.DockerConfi
 		volumes, err := c.collectDockerVolumes(ctx, client, baseURL)
 		if err == nil {
 			config.Volumes = volumes
+		} else {
+			partial.Add("volumes", err)
 		}
 	}
 
@@ -67,16 +85,18 @@ func (c *Collector) collectDockerConfig(ctx context.Context) (models.DockerConfi
 		networks, err := c.collectDockerNetworks(ctx, client, baseURL)
 		if err == nil {
 			config.Networks = networks
+		} else {
+			partial.Add("networks", err)
 		}
 	}
 
-	return config, nil
+	return config, partial.Err()
 }
 
-func (c *Collector) collectDockerContainers(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Container, error) {
+func (c *Runner) collectDockerContainers(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Container, error) {
 	containers := make(map[string]models.Container)
 
-	resp, err := client.Get(baseURL + "/containers/json?all=true")
+	resp, err := dockerGet(ctx, client, baseURL+"/containers/json?all=true")
 	if err != nil {
 		return containers, err
 	}
@@ -153,10 +173,10 @@ func (c *Collector) collectDockerContainers(ctx context.Context, client *http.Cl
 	return containers, nil
 }
 
-func (c *Collector) collectDockerImages(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Image, error) {
+func (c *Runner) collectDockerImages(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Image, error) {
 	images := make(map[string]models.Image)
 
-	resp, err := client.Get(baseURL + "/images/json")
+	resp, err := dockerGet(ctx, client, baseURL+"/images/json")
 	if err != nil {
 		return images, err
 	}
@@ -215,10 +235,10 @@ func (c *Collector) collectDockerImages(ctx context.Context, client *http.Client
 	return images, nil
 }
 
-func (c *Collector) collectDockerVolumes(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Volume, error) {
+func (c *Runner) collectDockerVolumes(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Volume, error) {
 	volumes := make(map[string]models.Volume)
 
-	resp, err := client.Get(baseURL + "/volumes")
+	resp, err := dockerGet(ctx, client, baseURL+"/volumes")
 	if err != nil {
 		return volumes, err
 	}
@@ -258,10 +278,10 @@ func (c *Collector) collectDockerVolumes(ctx context.Context, client *http.Clien
 	return volumes, nil
 }
 
-func (c *Collector) collectDockerNetworks(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Network, error) {
+func (c *Runner) collectDockerNetworks(ctx context.Context, client *http.Client, baseURL string) (map[string]models.Network, error) {
 	networks := make(map[string]models.Network)
 
-	resp, err := client.Get(baseURL + "/networks")
+	resp, err := dockerGet(ctx, client, baseURL+"/networks")
 	if err != nil {
 		return networks, err
 	}
@@ -49,7 +49,7 @@ var secretPatterns = []*regexp.Regexp{
 	regexp.MustCompile(`(?i)(telegram_bot_token|telegram_api_key)`),
 }
 
-func (c *Collector) collectEnvVars(ctx context.Context) (map[string]models.EnvVar, error) {
+func (c *Runner) collectEnvVars(ctx context.Context) (map[string]models.EnvVar, error) {
 	envVars := make(map[string]models.EnvVar)
 
 	// Compile include/exclude patterns
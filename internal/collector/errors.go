@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotApplicable is returned (usually wrapped) by collectors that cannot
+// run in the current environment, such as docker without a socket. The
+// collector is recorded as skipped instead of failed.
+var ErrNotApplicable = errors.New("not applicable")
+
+// PartialError is returned by collectors that produced a usable result but
+// could not read some of their sources. Each failure is tagged with the part
+// it belongs to, so the comparator can ignore only the affected items.
+type PartialError struct {
+	Parts  []string
+	Errors []error
+}
+
+// Add records that part could not be collected
+func (e *PartialError) Add(part string, err error) {
+	e.Parts = append(e.Parts, part)
+	e.Errors = append(e.Errors, err)
+}
+
+// Err returns nil when nothing failed, so collectors can return it directly
+func (e *PartialError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = fmt.Sprintf("%s: %v", e.Parts[i], err)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errors
+}
+
+// onlyNotApplicable reports whether every part of a PartialError failed with
+// ErrNotApplicable, which is expected rather than worth a warning
+func onlyNotApplicable(err error) bool {
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		return false
+	}
+	for _, e := range partial.Errors {
+		if !errors.Is(e, ErrNotApplicable) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,16 +1,19 @@
 package collector
 
 import (
+	"archive/tar"
 	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
-	"os"
+	"io/fs"
 	"os/user"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -22,18 +25,95 @@ func getPathDepth(path, basePath string) int {
 	return len(filepath.SplitList(relPath))
 }
 
-func (c *Collector) calculateFileHash(path string) (string, error) {
-	f, err := os.Open(path)
+// ctxReader stops a long read (hashing a large file) once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// ownerNames maps uids and gids to names through the passwd and group files
+// of an offline tree, where the host's own lookups would give wrong names
+type ownerNames struct {
+	once   sync.Once
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+func (o *ownerNames) load(fsys FS) {
+	o.once.Do(func() {
+		o.users = readIDNames(fsys, "/etc/passwd")
+		o.groups = readIDNames(fsys, "/etc/group")
+	})
+}
+
+// readIDNames reads the name:x:id:... lines of a passwd or group file
+func readIDNames(fsys FS, name string) map[uint32]string {
+	names := make(map[uint32]string)
+	data, err := readFile(fsys, name)
 	if err != nil {
-		return "", err
+		return names
 	}
-	defer f.Close()
 
-	var h interface {
-		io.Writer
-		Sum([]byte) []byte
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		if id, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+			names[uint32(id)] = fields[0]
+		}
+	}
+	return names
+}
+
+func (c *Runner) fileOwner(info fs.FileInfo) (owner, group string) {
+	var uid, gid uint32
+	switch sys := info.Sys().(type) {
+	case *syscall.Stat_t:
+		uid, gid = sys.Uid, sys.Gid
+	case *tar.Header:
+		// image layers carry both ids and names, but the names are often
+		// empty, so they go through the image's passwd like a mounted root
+		uid, gid = uint32(sys.Uid), uint32(sys.Gid)
+	default:
+		return "", ""
+	}
+
+	return c.userName(uid), c.groupName(gid)
+}
+
+// userName resolves a uid on the host being collected from
+func (c *Runner) userName(uid uint32) string {
+	if c.offline != "" {
+		c.owners.load(c.fs)
+		return c.owners.users[uid]
+	}
+	if u, err := user.LookupId(strconv.Itoa(int(uid))); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func (c *Runner) groupName(gid uint32) string {
+	if c.offline != "" {
+		c.owners.load(c.fs)
+		return c.owners.groups[gid]
+	}
+	if g, err := user.LookupGroupId(strconv.Itoa(int(gid))); err == nil {
+		return g.Name
 	}
+	return ""
+}
 
+func (c *Runner) calculateFileHash(ctx context.Context, path string) (string, error) {
+	var h hash.Hash
 	switch c.config.Files.HashAlgo {
 	case "md5":
 		h = md5.New()
@@ -41,14 +121,25 @@ func (c *Collector) calculateFileHash(path string) (string, error) {
 		h = sha256.New()
 	}
 
-	if _, err := io.Copy(h.(io.Writer), f); err != nil {
+	return fileDigest(ctx, c.fs, path, h)
+}
+
+// fileDigest hashes the content of name with h, as lowercase hex
+func fileDigest(ctx context.Context, fsys FS, name string, h hash.Hash) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, ctxReader{ctx, f}); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func (c *Collector) processFile(path string, info os.FileInfo) models.FileInfo {
+func (c *Runner) processFile(ctx context.Context, path string, info fs.FileInfo, capture func(string) bool) models.FileInfo {
 	fileInfo := models.FileInfo{
 		Path:        path,
 		Size:        info.Size(),
@@ -60,30 +151,30 @@ func (c *Collector) processFile(path string, info os.FileInfo) models.FileInfo {
 
 	// Get Owner / group (Unix Specific)
 
-	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
-		if u, err := user.LookupId((strconv.Itoa((int(stat.Uid))))); err == nil {
-			fileInfo.Owner = u.Username
-		}
-
-		if g, err := user.LookupGroupId(strconv.Itoa(int(stat.Gid))); err == nil {
-			fileInfo.Group = g.Name
-		}
-	}
+	fileInfo.Owner, fileInfo.Group = c.fileOwner(info)
 
 	// Calculating the has for files (not directories)
 
 	// we will be skipping file sizes greater than 100 MB
 	if !info.IsDir() && info.Size() < 100*1024*1024 {
-		hash, err := c.calculateFileHash(path)
+		hash, err := c.calculateFileHash(ctx, path)
 		if err == nil {
 			fileInfo.Hash = hash
 		}
 	}
 
+	if !info.IsDir() {
+		fileInfo.Package = c.packages.owner(c.fs, path)
+	}
+
+	if capture != nil && info.Mode().IsRegular() && capture(path) {
+		fileInfo.Content, fileInfo.Masked = c.captureContent(path, info.Size())
+	}
+
 	return fileInfo
 }
 
-func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInfo, error) {
+func (c *Runner) collectFiles(ctx context.Context) (map[string]models.FileInfo, error) {
 
 	files := make(map[string]models.FileInfo)
 
@@ -101,10 +192,12 @@ func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInf
 		excludePatterns = append(excludePatterns, re)
 	}
 
+	capture := c.contentMatcher()
+
 	// Worker pool for the file processing
 	type fileJob struct {
 		path string
-		info os.FileInfo
+		info fs.FileInfo
 	}
 
 	jobs := make(chan fileJob, 1000)
@@ -119,13 +212,10 @@ func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInf
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				select {
-				case <-ctx.Done():
+				if ctx.Err() != nil {
 					return
-				default:
-					fileInfo := c.processFile(job.path, job.info)
-					results <- fileInfo
 				}
+				results <- c.processFile(ctx, job.path, job.info, capture)
 			}
 		}()
 	}
@@ -139,8 +229,15 @@ func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInf
 
 	// walk torugh directores annd send jobs
 	go func() {
+		// jobs must be closed however the walk ends, or the workers never exit
+		defer close(jobs)
+
 		for _, basePath := range c.config.Files.Paths {
-			filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return
+			}
+
+			walk(c.fs, basePath, func(path string, info fs.FileInfo, err error) error {
 				if err != nil {
 					return nil // skip the files we are not allowed to have access to
 				}
@@ -174,11 +271,16 @@ func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInf
 					}
 				}
 
-				jobs <- fileJob{path: path, info: info}
-				return nil
+				// the workers stop on cancellation, so a plain send could
+				// block forever once the buffer fills up
+				select {
+				case jobs <- fileJob{path: path, info: info}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 			})
 		}
-		close(jobs)
 	}()
 
 	// Collect results
@@ -189,6 +291,7 @@ func (c *Collector) collectFiles(ctx context.Context) (map[string]models.FileInf
 		mu.Unlock()
 	}
 
-	return files, nil
+	// a cancelled walk still returns what it found, the runner marks it incomplete
+	return files, ctx.Err()
 
 }
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FS is what collectors read host files through. Names are absolute host
+// paths like "/etc/passwd"; an FS maps them onto wherever the files actually
+// live, so snapshots of a mounted image use the same keys as live ones.
+type FS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// hostFS reads the running host's own filesystem
+type hostFS struct{}
+
+func (hostFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (hostFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (hostFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (hostFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+// maxSymlinks bounds symlink resolution, same as the Linux kernel
+const maxSymlinks = 40
+
+var errTooManyLinks = errors.New("too many levels of symbolic links")
+
+// rootFS reads a filesystem tree mounted or extracted under root. Symlinks
+// are resolved inside the tree: an absolute link target such as
+// /usr/share/zoneinfo/UTC points into root, never at the host.
+type rootFS struct {
+	root string
+}
+
+func (r rootFS) Open(name string) (fs.File, error) {
+	real, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(real)
+}
+
+func (r rootFS) Stat(name string) (fs.FileInfo, error) {
+	real, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(real)
+}
+
+func (r rootFS) Lstat(name string) (fs.FileInfo, error) {
+	real, err := r.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(real)
+}
+
+func (r rootFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	real, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(real)
+}
+
+func (r rootFS) real(name string) string {
+	return filepath.Join(r.root, filepath.FromSlash(name))
+}
+
+// resolve maps name to a real path under root, following symlinks in every
+// component (and in the last one too if followLast is set). ".." never climbs
+// above root.
+func (r rootFS) resolve(name string, followLast bool) (string, error) {
+	parts := splitPath(name)
+	resolved := "/"
+	hops := 0
+
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		if part == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		if len(parts) == 0 && !followLast {
+			resolved = next
+			break
+		}
+
+		// a missing component is left for the final operation to report
+		info, err := os.Lstat(r.real(next))
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinks {
+			return "", &fs.PathError{Op: "open", Path: name, Err: errTooManyLinks}
+		}
+
+		target, err := os.Readlink(r.real(next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		parts = append(splitPath(target), parts...)
+	}
+
+	return r.real(resolved), nil
+}
+
+func splitPath(name string) []string {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func readFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// walk is filepath.Walk over an FS: symlinks are reported, not followed, and
+// the callback may return filepath.SkipDir or filepath.SkipAll
+func walk(fsys FS, root string, fn filepath.WalkFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, name string, info fs.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(name, info, nil)
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err := fn(name, info, err); err != nil || len(entries) == 0 {
+		return err
+	}
+
+	for _, entry := range entries {
+		child := filepath.Join(name, entry.Name())
+		childInfo, err := fsys.Lstat(child)
+		if err != nil {
+			if err := fn(child, childInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		if err := walkDir(fsys, child, childInfo, fn); err != nil {
+			if !childInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
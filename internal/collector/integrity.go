@@ -0,0 +1,427 @@
+package collector
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/rpmdb"
+)
+
+// packagedFile is what a package manager recorded about one file it installed
+type packagedFile struct {
+	path    string
+	pkg     string // package key, "dpkg:coreutils"
+	version string
+	config  bool
+	algo    string // md5, sha1, sha256...
+	digest  string
+	mode    int64 // permission bits, -1 when not recorded
+	owner   string
+	group   string
+}
+
+// collectPackageIntegrity verifies package-owned files against the checksums
+// recorded by dpkg and rpm, like debsums and rpm -V do
+func (c *Runner) collectPackageIntegrity(ctx context.Context) (models.PackageIntegrity, error) {
+	result := models.PackageIntegrity{
+		Checked: make(map[string]int),
+		Issues:  make(map[string]models.PackageFileIssue),
+	}
+	var partial PartialError
+
+	readers := make(map[string]func(FS) ([]packagedFile, error))
+	for _, manager := range c.config.PackageIntegrity.Managers {
+		switch manager {
+		case "apt", "dpkg":
+			readers["dpkg"] = readDpkgFiles
+		case "yum", "rpm":
+			readers["rpm"] = readRpmFiles
+		}
+	}
+
+	var files []packagedFile
+	for manager, read := range readers {
+		listed, err := read(c.fs)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("%w: %v", ErrNotApplicable, err)
+		}
+		if err != nil {
+			partial.Add(manager, err)
+			continue
+		}
+		files = append(files, listed...)
+		result.Checked[manager] = 0
+	}
+
+	files = c.filterPackagedFiles(files)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan packagedFile)
+
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				issue, checked := c.verifyPackagedFile(ctx, f)
+				if !checked {
+					continue
+				}
+				mu.Lock()
+				result.Checked[packageManager(f.pkg)]++
+				if issue != nil {
+					result.Issues[issue.Path] = *issue
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, partial.Err()
+}
+
+func packageManager(key string) string {
+	manager, _, _ := strings.Cut(key, ":")
+	return manager
+}
+
+// filterPackagedFiles applies the configured paths and exclude patterns
+func (c *Runner) filterPackagedFiles(files []packagedFile) []packagedFile {
+	cfg := c.config.PackageIntegrity
+
+	var exclude []*regexp.Regexp
+	for _, pattern := range cfg.ExcludePaths {
+		if re, err := regexp.Compile(pattern); err == nil {
+			exclude = append(exclude, re)
+		}
+	}
+
+	kept := files[:0]
+	for _, f := range files {
+		if len(cfg.Paths) > 0 && !underAny(f.path, cfg.Paths) {
+			continue
+		}
+		excluded := false
+		for _, re := range exclude {
+			if re.MatchString(f.path) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = strings.TrimSuffix(dir, "/")
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyPackagedFile checks one file. It reports false when the file could
+// not be checked at all (unreadable, or too large to hold in an image).
+func (c *Runner) verifyPackagedFile(ctx context.Context, f packagedFile) (*models.PackageFileIssue, bool) {
+	issue := &models.PackageFileIssue{
+		Path:    f.path,
+		Package: f.pkg,
+		Version: f.version,
+		Config:  f.config,
+	}
+
+	info, err := c.fs.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		issue.Problems = []string{"missing"}
+		issue.ExpectedHash = f.digest
+		return issue, true
+	}
+	if err != nil || info.IsDir() {
+		return nil, false
+	}
+
+	h := newDigest(f.algo)
+	if h == nil {
+		return nil, false
+	}
+	actual, err := fileDigest(ctx, c.fs, f.path, h)
+	if err != nil {
+		return nil, false
+	}
+	if !strings.EqualFold(actual, f.digest) {
+		issue.Problems = append(issue.Problems, "checksum")
+		issue.ExpectedHash = f.digest
+		issue.ActualHash = actual
+	}
+
+	if f.mode >= 0 {
+		if mode := unixPerm(info.Mode()); mode != f.mode {
+			issue.Problems = append(issue.Problems, "mode")
+			issue.ExpectedMode = fmt.Sprintf("%04o", f.mode)
+			issue.ActualMode = fmt.Sprintf("%04o", mode)
+		}
+	}
+
+	if f.owner != "" || f.group != "" {
+		// ids without a name (no passwd entry) can't be compared
+		owner, group := c.fileOwner(info)
+		ownerChanged := owner != "" && f.owner != "" && owner != f.owner
+		groupChanged := group != "" && f.group != "" && group != f.group
+		if ownerChanged {
+			issue.Problems = append(issue.Problems, "owner")
+		}
+		if groupChanged {
+			issue.Problems = append(issue.Problems, "group")
+		}
+		if ownerChanged || groupChanged {
+			issue.ExpectedOwner = f.owner + ":" + f.group
+			issue.ActualOwner = owner + ":" + group
+		}
+	}
+
+	if len(issue.Problems) == 0 {
+		return nil, true
+	}
+	return issue, true
+}
+
+// unixPerm turns a FileMode back into the permission bits rpm records,
+// setuid, setgid and sticky included
+func unixPerm(mode fs.FileMode) int64 {
+	perm := int64(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+func newDigest(algo string) hash.Hash {
+	switch algo {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "sha384":
+		return sha512.New384()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// readDpkgFiles lists the files of installed dpkg packages with their md5
+// sums. Conffiles come from the status file, everything else from the
+// info/<package>.md5sums lists.
+func readDpkgFiles(fsys FS) ([]packagedFile, error) {
+	data, err := readFile(fsys, "/var/lib/dpkg/status")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	conffiles := make(map[string]packagedFile)
+	for _, stanza := range readStanzas(data, ":") {
+		name := stanza["Package"]
+		if name == "" || !strings.HasSuffix(stanza["Status"], " installed") {
+			continue
+		}
+		versions[name] = stanza["Version"]
+
+		// " /etc/ssh/sshd_config 8caefdd9e251b7cc1baa37874149a870 [obsolete]"
+		for _, line := range strings.Split(stanza["Conffiles"], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || len(fields) > 2 && fields[2] == "obsolete" || fields[1] == "newconffile" {
+				continue
+			}
+			conffiles[fields[0]] = packagedFile{
+				path:    fields[0],
+				pkg:     "dpkg:" + name,
+				version: stanza["Version"],
+				config:  true,
+				algo:    "md5",
+				digest:  fields[1],
+				mode:    -1,
+			}
+		}
+	}
+
+	diversions := readDpkgDiversions(fsys)
+
+	entries, err := fsys.ReadDir("/var/lib/dpkg/info")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []packagedFile
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".md5sums") {
+			continue
+		}
+		// multi-arch packages use "libc6:amd64.md5sums"
+		name, _, _ := strings.Cut(strings.TrimSuffix(e.Name(), ".md5sums"), ":")
+		version, installed := versions[name]
+		if !installed {
+			continue
+		}
+
+		sums, err := readFile(fsys, "/var/lib/dpkg/info/"+e.Name())
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(sums), "\n") {
+			digest, path, ok := strings.Cut(line, "  ")
+			if !ok {
+				continue
+			}
+			path = "/" + path
+			if _, isConf := conffiles[path]; isConf {
+				continue
+			}
+			// a diverted file was moved aside by another package, the
+			// content dpkg knows about lives at the new name
+			if d, ok := diversions[path]; ok && d.pkg != name {
+				path = d.to
+			}
+			files = append(files, packagedFile{
+				path:    path,
+				pkg:     "dpkg:" + name,
+				version: version,
+				algo:    "md5",
+				digest:  digest,
+				mode:    -1,
+			})
+		}
+	}
+
+	for _, f := range conffiles {
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+type dpkgDiversion struct {
+	to  string
+	pkg string // ":" for a local diversion
+}
+
+// readDpkgDiversions reads /var/lib/dpkg/diversions, three lines per entry:
+// the diverted path, where it was moved to and the package that did it
+func readDpkgDiversions(fsys FS) map[string]dpkgDiversion {
+	diversions := make(map[string]dpkgDiversion)
+	data, err := readFile(fsys, "/var/lib/dpkg/diversions")
+	if err != nil {
+		return diversions
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i := 0; i+2 < len(lines); i += 3 {
+		diversions[lines[i]] = dpkgDiversion{to: lines[i+1], pkg: lines[i+2]}
+	}
+	return diversions
+}
+
+// rpm's PGPHASHALGO values for the file digest algorithm tag
+var rpmDigestAlgos = map[int64]string{1: "md5", 2: "sha1", 8: "sha256", 9: "sha384", 10: "sha512"}
+
+// readRpmFiles lists the regular files of installed rpm packages with their
+// digests, permissions and owners
+func readRpmFiles(fsys FS) ([]packagedFile, error) {
+	headers, err := readRpmHeaders(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []packagedFile
+	for _, h := range headers {
+		name := h.String(rpmdb.TagName)
+		if name == "" {
+			continue
+		}
+
+		algo := "md5" // packages built before rpm 4.6 don't record one
+		if h.Has(rpmdb.TagFileDigestAlgo) {
+			algo = rpmDigestAlgos[h.Int(rpmdb.TagFileDigestAlgo)]
+		}
+
+		paths := h.Files()
+		digests := h.Strings(rpmdb.TagFileDigests)
+		modes := h.Ints(rpmdb.TagFileModes)
+		flags := h.Ints(rpmdb.TagFileFlags)
+		states := h.Ints(rpmdb.TagFileStates)
+		users := h.Strings(rpmdb.TagFileUsername)
+		groups := h.Strings(rpmdb.TagFileGroupname)
+		version := rpmVersion(h)
+
+		for i, path := range paths {
+			// directories, symlinks and ghost files have no digest
+			if i >= len(digests) || digests[i] == "" {
+				continue
+			}
+			if i < len(flags) && flags[i]&rpmdb.FileGhost != 0 {
+				continue
+			}
+			if i < len(states) && states[i] != rpmdb.FileNormal {
+				continue
+			}
+
+			f := packagedFile{
+				path:    path,
+				pkg:     "rpm:" + name,
+				version: version,
+				config:  i < len(flags) && flags[i]&rpmdb.FileConfig != 0,
+				algo:    algo,
+				digest:  digests[i],
+				mode:    -1,
+			}
+			if i < len(modes) {
+				f.mode = modes[i] & 07777
+			}
+			if i < len(users) {
+				f.owner = users[i]
+			}
+			if i < len(groups) {
+				f.group = groups[i]
+			}
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
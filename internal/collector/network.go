@@ -2,8 +2,10 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -11,17 +13,21 @@ import (
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectNetworkConfig(ctx context.Context) (models.NetworkConfig, error) {
+func (c *Runner) collectNetworkConfig(ctx context.Context) (models.NetworkConfig, error) {
 	config := models.NetworkConfig{
 		Interfaces: make(map[string]models.NetworkInterface),
 		Routes:     []models.Route{},
 		DNS:        models.DNSConfig{},
 	}
 
+	var partial PartialError
+
 	if c.config.Network.Interfaces {
 		interfaces, err := c.collectNetworkInterfaces(ctx)
 		if err == nil {
 			config.Interfaces = interfaces
+		} else {
+			partial.Add("interfaces", err)
 		}
 	}
 
@@ -29,6 +35,8 @@ func (c *Collector) collectNetworkConfig(ctx context.Context) (models.NetworkCon
 		routes, err := c.collectRoutes(ctx)
 		if err == nil {
 			config.Routes = routes
+		} else {
+			partial.Add("routes", err)
 		}
 	}
 
@@ -36,6 +44,8 @@ func (c *Collector) collectNetworkConfig(ctx context.Context) (models.NetworkCon
 		dns, err := c.collectDNS(ctx)
 		if err == nil {
 			config.DNS = dns
+		} else {
+			partial.Add("dns", err)
 		}
 	}
 
@@ -43,15 +53,21 @@ func (c *Collector) collectNetworkConfig(ctx context.Context) (models.NetworkCon
 		rules, err := c.collectFirewallRules(ctx)
 		if err == nil {
 			config.FirewallRules = rules
+		} else {
+			partial.Add("firewall_rules", err)
 		}
 	}
 
-	return config, nil
+	return config, partial.Err()
 }
 
-func (c *Collector) collectNetworkInterfaces(ctx context.Context) (map[string]models.NetworkInterface, error) {
+func (c *Runner) collectNetworkInterfaces(ctx context.Context) (map[string]models.NetworkInterface, error) {
 	interfaces := make(map[string]models.NetworkInterface)
 
+	if err := c.requireLive(); err != nil {
+		return interfaces, err
+	}
+
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return interfaces, err
@@ -89,9 +105,13 @@ func (c *Collector) collectNetworkInterfaces(ctx context.Context) (map[string]mo
 	return interfaces, nil
 }
 
-func (c *Collector) collectRoutes(ctx context.Context) ([]models.Route, error) {
+func (c *Runner) collectRoutes(ctx context.Context) ([]models.Route, error) {
 	var routes []models.Route
 
+	if err := c.requireLive(); err != nil {
+		return routes, err
+	}
+
 	if runtime.GOOS == "darwin" {
 		return c.collectRoutesDarwin(ctx)
 	} else if runtime.GOOS == "linux" {
@@ -101,7 +121,7 @@ func (c *Collector) collectRoutes(ctx context.Context) ([]models.Route, error) {
 	return routes, nil
 }
 
-func (c *Collector) collectRoutesDarwin(ctx context.Context) ([]models.Route, error) {
+func (c *Runner) collectRoutesDarwin(ctx context.Context) ([]models.Route, error) {
 	var routes []models.Route
 
 	cmd := exec.CommandContext(ctx, "netstat", "-nr")
@@ -132,7 +152,7 @@ func (c *Collector) collectRoutesDarwin(ctx context.Context) ([]models.Route, er
 	return routes, nil
 }
 
-func (c *Collector) collectRoutesLinux(ctx context.Context) ([]models.Route, error) {
+func (c *Runner) collectRoutesLinux(ctx context.Context) ([]models.Route, error) {
 	var routes []models.Route
 
 	cmd := exec.CommandContext(ctx, "ip", "route")
@@ -182,14 +202,14 @@ func (c *Collector) collectRoutesLinux(ctx context.Context) ([]models.Route, err
 	return routes, nil
 }
 
-func (c *Collector) collectDNS(ctx context.Context) (models.DNSConfig, error) {
+func (c *Runner) collectDNS(ctx context.Context) (models.DNSConfig, error) {
 	dns := models.DNSConfig{
 		Nameservers:   []string{},
 		SearchDomains: []string{},
 	}
 
 	if runtime.GOOS == "darwin" {
-		data, err := os.ReadFile("/etc/resolv.conf")
+		data, err := c.readResolvConf()
 		if err != nil {
 			return dns, err
 		}
@@ -210,7 +230,7 @@ func (c *Collector) collectDNS(ctx context.Context) (models.DNSConfig, error) {
 			}
 		}
 	} else if runtime.GOOS == "linux" {
-		data, err := os.ReadFile("/etc/resolv.conf")
+		data, err := c.readResolvConf()
 		if err != nil {
 			return dns, err
 		}
@@ -235,14 +255,29 @@ func (c *Collector) collectDNS(ctx context.Context) (models.DNSConfig, error) {
 	return dns, nil
 }
 
-func (c *Collector) collectFirewallRules(ctx context.Context) ([]models.FirewallRule, error) {
+// readResolvConf reads the DNS settings. Offline trees and images usually
+// get theirs at boot or container start, so there a missing file means
+// there is nothing to compare rather than a failure.
+func (c *Runner) readResolvConf() ([]byte, error) {
+	data, err := readFile(c.fs, "/etc/resolv.conf")
+	if errors.Is(err, fs.ErrNotExist) && c.offline != "" {
+		return nil, fmt.Errorf("%w: no /etc/resolv.conf in %s", ErrNotApplicable, c.offline)
+	}
+	return data, err
+}
+
+func (c *Runner) collectFirewallRules(ctx context.Context) ([]models.FirewallRule, error) {
 	var rules []models.FirewallRule
 
+	if err := c.requireLive(); err != nil {
+		return rules, err
+	}
+
 	if runtime.GOOS == "darwin" {
 		cmd := exec.CommandContext(ctx, "pfctl", "-s", "rules")
 		output, err := cmd.Output()
 		if err != nil {
-			return rules, nil
+			return rules, err
 		}
 
 		lines := strings.Split(string(output), "\n")
@@ -260,7 +295,7 @@ func (c *Collector) collectFirewallRules(ctx context.Context) ([]models.Firewall
 		cmd := exec.CommandContext(ctx, "iptables", "-L", "-n")
 		output, err := cmd.Output()
 		if err != nil {
-			return rules, nil
+			return rules, err
 		}
 
 		lines := strings.Split(string(output), "\n")
@@ -1,51 +1,74 @@
 package collector
 
 import (
-	"os"
+	"context"
 	"os/exec"
 	"runtime"
 	"strings"
 )
 
-func getOSVersion() string {
+func getOSVersion(ctx context.Context) string {
 	switch runtime.GOOS {
 	case "linux":
-		return getLinuxVersion()
+		return getLinuxVersion(ctx)
 	case "darwin":
-		return getMacOSVersion()
+		return getMacOSVersion(ctx)
 	case "windows":
-		return getWindowsVersion()
+		return getWindowsVersion(ctx)
 	default:
 		return "unknown"
 	}
 }
 
-func getLinuxVersion() string {
+func getLinuxVersion(ctx context.Context) string {
 	// Trying /etc/os-release first
 
-	data, err := os.ReadFile("/etc/os-release")
-	if err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "PRETTY_NAME=") {
-				return strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), "\"")
-			}
-		}
+	if version := readOSRelease(hostFS{}); version != "" {
+		return version
 	}
 
 	// Falling back for lsb release
 
-	cmd := exec.Command("lsb_release", "-d", "-s")
+	cmd := exec.CommandContext(ctx, "lsb_release", "-d", "-s")
 	output, err := cmd.Output()
-	if err != nil {
+	if err == nil {
 		return strings.TrimSpace(string(output))
 	}
 
 	return "Linux"
 }
 
-func getMacOSVersion() string {
-	cmd := exec.Command("sw_vers", "-productVersion")
+// readOSRelease returns PRETTY_NAME from os-release, or "" if there is none
+func readOSRelease(fsys FS) string {
+	for _, name := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		data, err := readFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "PRETTY_NAME=") {
+				return strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), "\"")
+			}
+		}
+	}
+	return ""
+}
+
+// installedKernel guesses the kernel of an offline tree from its modules
+// directory; with several installed the highest sorting one wins
+func installedKernel(fsys FS) string {
+	for _, dir := range []string{"/lib/modules", "/usr/lib/modules"} {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		return entries[len(entries)-1].Name()
+	}
+	return "unknown"
+}
+
+func getMacOSVersion(ctx context.Context) string {
+	cmd := exec.CommandContext(ctx, "sw_vers", "-productVersion")
 	output, err := cmd.Output()
 	if err != nil {
 		return "macOS"
@@ -54,8 +77,8 @@ func getMacOSVersion() string {
 	return "macOS" + strings.TrimSpace(string(output))
 }
 
-func getWindowsVersion() string {
-	cmd := exec.Command("cmd", "/c", "ver")
+func getWindowsVersion(ctx context.Context) string {
+	cmd := exec.CommandContext(ctx, "cmd", "/c", "ver")
 	output, err := cmd.Output()
 	if err != nil {
 		return "Windows"
@@ -64,10 +87,10 @@ func getWindowsVersion() string {
 	return strings.TrimSpace(string(output))
 }
 
-func getKernelVersion() string {
+func getKernelVersion(ctx context.Context) string {
 	switch runtime.GOOS {
 	case "linux", "darwin":
-		cmd := exec.Command("uname", "-r")
+		cmd := exec.CommandContext(ctx, "uname", "-r")
 		output, err := cmd.Output()
 		if err != nil {
 			return "unknown"
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"context"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,12 +17,13 @@ import (
 
 type packageCollector func(ctx context.Context) (map[string]models.PackageInfo, error)
 
-func (c *Collector) collectPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
 	var mu sync.Mutex
 	var wg sync.WaitGroup
+	var partial PartialError
 
 	collectors := make(map[string]packageCollector)
 
@@ -46,35 +49,58 @@ func (c *Collector) collectPackages(ctx context.Context) (map[string]models.Pack
 
 	}
 
+	// these ask tools installed on the running host, they can't look into
+	// another root
+	liveOnly := map[string]bool{"go": true, "brew": true}
+
 	for name, collector := range collectors {
+		if liveOnly[name] {
+			if err := c.requireLive(); err != nil {
+				partial.Add(name, err)
+				continue
+			}
+		}
+
 		wg.Add(1)
 		go func(name string, collect packageCollector) {
 			defer wg.Done()
 			pkgs, err := collect(ctx)
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				return // skipping if error
+				// recorded per manager so a failing dpkg is not mistaken
+				// for every package having been removed
+				partial.Add(name, err)
+				return
 			}
-			mu.Lock()
 			for k, v := range pkgs {
 				packages[name+":"+k] = v
 			}
-			mu.Unlock()
 		}(name, collector)
 	}
 
 	wg.Wait()
-	return packages, nil
+	return packages, partial.Err()
 
 }
 
-func (c *Collector) collectDpkgPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectDpkgPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 	packages := make(map[string]models.PackageInfo)
 
+	native, err := readDpkgStatus(c.fs)
+	if err == nil || c.image() {
+		return native, err
+	}
+
 	if runtime.GOOS != "linux" {
 		return packages, nil
 	}
 
-	cmd := exec.CommandContext(ctx, "dpkg-query", "-W", "-f=${Package}\t${Version}\t${Architecture}\n")
+	args := []string{"-W", "-f=${Package}\t${Version}\t${Architecture}\t${source:Package}\t${Status}\t${Installed-Size}\t${Maintainer}\n"}
+	if c.config.Root != "" {
+		args = append(args, "--admindir="+filepath.Join(c.config.Root, "var/lib/dpkg"))
+	}
+	cmd := exec.CommandContext(ctx, "dpkg-query", args...)
 
 	output, err := cmd.Output()
 
@@ -87,12 +113,17 @@ func (c *Collector) collectDpkgPackages(ctx context.Context) (map[string]models.
 	for scanner.Scan() {
 		parts := strings.Split(scanner.Text(), "\t")
 		if len(parts) >= 2 {
+			size, _ := strconv.ParseInt(safeIndex(parts, 5), 10, 64)
 			packages[parts[0]] = models.PackageInfo{
-				Name:         parts[0],
-				Version:      parts[1],
-				Architecture: safeIndex(parts, 2),
-				Manager:      "dpkg",
-				Exists:       true,
+				Name:          parts[0],
+				Version:       parts[1],
+				Architecture:  safeIndex(parts, 2),
+				Manager:       "dpkg",
+				Exists:        true,
+				Source:        safeIndex(parts, 3),
+				Status:        safeIndex(parts, 4),
+				InstalledSize: size * 1024,
+				Maintainer:    safeIndex(parts, 6),
 			}
 		}
 	}
@@ -100,20 +131,30 @@ func (c *Collector) collectDpkgPackages(ctx context.Context) (map[string]models.
 	return packages, nil
 }
 
-func (c *Collector) collectRpmPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectRpmPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
+	// BerkeleyDB databases can't be read natively, the rpm tool still can
+	native, err := readRpmPackages(c.fs)
+	if err == nil || c.image() {
+		return native, err
+	}
+
 	if runtime.GOOS != "linux" {
 		return packages, nil
 	}
 
-	cmd := exec.CommandContext(ctx, "rpm", "-qa", "--queryformat", "%{NAME}\t%{VERSION}-%{RELEASE}\t%{ARCH}\n")
+	args := []string{"-qa", "--queryformat", "%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\t%{SOURCERPM}\t%{LONGSIZE}\t%|PACKAGER?{%{PACKAGER}}:{%{VENDOR}}|\n"}
+	if c.config.Root != "" {
+		args = append(args, "--root", c.config.Root)
+	}
+	cmd := exec.CommandContext(ctx, "rpm", args...)
 
 	output, err := cmd.Output()
 
 	if err != nil {
-		return packages, nil
+		return packages, err
 	}
 
 	scanner := bufio.NewScanner(bytes.NewReader(output))
@@ -121,23 +162,37 @@ func (c *Collector) collectRpmPackages(ctx context.Context) (map[string]models.P
 	for scanner.Scan() {
 		parts := strings.Split(scanner.Text(), "\t")
 		if len(parts) >= 2 {
+			size, _ := strconv.ParseInt(safeIndex(parts, 4), 10, 64)
 			packages[parts[0]] = models.PackageInfo{
-				Name:         parts[0],
-				Version:      parts[1],
-				Architecture: safeIndex(parts, 2),
-				Manager:      "rpm",
-				Exists:       true,
+				Name:          parts[0],
+				Version:       parts[1],
+				Architecture:  safeIndex(parts, 2),
+				Manager:       "rpm",
+				Exists:        true,
+				Source:        srpmName(safeIndex(parts, 3)),
+				Status:        "installed",
+				InstalledSize: size,
+				Maintainer:    safeIndex(parts, 5),
 			}
 		}
 	}
 	return packages, nil
 }
 
-func (c *Collector) collectApkPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectApkPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
-	cmd := exec.CommandContext(ctx, "apk", "list", "--installed")
+	native, err := readApkInstalled(c.fs)
+	if err == nil || c.image() {
+		return native, err
+	}
+
+	args := []string{"list", "--installed"}
+	if c.config.Root != "" {
+		args = append(args, "--root", c.config.Root)
+	}
+	cmd := exec.CommandContext(ctx, "apk", args...)
 
 	output, err := cmd.Output()
 
@@ -166,10 +221,15 @@ func (c *Collector) collectApkPackages(ctx context.Context) (map[string]models.P
 	return packages, nil
 }
 
-func (c *Collector) collectPipPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectPipPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
+	native, err := readPythonPackages(c.fs)
+	if err == nil || c.offline != "" {
+		return native, err
+	}
+
 	// Will try pip3 first, then pip
 
 	pipCmd := "pip3"
@@ -202,10 +262,15 @@ func (c *Collector) collectPipPackages(ctx context.Context) (map[string]models.P
 	return packages, nil
 }
 
-func (c *Collector) collectNpmPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectNpmPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
+	native, err := readNpmGlobal(c.fs)
+	if err == nil || c.offline != "" {
+		return native, err
+	}
+
 	cmd := exec.CommandContext(ctx, "npm", "list", "-g", "--depth=0", "--json")
 
 	output, err := cmd.Output()
@@ -237,7 +302,7 @@ func (c *Collector) collectNpmPackages(ctx context.Context) (map[string]models.P
 	return packages, nil
 }
 
-func (c *Collector) collectGoModules(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectGoModules(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
@@ -267,7 +332,7 @@ func (c *Collector) collectGoModules(ctx context.Context) (map[string]models.Pac
 	return packages, nil
 }
 
-func (c *Collector) collectBrewPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
+func (c *Runner) collectBrewPackages(ctx context.Context) (map[string]models.PackageInfo, error) {
 
 	packages := make(map[string]models.PackageInfo)
 
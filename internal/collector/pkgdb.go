@@ -0,0 +1,494 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/rpmdb"
+)
+
+// Package databases read straight from the filesystem. These work on
+// minimal hosts, offline roots and images alike; the package tools are only
+// asked when the database can't be read.
+
+// readStanzas splits an RFC 822 style file (dpkg status, apk installed) into
+// paragraphs of key/value fields. Continuation lines are appended to the
+// previous field.
+func readStanzas(data []byte, sep string) []map[string]string {
+	var stanzas []map[string]string
+	current := make(map[string]string)
+	last := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				stanzas = append(stanzas, current)
+				current = make(map[string]string)
+			}
+			last = ""
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && last != "" {
+			current[last] += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		last = key
+		current[key] = strings.TrimSpace(value)
+	}
+
+	if len(current) > 0 {
+		stanzas = append(stanzas, current)
+	}
+	return stanzas
+}
+
+// readDpkgStatus lists the packages in /var/lib/dpkg/status, leaving out
+// ones dpkg only remembers as not installed
+func readDpkgStatus(fsys FS) (map[string]models.PackageInfo, error) {
+	packages := make(map[string]models.PackageInfo)
+
+	data, err := readFile(fsys, "/var/lib/dpkg/status")
+	if err != nil {
+		return packages, err
+	}
+
+	for _, stanza := range readStanzas(data, ":") {
+		name := stanza["Package"]
+		if name == "" || strings.HasSuffix(stanza["Status"], "not-installed") {
+			continue
+		}
+
+		// "Source: openssl (3.0.11-1)" when the versions differ, and no
+		// field at all when the source package has the same name
+		source := name
+		if fields := strings.Fields(stanza["Source"]); len(fields) > 0 {
+			source = fields[0]
+		}
+		size, _ := strconv.ParseInt(stanza["Installed-Size"], 10, 64)
+
+		packages[name] = models.PackageInfo{
+			Name:          name,
+			Version:       stanza["Version"],
+			Architecture:  stanza["Architecture"],
+			Manager:       "dpkg",
+			Exists:        true,
+			Source:        source,
+			Status:        stanza["Status"],
+			InstalledSize: size * 1024,
+			Maintainer:    stanza["Maintainer"],
+		}
+	}
+
+	return packages, nil
+}
+
+// readApkInstalled lists the packages in /lib/apk/db/installed
+func readApkInstalled(fsys FS) (map[string]models.PackageInfo, error) {
+	packages := make(map[string]models.PackageInfo)
+
+	data, err := readFile(fsys, "/lib/apk/db/installed")
+	if err != nil {
+		return packages, err
+	}
+
+	for _, stanza := range readStanzas(data, ":") {
+		name := stanza["P"]
+		if name == "" {
+			continue
+		}
+		size, _ := strconv.ParseInt(stanza["I"], 10, 64)
+
+		packages[name] = models.PackageInfo{
+			Name:          name,
+			Version:       stanza["V"],
+			Architecture:  stanza["A"],
+			Manager:       "apk",
+			Exists:        true,
+			Source:        stanza["o"],
+			Status:        "installed",
+			InstalledSize: size,
+			Maintainer:    stanza["m"],
+		}
+	}
+
+	return packages, nil
+}
+
+// rpm moved its database to /usr/lib/sysimage/rpm; /var/lib/rpm is usually
+// a symlink to it, but not in every image
+var rpmDBDirs = []string{"/var/lib/rpm", "/usr/lib/sysimage/rpm"}
+
+var errRpmBerkeleyDB = errors.New("BerkeleyDB rpm databases can only be read with the rpm tool")
+
+// readRpmHeaders loads every installed package header from the rpm database
+func readRpmHeaders(fsys FS) ([]*rpmdb.Header, error) {
+	for _, dir := range rpmDBDirs {
+		if data, err := readFile(fsys, dir+"/rpmdb.sqlite"); err == nil {
+			wal, _ := readFile(fsys, dir+"/rpmdb.sqlite-wal")
+			return rpmdb.FromSQLite(data, wal)
+		}
+		if data, err := readFile(fsys, dir+"/Packages.db"); err == nil {
+			return rpmdb.FromNDB(data)
+		}
+	}
+
+	for _, dir := range rpmDBDirs {
+		if _, err := fsys.Stat(dir + "/Packages"); err == nil {
+			return nil, errRpmBerkeleyDB
+		}
+	}
+	return nil, fmt.Errorf("no rpm database found: %w", fs.ErrNotExist)
+}
+
+func readRpmPackages(fsys FS) (map[string]models.PackageInfo, error) {
+	packages := make(map[string]models.PackageInfo)
+
+	headers, err := readRpmHeaders(fsys)
+	if err != nil {
+		return packages, err
+	}
+
+	for _, h := range headers {
+		name := h.String(rpmdb.TagName)
+		if name == "" {
+			continue
+		}
+
+		size := h.Int(rpmdb.TagLongSize)
+		if size == 0 {
+			size = h.Int(rpmdb.TagSize)
+		}
+		maintainer := h.String(rpmdb.TagPackager)
+		if maintainer == "" {
+			maintainer = h.String(rpmdb.TagVendor)
+		}
+
+		packages[name] = models.PackageInfo{
+			Name:          name,
+			Version:       rpmVersion(h),
+			Architecture:  h.String(rpmdb.TagArch),
+			Manager:       "rpm",
+			Exists:        true,
+			Source:        srpmName(h.String(rpmdb.TagSourceRPM)),
+			Status:        "installed",
+			InstalledSize: size,
+			Maintainer:    maintainer,
+		}
+	}
+
+	return packages, nil
+}
+
+// rpmVersion formats [epoch:]version-release, the same as the rpm query
+// format used by the command line fallback
+func rpmVersion(h *rpmdb.Header) string {
+	version := h.String(rpmdb.TagVersion) + "-" + h.String(rpmdb.TagRelease)
+	if h.Has(rpmdb.TagEpoch) {
+		version = strconv.FormatInt(h.Int(rpmdb.TagEpoch), 10) + ":" + version
+	}
+	return version
+}
+
+// srpmName turns "openssl-3.0.7-24.el9.src.rpm" into "openssl"
+func srpmName(srpm string) string {
+	name := strings.TrimSuffix(srpm, ".src.rpm")
+	name = strings.TrimSuffix(name, ".nosrc.rpm")
+	for i := 0; i < 2; i++ {
+		if idx := strings.LastIndex(name, "-"); idx > 0 {
+			name = name[:idx]
+		}
+	}
+	return name
+}
+
+// pythonLibDirs are searched in sys.path order, so a package installed in
+// /usr/local shadows the distribution's copy like it does for pip
+var pythonLibDirs = []string{"/usr/local/lib", "/usr/lib", "/usr/lib64"}
+
+// readPythonPackages lists the distributions installed in the system
+// site-packages and dist-packages directories
+func readPythonPackages(fsys FS) (map[string]models.PackageInfo, error) {
+	packages := make(map[string]models.PackageInfo)
+
+	var dirs []string
+	for _, lib := range pythonLibDirs {
+		entries, err := fsys.ReadDir(lib)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), "python") {
+				continue
+			}
+			for _, sub := range []string{"site-packages", "dist-packages"} {
+				dir := lib + "/" + e.Name() + "/" + sub
+				if info, err := fsys.Stat(dir); err == nil && info.IsDir() {
+					dirs = append(dirs, dir)
+				}
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return packages, fmt.Errorf("no python site-packages found: %w", fs.ErrNotExist)
+	}
+
+	for _, dir := range dirs {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			var metadata string
+			switch {
+			case strings.HasSuffix(e.Name(), ".dist-info"):
+				metadata = dir + "/" + e.Name() + "/METADATA"
+			case strings.HasSuffix(e.Name(), ".egg-info") && e.IsDir():
+				metadata = dir + "/" + e.Name() + "/PKG-INFO"
+			case strings.HasSuffix(e.Name(), ".egg-info"):
+				metadata = dir + "/" + e.Name()
+			default:
+				continue
+			}
+
+			data, err := readFile(fsys, metadata)
+			if err != nil {
+				continue
+			}
+			stanzas := readStanzas(data, ":")
+			if len(stanzas) == 0 || stanzas[0]["Name"] == "" {
+				continue
+			}
+			meta := stanzas[0]
+
+			name := meta["Name"]
+			if _, seen := packages[name]; seen {
+				continue
+			}
+			maintainer := meta["Maintainer"]
+			if maintainer == "" {
+				maintainer = meta["Author"]
+			}
+
+			packages[name] = models.PackageInfo{
+				Name:          name,
+				Version:       meta["Version"],
+				Manager:       "pip",
+				Exists:        true,
+				InstalledSize: pythonRecordSize(fsys, dir+"/"+e.Name()+"/RECORD"),
+				Maintainer:    maintainer,
+			}
+		}
+	}
+
+	return packages, nil
+}
+
+// pythonRecordSize sums the sizes listed in a dist-info RECORD file
+func pythonRecordSize(fsys FS, record string) int64 {
+	data, err := readFile(fsys, record)
+	if err != nil {
+		return 0
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, _ := r.ReadAll()
+
+	var total int64
+	for _, row := range rows {
+		if len(row) >= 3 {
+			size, _ := strconv.ParseInt(row[2], 10, 64)
+			total += size
+		}
+	}
+	return total
+}
+
+var npmGlobalDirs = []string{"/usr/local/lib/node_modules", "/usr/lib/node_modules"}
+
+// readNpmGlobal lists globally installed npm packages, the same set as
+// `npm list -g --depth=0`
+func readNpmGlobal(fsys FS) (map[string]models.PackageInfo, error) {
+	packages := make(map[string]models.PackageInfo)
+
+	found := false
+	for _, dir := range npmGlobalDirs {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		found = true
+
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			if !strings.HasPrefix(e.Name(), "@") {
+				readNpmPackage(fsys, dir+"/"+e.Name(), packages)
+				continue
+			}
+
+			// scoped packages live one level down, @scope/name
+			scoped, err := fsys.ReadDir(dir + "/" + e.Name())
+			if err != nil {
+				continue
+			}
+			for _, s := range scoped {
+				readNpmPackage(fsys, dir+"/"+e.Name()+"/"+s.Name(), packages)
+			}
+		}
+	}
+
+	if !found {
+		return packages, fmt.Errorf("no global node_modules found: %w", fs.ErrNotExist)
+	}
+	return packages, nil
+}
+
+func readNpmPackage(fsys FS, dir string, packages map[string]models.PackageInfo) {
+	data, err := readFile(fsys, dir+"/package.json")
+	if err != nil {
+		return
+	}
+
+	var manifest struct {
+		Name    string          `json:"name"`
+		Version string          `json:"version"`
+		Author  json.RawMessage `json:"author"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Name == "" {
+		return
+	}
+	if _, seen := packages[manifest.Name]; seen {
+		return
+	}
+
+	// author is either "Name <email>" or {"name": ..., "email": ...}
+	var author string
+	if err := json.Unmarshal(manifest.Author, &author); err != nil {
+		var person struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(manifest.Author, &person)
+		author = person.Name
+	}
+
+	packages[manifest.Name] = models.PackageInfo{
+		Name:       manifest.Name,
+		Version:    manifest.Version,
+		Manager:    "npm",
+		Exists:     true,
+		Maintainer: author,
+	}
+}
+
+// packageFiles maps installed paths to the package that owns them, so file
+// drift can be traced back to package changes. It is loaded on first use.
+type packageFiles struct {
+	once  sync.Once
+	paths map[string]string
+}
+
+func (p *packageFiles) owner(fsys FS, path string) string {
+	p.once.Do(func() {
+		p.paths = make(map[string]string)
+		readDpkgLists(fsys, p.paths)
+		readRpmLists(fsys, p.paths)
+		readApkLists(fsys, p.paths)
+	})
+	return p.paths[path]
+}
+
+// readDpkgLists reads the info/<package>.list files, one path per line
+func readDpkgLists(fsys FS, paths map[string]string) {
+	entries, err := fsys.ReadDir("/var/lib/dpkg/info")
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".list") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimSuffix(e.Name(), ".list"), ":")
+		data, err := readFile(fsys, "/var/lib/dpkg/info/"+e.Name())
+		if err != nil {
+			continue
+		}
+		for _, path := range strings.Split(string(data), "\n") {
+			if path == "" || path == "/." {
+				continue
+			}
+			if _, owned := paths[path]; !owned {
+				paths[path] = "dpkg:" + name
+			}
+		}
+	}
+}
+
+func readRpmLists(fsys FS, paths map[string]string) {
+	headers, err := readRpmHeaders(fsys)
+	if err != nil {
+		return
+	}
+
+	for _, h := range headers {
+		name := h.String(rpmdb.TagName)
+		for _, path := range h.Files() {
+			if _, owned := paths[path]; !owned {
+				paths[path] = "rpm:" + name
+			}
+		}
+	}
+}
+
+// readApkLists reads the F: (directory) and R: (file in that directory)
+// lines of each package in /lib/apk/db/installed
+func readApkLists(fsys FS, paths map[string]string) {
+	data, err := readFile(fsys, "/lib/apk/db/installed")
+	if err != nil {
+		return
+	}
+
+	var name, dir string
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "P":
+			name, dir = value, ""
+		case "F":
+			dir = value
+		case "R":
+			path := "/" + value
+			if dir != "" {
+				path = "/" + dir + "/" + value
+			}
+			if _, owned := paths[path]; !owned && name != "" {
+				paths[path] = "apk:" + name
+			}
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/sqlite"
+)
+
+// collectPackageHistory reads the transaction history of the configured
+// package managers, oldest first
+func (c *Runner) collectPackageHistory(ctx context.Context) ([]models.PackageTransaction, error) {
+	var history []models.PackageTransaction
+	var partial PartialError
+
+	readers := make(map[string]func() ([]models.PackageTransaction, error))
+	for _, manager := range c.config.Packages.Managers {
+		switch manager {
+		case "apt", "dpkg":
+			readers["dpkg"] = c.readDpkgHistory
+		case "yum", "rpm":
+			readers["rpm"] = c.readRpmHistory
+		case "apk":
+			readers["apk"] = c.readApkHistory
+		}
+	}
+
+	for manager, read := range readers {
+		if ctx.Err() != nil {
+			return history, ctx.Err()
+		}
+		transactions, err := read()
+		if err != nil {
+			partial.Add(manager, err)
+		}
+		history = append(history, transactions...)
+	}
+
+	if days := c.config.Packages.HistoryDays; days > 0 {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		kept := history[:0]
+		for _, t := range history {
+			if !t.Start.Before(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		history = kept
+	}
+
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Start.Before(history[j].Start) })
+	return history, partial.Err()
+}
+
+// readRotatedLogs returns the content of a log and its rotations (name.1,
+// name.2.gz, ...), oldest first
+func readRotatedLogs(fsys FS, name string) ([][]byte, error) {
+	dir, base := path.Split(name)
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type rotation struct {
+		n    int
+		name string
+	}
+	var rotations []rotation
+	for _, e := range entries {
+		if e.Name() == base {
+			rotations = append(rotations, rotation{0, e.Name()})
+			continue
+		}
+		suffix, ok := strings.CutPrefix(e.Name(), base+".")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(suffix, ".gz")); err == nil {
+			rotations = append(rotations, rotation{n, e.Name()})
+		}
+	}
+	if len(rotations) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	sort.Slice(rotations, func(i, j int) bool { return rotations[i].n > rotations[j].n })
+
+	var logs [][]byte
+	for _, r := range rotations {
+		data, err := readFile(fsys, dir+r.name)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(r.name, ".gz") {
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			data, err = io.ReadAll(gz)
+			if err != nil {
+				continue
+			}
+		}
+		logs = append(logs, data)
+	}
+	return logs, nil
+}
+
+// readDpkgHistory combines apt's history log, which knows the command and
+// the user, with dpkg's own log for packages installed with dpkg directly
+func (c *Runner) readDpkgHistory() ([]models.PackageTransaction, error) {
+	aptLogs, aptErr := readRotatedLogs(c.fs, "/var/log/apt/history.log")
+	dpkgLogs, dpkgErr := readRotatedLogs(c.fs, "/var/log/dpkg.log")
+	if aptErr != nil && dpkgErr != nil {
+		return nil, fmt.Errorf("%w: no apt or dpkg logs", ErrNotApplicable)
+	}
+
+	var apt []models.PackageTransaction
+	for _, data := range aptLogs {
+		apt = append(apt, parseAptHistory(data)...)
+	}
+
+	history := apt
+	for _, data := range dpkgLogs {
+		for _, t := range parseDpkgLog(data) {
+			// apt runs dpkg for every change it makes; those are already
+			// covered with more detail
+			changes := t.Changes[:0]
+			for _, change := range t.Changes {
+				if !coveredByApt(apt, change.Package, t.Start) {
+					changes = append(changes, change)
+				}
+			}
+			if len(changes) > 0 {
+				t.Changes = changes
+				history = append(history, t)
+			}
+		}
+	}
+	return history, nil
+}
+
+func coveredByApt(apt []models.PackageTransaction, pkg string, at time.Time) bool {
+	for _, t := range apt {
+		end := t.End
+		if end.IsZero() {
+			end = t.Start.Add(time.Hour)
+		}
+		if at.Before(t.Start) || at.After(end) {
+			continue
+		}
+		for _, change := range t.Changes {
+			if change.Package == pkg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// "openssl:amd64 (3.0.9-1, 3.0.11-1)" and "libfoo:amd64 (2.0, automatic)"
+var aptPackageRe = regexp.MustCompile(`([^\s,]+) \(([^)]*)\)`)
+
+// parseAptHistory reads the blank-line separated entries of
+// /var/log/apt/history.log
+func parseAptHistory(data []byte) []models.PackageTransaction {
+	var history []models.PackageTransaction
+
+	for _, entry := range readStanzas(data, ": ") {
+		start, err := parseLogTime(entry["Start-Date"])
+		if err != nil {
+			continue
+		}
+		t := models.PackageTransaction{
+			ID:      "apt:" + start.UTC().Format(time.RFC3339),
+			Manager: "dpkg",
+			Start:   start,
+			Command: entry["Commandline"],
+		}
+		t.End, _ = parseLogTime(entry["End-Date"])
+
+		// "Requested-By: alice (1000)" is only there when run through sudo
+		if user, _, ok := strings.Cut(entry["Requested-By"], " ("); ok {
+			t.User = user
+		} else if t.Command != "" {
+			t.User = "root"
+		}
+
+		for _, field := range []string{"Install", "Upgrade", "Downgrade", "Reinstall", "Remove", "Purge"} {
+			for _, m := range aptPackageRe.FindAllStringSubmatch(entry[field], -1) {
+				name, _, _ := strings.Cut(m[1], ":")
+				versions := strings.Split(m[2], ", ")
+				change := models.PackageChange{
+					Package: "dpkg:" + name,
+					Action:  strings.ToLower(field),
+				}
+				switch field {
+				case "Upgrade", "Downgrade":
+					change.From = versions[0]
+					if len(versions) > 1 {
+						change.To = versions[1]
+					}
+				case "Remove", "Purge":
+					change.Action = "remove"
+					change.From = versions[0]
+				default:
+					change.To = versions[0]
+				}
+				t.Changes = append(t.Changes, change)
+			}
+		}
+
+		if len(t.Changes) > 0 {
+			history = append(history, t)
+		}
+	}
+	return history
+}
+
+// parseDpkgLog reads /var/log/dpkg.log. Every dpkg run starts with a
+// "startup" line, which is used to group the changes into transactions.
+func parseDpkgLog(data []byte) []models.PackageTransaction {
+	var history []models.PackageTransaction
+	var current *models.PackageTransaction
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// 2024-03-01 10:15:02 upgrade openssl:amd64 3.0.9-1 3.0.11-1
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		at, err := parseLogTime(fields[0] + " " + fields[1])
+		if err != nil {
+			continue
+		}
+
+		if fields[2] == "startup" || current == nil {
+			history = append(history, models.PackageTransaction{
+				ID:      "dpkg:" + at.UTC().Format(time.RFC3339),
+				Manager: "dpkg",
+				Start:   at,
+			})
+			current = &history[len(history)-1]
+		}
+		current.End = at
+
+		if len(fields) < 6 {
+			continue
+		}
+		var action string
+		switch fields[2] {
+		case "install", "upgrade":
+			action = fields[2]
+		case "remove", "purge":
+			action = "remove"
+		default:
+			continue
+		}
+
+		name, _, _ := strings.Cut(fields[3], ":")
+		change := models.PackageChange{Package: "dpkg:" + name, Action: action}
+		if fields[4] != "<none>" {
+			change.From = fields[4]
+		}
+		if fields[5] != "<none>" {
+			change.To = fields[5]
+		}
+		// a purge after a remove repeats the package
+		if n := len(current.Changes); action == "remove" && n > 0 && current.Changes[n-1].Package == change.Package && current.Changes[n-1].Action == "remove" {
+			continue
+		}
+		current.Changes = append(current.Changes, change)
+	}
+
+	kept := history[:0]
+	for _, t := range history {
+		if len(t.Changes) > 0 {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// parseLogTime parses the local "2024-03-01 10:15:02" timestamps of the apt
+// and dpkg logs; apt pads with two spaces
+func parseLogTime(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02 15:04:05", strings.Join(strings.Fields(s), " "), time.Local)
+}
+
+// readRpmHistory reads dnf's history database, or yum.log on hosts that
+// predate dnf
+func (c *Runner) readRpmHistory() ([]models.PackageTransaction, error) {
+	if data, err := readFile(c.fs, "/var/lib/dnf/history.sqlite"); err == nil {
+		wal, _ := readFile(c.fs, "/var/lib/dnf/history.sqlite-wal")
+		return c.parseDnfHistory(data, wal)
+	}
+
+	logs, err := readRotatedLogs(c.fs, "/var/log/yum.log")
+	if err != nil {
+		return nil, fmt.Errorf("%w: no dnf history or yum.log", ErrNotApplicable)
+	}
+
+	// yum.log has no year in its timestamps
+	year := time.Now().Year()
+	if info, err := c.fs.Stat("/var/log/yum.log"); err == nil {
+		year = info.ModTime().Year()
+	}
+
+	var history []models.PackageTransaction
+	for _, data := range logs {
+		history = append(history, parseYumLog(data, year)...)
+	}
+	return history, nil
+}
+
+// dnf trans_item actions, from libdnf's TransactionItemAction
+var dnfActions = map[int64]string{
+	1: "install", 2: "downgrade", 3: "downgraded", 4: "install", 5: "remove",
+	6: "upgrade", 7: "upgraded", 8: "remove", 9: "reinstall",
+}
+
+// parseDnfHistory reads the trans, trans_item and rpm tables of dnf's
+// history.sqlite
+func (c *Runner) parseDnfHistory(data, wal []byte) ([]models.PackageTransaction, error) {
+	db, err := sqlite.Open(data, wal)
+	if err != nil {
+		return nil, err
+	}
+
+	trans, err := tableByName(db, "trans")
+	if err != nil {
+		return nil, err
+	}
+	items, err := tableByName(db, "trans_item")
+	if err != nil {
+		return nil, err
+	}
+	rpms, err := tableByName(db, "rpm")
+	if err != nil {
+		return nil, err
+	}
+
+	type nevra struct{ name, version string }
+	packages := make(map[int64]nevra)
+	for _, row := range rpms {
+		version := row.text("version") + "-" + row.text("release")
+		if epoch := row.int("epoch"); epoch > 0 {
+			version = strconv.FormatInt(epoch, 10) + ":" + version
+		}
+		packages[row.int("item_id")] = nevra{row.text("name"), version}
+	}
+
+	byID := make(map[int64]*models.PackageTransaction)
+	var order []int64
+	for _, row := range trans {
+		id := row.int("id")
+		t := &models.PackageTransaction{
+			ID:      "dnf:" + strconv.FormatInt(id, 10),
+			Manager: "rpm",
+			Start:   time.Unix(row.int("dt_begin"), 0),
+			Command: row.text("cmdline"),
+			User:    c.userName(uint32(row.int("user_id"))),
+		}
+		if end := row.int("dt_end"); end > 0 {
+			t.End = time.Unix(end, 0)
+		}
+		byID[id] = t
+		order = append(order, id)
+	}
+
+	// an upgrade is two items, the new package and the one it replaced
+	changes := make(map[int64]map[string]*models.PackageChange)
+	for _, row := range items {
+		const stateError = 2
+		if row.int("state") == stateError {
+			continue
+		}
+		action, ok := dnfActions[row.int("action")]
+		pkg, known := packages[row.int("item_id")]
+		tid := row.int("trans_id")
+		if !ok || !known || byID[tid] == nil {
+			continue
+		}
+
+		if changes[tid] == nil {
+			changes[tid] = make(map[string]*models.PackageChange)
+		}
+		change := changes[tid][pkg.name]
+		if change == nil {
+			change = &models.PackageChange{Package: "rpm:" + pkg.name}
+			changes[tid][pkg.name] = change
+		}
+		switch action {
+		case "upgraded", "downgraded":
+			change.From = pkg.version
+		case "remove":
+			change.Action = action
+			change.From = pkg.version
+		default:
+			change.Action = action
+			change.To = pkg.version
+		}
+	}
+
+	var history []models.PackageTransaction
+	for _, id := range order {
+		t := byID[id]
+		names := make([]string, 0, len(changes[id]))
+		for name := range changes[id] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if change := changes[id][name]; change.Action != "" {
+				t.Changes = append(t.Changes, *change)
+			}
+		}
+		if len(t.Changes) > 0 {
+			history = append(history, *t)
+		}
+	}
+	return history, nil
+}
+
+// namedRow reads row values by column name
+type namedRow struct {
+	row     sqlite.Row
+	columns map[string]int
+}
+
+func (r namedRow) int(column string) int64 {
+	if i, ok := r.columns[column]; ok {
+		return r.row.Int(i)
+	}
+	return 0
+}
+
+func (r namedRow) text(column string) string {
+	if i, ok := r.columns[column]; ok {
+		return r.row.Text(i)
+	}
+	return ""
+}
+
+func tableByName(db *sqlite.DB, table string) ([]namedRow, error) {
+	columns, err := db.Columns(table)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Rows(table)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	named := make([]namedRow, len(rows))
+	for i, row := range rows {
+		named[i] = namedRow{row, index}
+	}
+	return named, nil
+}
+
+// parseYumLog reads "Mar 01 10:15:02 Updated: openssl-1:1.0.2k-19.el7.x86_64"
+// lines. Lines less than a minute apart are taken as one transaction.
+func parseYumLog(data []byte, year int) []models.PackageTransaction {
+	var history []models.PackageTransaction
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if len(line) < 16 {
+			continue
+		}
+		at, err := time.ParseInLocation("2006 Jan _2 15:04:05", fmt.Sprintf("%d %s", year, line[:15]), time.Local)
+		if err != nil {
+			continue
+		}
+		verb, nevra, ok := strings.Cut(strings.TrimSpace(line[15:]), ": ")
+		if !ok {
+			continue
+		}
+
+		name, version := splitNEVRA(nevra)
+		change := models.PackageChange{Package: "rpm:" + name}
+		switch verb {
+		case "Installed":
+			change.Action, change.To = "install", version
+		case "Updated":
+			change.Action, change.To = "upgrade", version
+		case "Erased":
+			change.Action, change.From = "remove", version
+		default:
+			continue
+		}
+
+		if n := len(history); n > 0 && at.Sub(history[n-1].End) < time.Minute {
+			history[n-1].End = at
+			history[n-1].Changes = append(history[n-1].Changes, change)
+			continue
+		}
+		history = append(history, models.PackageTransaction{
+			ID:      "yum:" + at.UTC().Format(time.RFC3339),
+			Manager: "rpm",
+			Start:   at,
+			End:     at,
+			Changes: []models.PackageChange{change},
+		})
+	}
+	return history
+}
+
+// splitNEVRA splits "openssl-1:1.0.2k-19.el7.x86_64" into the name and the
+// "1:1.0.2k-19.el7" version. yum.log puts the epoch in front instead
+// ("1:openssl-1.0.2k-19.el7.x86_64"), and Erased lines sometimes carry only
+// the name.
+func splitNEVRA(nevra string) (name, version string) {
+	epoch := ""
+	if e, rest, ok := strings.Cut(nevra, ":"); ok && e != "" && strings.Trim(e, "0123456789") == "" {
+		epoch, nevra = e+":", rest
+	}
+
+	nevr := nevra
+	if i := strings.LastIndex(nevra, "."); i > 0 && !strings.Contains(nevra[i:], "-") {
+		nevr = nevra[:i] // the architecture
+	}
+
+	release := strings.LastIndex(nevr, "-")
+	if release <= 0 {
+		return nevra, ""
+	}
+	ver := strings.LastIndex(nevr[:release], "-")
+	if ver <= 0 || nevr[ver+1] < '0' || nevr[ver+1] > '9' {
+		return nevra, ""
+	}
+	version = nevr[ver+1:]
+	if epoch != "" && !strings.Contains(version, ":") {
+		version = epoch + version
+	}
+	return nevr[:ver], version
+}
+
+// readApkHistory: apk keeps no history, but its installed database is
+// rewritten on every change, so its modification time says when packages
+// last changed
+func (c *Runner) readApkHistory() ([]models.PackageTransaction, error) {
+	info, err := c.fs.Stat("/lib/apk/db/installed")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotApplicable, err)
+	}
+	return []models.PackageTransaction{{
+		ID:      "apk:" + info.ModTime().UTC().Format(time.RFC3339),
+		Manager: "apk",
+		Start:   info.ModTime(),
+	}}, nil
+}
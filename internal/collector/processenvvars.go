@@ -14,7 +14,7 @@ import (
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectProcessEnvVars(ctx context.Context) (map[int]models.ProcessEnvVar, error) {
+func (c *Runner) collectProcessEnvVars(ctx context.Context) (map[int]models.ProcessEnvVar, error) {
 	processEnvVars := make(map[int]models.ProcessEnvVar)
 
 	if runtime.GOOS == "windows" {
@@ -54,7 +54,7 @@ func (c *Collector) collectProcessEnvVars(ctx context.Context) (map[int]models.P
 	return c.collectProcessEnvVarsLinux(ctx, procMap, maxProcs, excludePatterns)
 }
 
-func (c *Collector) collectProcessEnvVarsLinux(ctx context.Context, procMap map[string]bool, maxProcs int, excludePatterns []*regexp.Regexp) (map[int]models.ProcessEnvVar, error) {
+func (c *Runner) collectProcessEnvVarsLinux(ctx context.Context, procMap map[string]bool, maxProcs int, excludePatterns []*regexp.Regexp) (map[int]models.ProcessEnvVar, error) {
 	processEnvVars := make(map[int]models.ProcessEnvVar)
 
 	procsDir := "/proc"
@@ -172,7 +172,7 @@ func (c *Collector) collectProcessEnvVarsLinux(ctx context.Context, procMap map[
 	return processEnvVars, nil
 }
 
-func (c *Collector) collectProcessEnvVarsDarwin(ctx context.Context, procMap map[string]bool, maxProcs int, excludePatterns []*regexp.Regexp) (map[int]models.ProcessEnvVar, error) {
+func (c *Runner) collectProcessEnvVarsDarwin(ctx context.Context, procMap map[string]bool, maxProcs int, excludePatterns []*regexp.Regexp) (map[int]models.ProcessEnvVar, error) {
 	processEnvVars := make(map[int]models.ProcessEnvVar)
 
 	for procName := range procMap {
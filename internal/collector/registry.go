@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// Collector is a single unit of snapshot collection. The built-in collectors
+// and any registered from other packages go through the same registry.
+type Collector interface {
+	// Name identifies the collector in errors and, for extension
+	// collectors, is the key its results are stored under.
+	Name() string
+
+	// Enabled reports whether the collector should run for this config.
+	Enabled(config models.CollectorConfig) bool
+
+	// Collect gathers the collector's data. It must not modify the snapshot.
+	Collect(ctx context.Context, r *Runner) (interface{}, error)
+
+	// Merge stores a result returned by Collect into the snapshot. Calls
+	// to Merge are serialised by the runner.
+	Merge(snapshot *models.EnvironmentSnapshot, result interface{})
+}
+
+// TimeoutCollector is implemented by collectors with their own time budget.
+// Collectors without one use CollectorConfig.DefaultTimeout.
+type TimeoutCollector interface {
+	Timeout(config models.CollectorConfig) time.Duration
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Collector
+)
+
+// Register adds a collector to the registry. It is meant to be called from an
+// init function; registering the same name twice panics.
+func Register(col Collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range registry {
+		if existing.Name() == col.Name() {
+			panic(fmt.Sprintf("collector: %q registered twice", col.Name()))
+		}
+	}
+	registry = append(registry, col)
+}
+
+// Registered returns the registered collectors in registration order.
+func Registered() []Collector {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	cols := make([]Collector, len(registry))
+	copy(cols, registry)
+	return cols
+}
+
+// funcCollector adapts plain functions to the Collector interface
+type funcCollector[T any] struct {
+	name    string
+	enabled func(models.CollectorConfig) bool
+	timeout func(models.CollectorConfig) time.Duration
+	collect func(*Runner, context.Context) (T, error)
+	merge   func(*models.EnvironmentSnapshot, T)
+}
+
+func (f funcCollector[T]) Name() string { return f.name }
+
+func (f funcCollector[T]) Enabled(config models.CollectorConfig) bool {
+	return f.enabled(config)
+}
+
+func (f funcCollector[T]) Timeout(config models.CollectorConfig) time.Duration {
+	if f.timeout == nil {
+		return 0
+	}
+	return f.timeout(config)
+}
+
+func (f funcCollector[T]) Collect(ctx context.Context, r *Runner) (interface{}, error) {
+	return f.collect(r, ctx)
+}
+
+func (f funcCollector[T]) Merge(snapshot *models.EnvironmentSnapshot, result interface{}) {
+	if v, ok := result.(T); ok {
+		f.merge(snapshot, v)
+	}
+}
+
+// NewExtension builds a collector whose results are stored in the snapshot's
+// extension section under name. The enabled check defaults to the
+// "enabled" key of the collector's entry in CollectorConfig.Extensions, and
+// its "timeout" key (a duration string) sets the time budget.
+func NewExtension(name string, collect func(ctx context.Context, r *Runner) (interface{}, error)) Collector {
+	return funcCollector[interface{}]{
+		name: name,
+		enabled: func(config models.CollectorConfig) bool {
+			enabled, _ := config.Extensions[name]["enabled"].(bool)
+			return enabled
+		},
+		timeout: func(config models.CollectorConfig) time.Duration {
+			raw, _ := config.Extensions[name]["timeout"].(string)
+			timeout, _ := time.ParseDuration(raw)
+			return timeout
+		},
+		collect: func(r *Runner, ctx context.Context) (interface{}, error) {
+			return collect(ctx, r)
+		},
+		merge: func(snapshot *models.EnvironmentSnapshot, result interface{}) {
+			snapshot.SetExtension(name, result)
+		},
+	}
+}
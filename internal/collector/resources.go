@@ -11,13 +11,17 @@ import (
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectSystemResources(ctx context.Context) (models.SystemResources, error) {
+func (c *Runner) collectSystemResources(ctx context.Context) (models.SystemResources, error) {
 	resources := models.SystemResources{}
 
+	var partial PartialError
+
 	if c.config.SystemResources.CPU {
 		cpu, err := c.collectCPUInfo(ctx)
 		if err == nil {
 			resources.CPU = cpu
+		} else {
+			partial.Add("cpu", err)
 		}
 	}
 
@@ -25,6 +29,8 @@ func (c *Collector) collectSystemResources(ctx context.Context) (models.SystemRe
 		memory, err := c.collectMemoryInfo(ctx)
 		if err == nil {
 			resources.Memory = memory
+		} else {
+			partial.Add("memory", err)
 		}
 	}
 
@@ -32,6 +38,8 @@ func (c *Collector) collectSystemResources(ctx context.Context) (models.SystemRe
 		disks, err := c.collectDiskInfo(ctx)
 		if err == nil {
 			resources.Disks = disks
+		} else {
+			partial.Add("disks", err)
 		}
 	}
 
@@ -39,18 +47,22 @@ func (c *Collector) collectSystemResources(ctx context.Context) (models.SystemRe
 		load, err := c.collectLoadAverage(ctx)
 		if err == nil {
 			resources.LoadAverage = load
+		} else {
+			partial.Add("load", err)
 		}
 	}
 
 	procCount, err := c.getProcessCount(ctx)
 	if err == nil {
 		resources.ProcessCount = procCount
+	} else {
+		partial.Add("process_count", err)
 	}
 
-	return resources, nil
+	return resources, partial.Err()
 }
 
-func (c *Collector) collectCPUInfo(ctx context.Context) (models.CPUInfo, error) {
+func (c *Runner) collectCPUInfo(ctx context.Context) (models.CPUInfo, error) {
 	cpu := models.CPUInfo{}
 
 	if runtime.GOOS == "darwin" {
@@ -63,7 +75,7 @@ func (c *Collector) collectCPUInfo(ctx context.Context) (models.CPUInfo, error)
 	return cpu, nil
 }
 
-func (c *Collector) collectCPUInfoDarwin(ctx context.Context) (models.CPUInfo, error) {
+func (c *Runner) collectCPUInfoDarwin(ctx context.Context) (models.CPUInfo, error) {
 	cpu := models.CPUInfo{}
 
 	cmd := exec.CommandContext(ctx, "sysctl", "-n", "hw.ncpu")
@@ -112,7 +124,7 @@ func (c *Collector) collectCPUInfoDarwin(ctx context.Context) (models.CPUInfo, e
 	return cpu, nil
 }
 
-func (c *Collector) collectCPUInfoLinux(ctx context.Context) (models.CPUInfo, error) {
+func (c *Runner) collectCPUInfoLinux(ctx context.Context) (models.CPUInfo, error) {
 	cpu := models.CPUInfo{}
 
 	data, err := os.ReadFile("/proc/cpuinfo")
@@ -165,7 +177,7 @@ func (c *Collector) collectCPUInfoLinux(ctx context.Context) (models.CPUInfo, er
 	return cpu, nil
 }
 
-func (c *Collector) collectMemoryInfo(ctx context.Context) (models.MemoryInfo, error) {
+func (c *Runner) collectMemoryInfo(ctx context.Context) (models.MemoryInfo, error) {
 	memory := models.MemoryInfo{}
 
 	if runtime.GOOS == "darwin" {
@@ -177,7 +189,7 @@ func (c *Collector) collectMemoryInfo(ctx context.Context) (models.MemoryInfo, e
 	return memory, nil
 }
 
-func (c *Collector) collectMemoryInfoDarwin(ctx context.Context) (models.MemoryInfo, error) {
+func (c *Runner) collectMemoryInfoDarwin(ctx context.Context) (models.MemoryInfo, error) {
 	memory := models.MemoryInfo{}
 
 	cmd := exec.CommandContext(ctx, "vm_stat")
@@ -240,7 +252,7 @@ func (c *Collector) collectMemoryInfoDarwin(ctx context.Context) (models.MemoryI
 	return memory, nil
 }
 
-func (c *Collector) collectMemoryInfoLinux(ctx context.Context) (models.MemoryInfo, error) {
+func (c *Runner) collectMemoryInfoLinux(ctx context.Context) (models.MemoryInfo, error) {
 	memory := models.MemoryInfo{}
 
 	data, err := os.ReadFile("/proc/meminfo")
@@ -281,7 +293,7 @@ func (c *Collector) collectMemoryInfoLinux(ctx context.Context) (models.MemoryIn
 	return memory, nil
 }
 
-func (c *Collector) collectDiskInfo(ctx context.Context) (map[string]models.DiskInfo, error) {
+func (c *Runner) collectDiskInfo(ctx context.Context) (map[string]models.DiskInfo, error) {
 	disks := make(map[string]models.DiskInfo)
 
 	cmd := exec.CommandContext(ctx, "df", "-h")
@@ -354,7 +366,7 @@ func parseDiskSize(s string) int64 {
 	return 0
 }
 
-func (c *Collector) collectLoadAverage(ctx context.Context) (models.LoadAverage, error) {
+func (c *Runner) collectLoadAverage(ctx context.Context) (models.LoadAverage, error) {
 	load := models.LoadAverage{}
 
 	if runtime.GOOS == "darwin" {
@@ -403,7 +415,7 @@ func (c *Collector) collectLoadAverage(ctx context.Context) (models.LoadAverage,
 	return load, nil
 }
 
-func (c *Collector) getProcessCount(ctx context.Context) (int, error) {
+func (c *Runner) getProcessCount(ctx context.Context) (int, error) {
 	cmd := exec.CommandContext(ctx, "ps", "-axo", "pid")
 	output, err := cmd.Output()
 	if err != nil {
@@ -2,27 +2,34 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectScheduledTasks(ctx context.Context) (models.ScheduledTasks, error) {
+func (c *Runner) collectScheduledTasks(ctx context.Context) (models.ScheduledTasks, error) {
 	tasks := models.ScheduledTasks{
 		CronJobs:      make(map[string]models.CronJob),
 		SystemdTimers: make(map[string]models.SystemdTimer),
 		LaunchdJobs:   make(map[string]models.LaunchdJob),
 	}
 
+	var partial PartialError
+
 	if runtime.GOOS == "darwin" {
 		if c.config.ScheduledTasks.LaunchdJobs {
 			jobs, err := c.collectLaunchdJobs(ctx)
 			if err == nil {
 				tasks.LaunchdJobs = jobs
+			} else {
+				partial.Add("launchd_jobs", err)
 			}
 		}
 	} else if runtime.GOOS == "linux" {
@@ -30,6 +37,8 @@ func (c *Collector) collectScheduledTasks(ctx context.Context) (models.Scheduled
 			crons, err := c.collectCronJobs(ctx)
 			if err == nil {
 				tasks.CronJobs = crons
+			} else {
+				partial.Add("cron_jobs", err)
 			}
 		}
 
@@ -37,14 +46,16 @@ func (c *Collector) collectScheduledTasks(ctx context.Context) (models.Scheduled
 			timers, err := c.collectSystemdTimers(ctx)
 			if err == nil {
 				tasks.SystemdTimers = timers
+			} else {
+				partial.Add("systemd_timers", err)
 			}
 		}
 	}
 
-	return tasks, nil
+	return tasks, partial.Err()
 }
 
-func (c *Collector) collectCronJobs(ctx context.Context) (map[string]models.CronJob, error) {
+func (c *Runner) collectCronJobs(ctx context.Context) (map[string]models.CronJob, error) {
 	cronJobs := make(map[string]models.CronJob)
 
 	cronPaths := []string{
@@ -53,13 +64,13 @@ func (c *Collector) collectCronJobs(ctx context.Context) (map[string]models.Cron
 	}
 
 	for _, path := range cronPaths {
-		info, err := os.Stat(path)
+		info, err := c.fs.Stat(path)
 		if err != nil {
 			continue
 		}
 
 		if info.IsDir() {
-			entries, err := os.ReadDir(path)
+			entries, err := c.fs.ReadDir(path)
 			if err != nil {
 				continue
 			}
@@ -90,10 +101,10 @@ func (c *Collector) collectCronJobs(ctx context.Context) (map[string]models.Cron
 	return cronJobs, nil
 }
 
-func (c *Collector) parseCronFile(ctx context.Context, path string) (map[string]models.CronJob, error) {
+func (c *Runner) parseCronFile(ctx context.Context, path string) (map[string]models.CronJob, error) {
 	jobs := make(map[string]models.CronJob)
 
-	data, err := os.ReadFile(path)
+	data, err := readFile(c.fs, path)
 	if err != nil {
 		return jobs, err
 	}
@@ -123,7 +134,7 @@ func (c *Collector) parseCronFile(ctx context.Context, path string) (map[string]
 			}
 		}
 
-		jobs[path+":"+string(lineNum)] = models.CronJob{
+		jobs[path+":"+strconv.Itoa(lineNum)] = models.CronJob{
 			User:     user,
 			Schedule: schedule,
 			Command:  command,
@@ -134,9 +145,13 @@ func (c *Collector) parseCronFile(ctx context.Context, path string) (map[string]
 	return jobs, nil
 }
 
-func (c *Collector) collectSystemdTimers(ctx context.Context) (map[string]models.SystemdTimer, error) {
+func (c *Runner) collectSystemdTimers(ctx context.Context) (map[string]models.SystemdTimer, error) {
 	timers := make(map[string]models.SystemdTimer)
 
+	if err := c.requireLive(); err != nil {
+		return timers, err
+	}
+
 	cmd := exec.CommandContext(ctx, "systemctl", "list-timers", "--all", "--no-pager")
 	output, err := cmd.Output()
 	if err != nil {
@@ -189,21 +204,23 @@ func parseSystemdTime(s string) time.Time {
 	return time.Time{}
 }
 
-func (c *Collector) collectLaunchdJobs(ctx context.Context) (map[string]models.LaunchdJob, error) {
+func (c *Runner) collectLaunchdJobs(ctx context.Context) (map[string]models.LaunchdJob, error) {
 	jobs := make(map[string]models.LaunchdJob)
 
 	paths := []string{
 		"/Library/LaunchDaemons",
 		"/Library/LaunchAgents",
-		os.Getenv("HOME") + "/Library/LaunchAgents",
+	}
+	if c.offline == "" {
+		paths = append(paths, os.Getenv("HOME")+"/Library/LaunchAgents")
 	}
 
 	for _, path := range paths {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := c.fs.Stat(path); errors.Is(err, fs.ErrNotExist) {
 			continue
 		}
 
-		entries, err := os.ReadDir(path)
+		entries, err := c.fs.ReadDir(path)
 		if err != nil {
 			continue
 		}
@@ -224,13 +241,17 @@ func (c *Collector) collectLaunchdJobs(ctx context.Context) (map[string]models.L
 	return jobs, nil
 }
 
-func (c *Collector) parseLaunchdPlist(ctx context.Context, path string) (models.LaunchdJob, error) {
+func (c *Runner) parseLaunchdPlist(ctx context.Context, path string) (models.LaunchdJob, error) {
 	job := models.LaunchdJob{
 		Label:   path,
 		Path:    path,
 		Enabled: true,
 	}
 
+	if c.offline != "" {
+		return job, nil
+	}
+
 	cmd := exec.CommandContext(ctx, "launchctl", "list")
 	output, err := cmd.Output()
 	if err == nil {
@@ -12,7 +12,7 @@ import (
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
+func (c *Runner) collectServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
 
 	switch c.config.Services.InitType {
 	case "sysemd":
@@ -32,7 +32,7 @@ func (c *Collector) collectServices(ctx context.Context) (map[string]models.Serv
 
 }
 
-func (c *Collector) collectSystemdServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
+func (c *Runner) collectSystemdServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
 	services := make(map[string]models.ServiceInfo)
 
 	// retireve list of all services
@@ -62,6 +62,11 @@ func (c *Collector) collectSystemdServices(ctx context.Context) (map[string]mode
 	scanner := bufio.NewScanner(bytes.NewReader(output))
 
 	for scanner.Scan() {
+		// one systemctl call per service adds up, stop once time is up
+		if ctx.Err() != nil {
+			return services, ctx.Err()
+		}
+
 		line := scanner.Text()
 		fields := strings.Fields(line)
 
@@ -130,13 +135,13 @@ func matchesPatterns(name string, include, exclude []*regexp.Regexp) bool {
 	return true
 }
 
-func (c *Collector) isServiceEnabled(ctx context.Context, name string) bool {
+func (c *Runner) isServiceEnabled(ctx context.Context, name string) bool {
 	cmd := exec.CommandContext(ctx, "systemctl", "is-enabled", name+".service")
 	output, _ := cmd.Output()
 	return strings.TrimSpace(string(output)) == "enabled"
 }
 
-func (c *Collector) collectSysvinitServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
+func (c *Runner) collectSysvinitServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
 
 	services := make(map[string]models.ServiceInfo)
 
@@ -169,7 +174,7 @@ func (c *Collector) collectSysvinitServices(ctx context.Context) (map[string]mod
 	return services, nil
 }
 
-func (c *Collector) collectLaunchdServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
+func (c *Runner) collectLaunchdServices(ctx context.Context) (map[string]models.ServiceInfo, error) {
 	services := make(map[string]models.ServiceInfo)
 
 	cmd := exec.CommandContext(ctx, "launctl", "list")
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// defaultSysctls are the kernel settings collected when none are configured,
+// the ones hardening guides look at
+var defaultSysctls = []string{
+	"fs.suid_dumpable",
+	"kernel.dmesg_restrict",
+	"kernel.kptr_restrict",
+	"kernel.randomize_va_space",
+	"net.ipv4.conf.all.accept_redirects",
+	"net.ipv4.conf.all.accept_source_route",
+	"net.ipv4.conf.all.rp_filter",
+	"net.ipv4.conf.all.send_redirects",
+	"net.ipv4.icmp_echo_ignore_broadcasts",
+	"net.ipv4.ip_forward",
+	"net.ipv4.tcp_syncookies",
+	"net.ipv6.conf.all.accept_redirects",
+}
+
+// collectSettings gathers single-valued system settings, keyed by where they
+// come from: "sysctl:net.ipv4.ip_forward", "sshd:permitrootlogin"
+func (c *Runner) collectSettings(ctx context.Context) (map[string]string, error) {
+	settings := make(map[string]string)
+	var partial PartialError
+
+	if err := c.requireLive(); err != nil {
+		// /proc/sys only exists on the running kernel
+		partial.Add("sysctl", err)
+	} else if err := c.collectSysctls(settings); err != nil {
+		partial.Add("sysctl", err)
+	}
+
+	if c.config.Settings.SSHD {
+		if err := c.collectSSHDConfig(settings); err != nil {
+			partial.Add("sshd", err)
+		}
+	}
+
+	return settings, partial.Err()
+}
+
+func (c *Runner) collectSysctls(settings map[string]string) error {
+	keys := c.config.Settings.Sysctl
+	if len(keys) == 0 {
+		keys = defaultSysctls
+	}
+
+	for _, key := range keys {
+		data, err := readFile(c.fs, "/proc/sys/"+strings.ReplaceAll(key, ".", "/"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// multi-valued settings are tab separated, as sysctl prints them
+		settings["sysctl:"+key] = strings.Join(strings.Fields(string(data)), " ")
+	}
+	return nil
+}
+
+// collectSSHDConfig records the global sshd_config settings. Keywords are
+// case-insensitive and stored lower case; like sshd, the first value given
+// for a keyword wins, and Match blocks are left out.
+func (c *Runner) collectSSHDConfig(settings map[string]string) error {
+	const config = "/etc/ssh/sshd_config"
+	if _, err := c.fs.Stat(config); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	_, err := c.readSSHDConfig(config, settings, 0)
+	return err
+}
+
+// readSSHDConfig reads one config file, following Include directives. It
+// reports whether a Match block started, which ends the global settings.
+func (c *Runner) readSSHDConfig(name string, settings map[string]string, depth int) (bool, error) {
+	if depth > 16 {
+		return false, nil
+	}
+
+	data, err := readFile(c.fs, name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// "Keyword value", "Keyword=value" and "Keyword = value" all work
+		keyword, value := line, ""
+		if i := strings.IndexAny(line, " \t="); i >= 0 {
+			keyword, value = line[:i], strings.TrimSpace(line[i:])
+			value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		}
+		keyword = strings.ToLower(keyword)
+
+		switch keyword {
+		case "match":
+			return true, nil
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				if !strings.HasPrefix(pattern, "/") {
+					pattern = "/etc/ssh/" + pattern
+				}
+				for _, file := range c.glob(pattern) {
+					if match, err := c.readSSHDConfig(file, settings, depth+1); err != nil || match {
+						return match, err
+					}
+				}
+			}
+		default:
+			if _, seen := settings["sshd:"+keyword]; !seen {
+				settings["sshd:"+keyword] = value
+			}
+		}
+	}
+	return false, nil
+}
+
+// glob expands a pattern whose wildcards are in the last path element only,
+// which is all sshd_config includes use in practice
+func (c *Runner) glob(pattern string) []string {
+	dir, base := path.Split(pattern)
+	if !strings.ContainsAny(base, "*?[") {
+		return []string{pattern}
+	}
+
+	entries, err := c.fs.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if ok, _ := path.Match(base, entry.Name()); ok && !entry.IsDir() {
+			files = append(files, path.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
@@ -3,24 +3,28 @@ package collector
 import (
 	"bufio"
 	"context"
-	"os"
-	"runtime"
+	"errors"
+	"io/fs"
 	"strconv"
 	"strings"
 
 	"github.com/AshitomW/Drifty/internal/models"
 )
 
-func (c *Collector) collectUserGroupConfig(ctx context.Context) (models.UserGroupConfig, error) {
+func (c *Runner) collectUserGroupConfig(ctx context.Context) (models.UserGroupConfig, error) {
 	config := models.UserGroupConfig{
 		Users:  make(map[string]models.UserInfo),
 		Groups: make(map[string]models.GroupInfo),
 	}
 
+	var partial PartialError
+
 	if c.config.UsersGroups.Users {
 		users, err := c.collectUsers(ctx)
 		if err == nil {
 			config.Users = users
+		} else {
+			partial.Add("users", err)
 		}
 	}
 
@@ -28,6 +32,8 @@ func (c *Collector) collectUserGroupConfig(ctx context.Context) (models.UserGrou
 		groups, err := c.collectGroups(ctx)
 		if err == nil {
 			config.Groups = groups
+		} else {
+			partial.Add("groups", err)
 		}
 	}
 
@@ -35,17 +41,19 @@ func (c *Collector) collectUserGroupConfig(ctx context.Context) (models.UserGrou
 		rules, err := c.collectSudoRules(ctx)
 		if err == nil {
 			config.SudoRules = rules
+		} else {
+			partial.Add("sudo_rules", err)
 		}
 	}
 
-	return config, nil
+	return config, partial.Err()
 }
 
-func (c *Collector) collectUsers(ctx context.Context) (map[string]models.UserInfo, error) {
+func (c *Runner) collectUsers(ctx context.Context) (map[string]models.UserInfo, error) {
 	users := make(map[string]models.UserInfo)
 
 	passwdPath := "/etc/passwd"
-	file, err := os.Open(passwdPath)
+	file, err := c.fs.Open(passwdPath)
 	if err != nil {
 		return users, err
 	}
@@ -72,14 +80,46 @@ func (c *Collector) collectUsers(ctx context.Context) (map[string]models.UserInf
 		}
 	}
 
+	c.readPasswordStatus(users)
 	return users, nil
 }
 
-func (c *Collector) collectGroups(ctx context.Context) (map[string]models.GroupInfo, error) {
+// readPasswordStatus notes from /etc/shadow whether each account has a
+// password. Only root can read the file, so without it the status is left
+// out rather than failing the collector.
+func (c *Runner) readPasswordStatus(users map[string]models.UserInfo) {
+	data, err := readFile(c.fs, "/etc/shadow")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 {
+			continue
+		}
+		user, ok := users[fields[0]]
+		if !ok {
+			continue
+		}
+
+		switch hash := fields[1]; {
+		case hash == "":
+			user.Password = "empty"
+		case strings.HasPrefix(hash, "!"), strings.HasPrefix(hash, "*"):
+			user.Password = "locked"
+		default:
+			user.Password = "set"
+		}
+		users[fields[0]] = user
+	}
+}
+
+func (c *Runner) collectGroups(ctx context.Context) (map[string]models.GroupInfo, error) {
 	groups := make(map[string]models.GroupInfo)
 
 	groupPath := "/etc/group"
-	file, err := os.Open(groupPath)
+	file, err := c.fs.Open(groupPath)
 	if err != nil {
 		return groups, err
 	}
@@ -109,15 +149,15 @@ func (c *Collector) collectGroups(ctx context.Context) (map[string]models.GroupI
 	return groups, nil
 }
 
-func (c *Collector) collectSudoRules(ctx context.Context) ([]models.SudoRule, error) {
+func (c *Runner) collectSudoRules(ctx context.Context) ([]models.SudoRule, error) {
 	var rules []models.SudoRule
 
 	sudoersPath := "/etc/sudoers"
-	if _, err := os.Stat(sudoersPath); os.IsNotExist(err) {
+	if _, err := c.fs.Stat(sudoersPath); errors.Is(err, fs.ErrNotExist) {
 		return rules, nil
 	}
 
-	file, err := os.Open(sudoersPath)
+	file, err := c.fs.Open(sudoersPath)
 	if err != nil {
 		return rules, err
 	}
@@ -169,20 +209,20 @@ func (c *Collector) collectSudoRules(ctx context.Context) ([]models.SudoRule, er
 		})
 	}
 
-	if runtime.GOOS == "darwin" {
-		sudoDPath := "/etc/sudoers.d"
-		if info, err := os.Stat(sudoDPath); err == nil && info.IsDir() {
-			entries, _ := os.ReadDir(sudoDPath)
-			for _, entry := range entries {
-				if entry.IsDir() {
-					continue
-				}
+	// sudoers.d is read on Linux too, where cloud images put their NOPASSWD
+	// rules
+	sudoDPath := "/etc/sudoers.d"
+	if info, err := c.fs.Stat(sudoDPath); err == nil && info.IsDir() {
+		entries, _ := c.fs.ReadDir(sudoDPath)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
 
-				filePath := sudoDPath + "/" + entry.Name()
-				fileRules, err := c.parseSudoersFile(ctx, filePath)
-				if err == nil {
-					rules = append(rules, fileRules...)
-				}
+			filePath := sudoDPath + "/" + entry.Name()
+			fileRules, err := c.parseSudoersFile(ctx, filePath)
+			if err == nil {
+				rules = append(rules, fileRules...)
 			}
 		}
 	}
@@ -190,10 +230,10 @@ func (c *Collector) collectSudoRules(ctx context.Context) ([]models.SudoRule, er
 	return rules, nil
 }
 
-func (c *Collector) parseSudoersFile(ctx context.Context, path string) ([]models.SudoRule, error) {
+func (c *Runner) parseSudoersFile(ctx context.Context, path string) ([]models.SudoRule, error) {
 	var rules []models.SudoRule
 
-	file, err := os.Open(path)
+	file, err := c.fs.Open(path)
 	if err != nil {
 		return rules, err
 	}
@@ -0,0 +1,74 @@
+package comparator
+
+import (
+	"fmt"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// Accept sets the acceptances checked against every drift the comparator
+// reports from then on
+func (c *Comparator) Accept(accepted []models.Acceptance) {
+	c.accepted = accepted
+}
+
+// NewAcceptance records drift as expected
+func NewAcceptance(drift models.DriftItem) models.Acceptance {
+	return models.Acceptance{
+		Category: drift.Category,
+		Name:     drift.Name,
+		Type:     drift.Type,
+		Value:    AcceptedValue(drift),
+	}
+}
+
+// AcceptedValue digests the target value of a drift, and the new value of
+// each changed field, since the value doesn't always hold every field: a cron
+// job's is only its command
+func AcceptedValue(drift models.DriftItem) string {
+	value := valueDigest(drift.Category, drift.TargetVal)
+	if len(drift.Changes) == 0 {
+		return value
+	}
+	fields := make(map[string]interface{}, len(drift.Changes))
+	for _, ch := range drift.Changes {
+		fields[ch.Field] = ch.New
+	}
+	return value + valueDigest("", fields)
+}
+
+// markAccepted attaches the matching acceptance to each drift. One that has
+// expired, or whose value changed since, leaves the drift active with a note.
+func (c *Comparator) markAccepted(report *models.DriftReport) {
+	if len(c.accepted) == 0 {
+		return
+	}
+
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		value := AcceptedValue(*drift)
+
+		note := ""
+		for _, a := range c.accepted {
+			if a.Category != drift.Category || a.Name != drift.Name || a.Type != drift.Type {
+				continue
+			}
+			switch {
+			case a.Value != value:
+				note = fmt.Sprintf("%s %s changed again since it was accepted (%s)", drift.Category, drift.Name, a.Reason)
+			case !a.Expires.IsZero() && !report.Timestamp.Before(a.Expires):
+				note = fmt.Sprintf("acceptance of %s %s expired on %s (%s)", drift.Category, drift.Name, a.Expires.Local().Format("2006-01-02"), a.Reason)
+			default:
+				accepted := a
+				drift.Accepted = &accepted
+			}
+			if drift.Accepted != nil {
+				break
+			}
+		}
+
+		if drift.Accepted == nil && note != "" {
+			report.Notes = append(report.Notes, note)
+		}
+	}
+}
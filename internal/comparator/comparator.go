@@ -2,32 +2,38 @@ package comparator
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/AshitomW/Drifty/internal/configfile"
 	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+	"github.com/AshitomW/Drifty/internal/version"
 	"github.com/google/uuid"
 )
 
-type SeverityRules struct {
-	CriticalPackages []string
-	CriticalServices []string
-	CriticalFiles    []string
-	CriticalEnvVars  []string
-}
-
 type Comparator struct {
-	severityRules SeverityRules
+	rules     []compiledRule
+	accepted  []models.Acceptance
+	normalize normalizer
 }
 
-func New(rules SeverityRules) *Comparator {
-	return &Comparator{
-		severityRules: rules,
+// New creates a comparator rating drifts with rules, in order, followed by
+// the built-in defaults
+func New(rules []Rule) (*Comparator, error) {
+	compiled, err := compileRules(append(append([]Rule(nil), rules...), defaultRules...))
+	if err != nil {
+		return nil, err
 	}
+	return &Comparator{rules: compiled}, nil
 }
 
 // Compare will generate a drift report between two snapshots
 
 func (c *Comparator) Compare(source, target *models.EnvironmentSnapshot) *models.DriftReport {
+	source, sourceNotes := c.normalize.snapshot(source, "source")
+	target, targetNotes := c.normalize.snapshot(target, "target")
+
 	report := &models.DriftReport{
 		ID:             uuid.New().String(),
 		Timestamp:      time.Now().UTC(),
@@ -41,163 +47,602 @@ func (c *Comparator) Compare(source, target *models.EnvironmentSnapshot) *models
 			ByType:     make(map[string]int),
 		},
 	}
+	report.Notes = append(append(report.Notes, sourceNotes...), targetNotes...)
 
-	// compare the files
-	c.compareFiles(source.Files, target.Files, report)
+	for _, sec := range c.sections() {
+		note, ok := collectionGap(sec.collector, source, target)
+		if note != "" {
+			report.Notes = append(report.Notes, note)
+		}
+		if !ok {
+			continue
+		}
 
-	// compare environment variables
-	c.compareEnvVars(source.EnvVars, target.EnvVars, report)
+		start := len(report.Drifts)
+		sec.compare(source, target, report)
+		dropIncomplete(sec, source, target, report, start)
+	}
+	dropExcluded(source, target, report)
 
-	// compare packages
-	c.comparePackages(source.Packages, target.Packages, report)
+	sortDrifts(report)
+	attachTransactions(source, target, report)
+	explainFileDrifts(report)
+	c.applyRules(report)
+	c.markAccepted(report)
 
-	// compare Services
+	// Update summary
+	c.updateSummary(report)
 
-	c.compareServices(source.Services, target.Services, report)
+	return report
+}
 
-	// compare Network Config
-	c.compareNetworkConfig(source.NetworkConfig, target.NetworkConfig, report)
+// section ties a comparison to the collector that produced its data
+type section struct {
+	collector string
+	part      func(drift models.DriftItem) string // the collector part a drift came from, "" if not split
+	compare   func(source, target *models.EnvironmentSnapshot, report *models.DriftReport)
+}
 
-	// compare Docker Config
-	c.compareDockerConfig(source.DockerConfig, target.DockerConfig, report)
+func (c *Comparator) sections() []section {
+	whole := func(models.DriftItem) string { return "" }
+	fixed := func(part string) func(models.DriftItem) string {
+		return func(models.DriftItem) string { return part }
+	}
 
-	// compare System Resources
-	c.compareSystemResources(source.SystemResources, target.SystemResources, report)
+	return []section{
+		{"files", whole, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareFiles(s.Files, t.Files, r)
+		}},
+		{"env_vars", whole, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareEnvVars(s.EnvVars, t.EnvVars, r)
+		}},
+		{"packages", packageManager, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.comparePackages(s.Packages, t.Packages, r)
+		}},
+		{"package_integrity", integrityManager, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.comparePackageIntegrity(s, t, r)
+		}},
+		{"services", whole, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareServices(s.Services, t.Services, r)
+		}},
+		{"network", fixed("interfaces"), func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			// a baseline built from many hosts holds no MAC addresses, so
+			// there's nothing to hold the other side to
+			macs := s.Baseline == nil && t.Baseline == nil
+			c.compareNetworkConfig(s.NetworkConfig, t.NetworkConfig, macs, r)
+		}},
+		{"docker", fixed("containers"), func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareDockerConfig(s.DockerConfig, t.DockerConfig, r)
+		}},
+		{"system_resources", resourcePart, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareSystemResources(s.SystemResources, t.SystemResources, r)
+		}},
+		{"scheduled_tasks", fixed("cron_jobs"), func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareScheduledTasks(s.ScheduledTasks, t.ScheduledTasks, r)
+		}},
+		{"certificates", whole, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareCertificates(s.Certificates, t.Certificates, r)
+		}},
+		{"users_groups", fixed("users"), func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareUserGroupConfig(s.UserGroupConfig, t.UserGroupConfig, r)
+		}},
+		{"settings", settingSource, func(s, t *models.EnvironmentSnapshot, r *models.DriftReport) {
+			c.compareSettings(s.Settings, t.Settings, r)
+		}},
+	}
+}
 
-	// compare Scheduled Tasks
-	c.compareScheduledTasks(source.ScheduledTasks, target.ScheduledTasks, report)
+// packageManager extracts the manager from a "dpkg:openssl" package key
+func packageManager(drift models.DriftItem) string {
+	if idx := strings.Index(drift.Name, ":"); idx > 0 {
+		return drift.Name[:idx]
+	}
+	return ""
+}
 
-	// compare Certificates
-	c.compareCertificates(source.Certificates, target.Certificates, report)
+// integrityManager gives the package manager of a package file drift
+func integrityManager(drift models.DriftItem) string {
+	for _, v := range []interface{}{drift.TargetVal, drift.SourceVal} {
+		if issue, ok := v.(models.PackageFileIssue); ok {
+			return packageManager(models.DriftItem{Name: issue.Package})
+		}
+	}
+	return ""
+}
 
-	// compare User/Group Config
-	c.compareUserGroupConfig(source.UserGroupConfig, target.UserGroupConfig, report)
+// settingSource gives where a setting was read from, "sysctl" or "sshd"
+func settingSource(drift models.DriftItem) string {
+	source, _, _ := strings.Cut(drift.Name, ":")
+	return source
+}
 
-	// Update summary
-	c.updateSummary(report)
+func resourcePart(drift models.DriftItem) string {
+	if strings.HasPrefix(drift.Name, "Memory") {
+		return "memory"
+	}
+	return "cpu"
+}
 
-	return report
+// collectionGap decides whether a collector's data can be compared at all.
+// A collector that failed or was skipped on only one side would otherwise
+// show up as every item having been added or removed.
+func collectionGap(name string, source, target *models.EnvironmentSnapshot) (string, bool) {
+	src, srcOK := source.Collectors[name]
+	tgt, tgtOK := target.Collectors[name]
+
+	// snapshots taken before collector statuses were recorded
+	if !srcOK || !tgtOK {
+		return "", true
+	}
+
+	if src.Status == "skipped" && tgt.Status == "skipped" {
+		return "", false
+	}
+
+	for _, side := range []struct {
+		label  string
+		status models.CollectorStatus
+	}{{"source", src}, {"target", tgt}} {
+		if side.status.Status != "failed" && side.status.Status != "skipped" {
+			continue
+		}
+
+		detail := side.status.Message
+		if len(side.status.Errors) > 0 {
+			detail = strings.Join(side.status.Errors, "; ")
+		}
+		return fmt.Sprintf("%s not compared: collection %s on %s (%s)", name, side.status.Status, side.label, detail), false
+	}
+
+	return "", true
+}
+
+// incomplete reports whether part of a collector could not be read
+func incomplete(status models.CollectorStatus, part string) bool {
+	if status.Status != "partial" {
+		return false
+	}
+	// a collector that ran out of time may have stopped anywhere
+	if part == "" || status.TimedOut {
+		return true
+	}
+	for _, p := range status.FailedParts {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
+
+// dropIncomplete removes drifts produced since start that only exist
+// because part of a collector failed on one side, and leaves a note with the
+// number of drifts that were held back.
+func dropIncomplete(sec section, source, target *models.EnvironmentSnapshot, report *models.DriftReport, start int) {
+	src := source.Collectors[sec.collector]
+	tgt := target.Collectors[sec.collector]
+	if src.Status != "partial" && tgt.Status != "partial" {
+		return
+	}
+
+	dropped := map[string]int{}
+	kept := report.Drifts[:start]
+	for _, drift := range report.Drifts[start:] {
+		part := sec.part(drift)
+		// a modified value is only suspect when the specific part failed;
+		// files that exist on both sides were read fine
+		modified := drift.Type == "modified" && part != ""
+		switch {
+		case incomplete(tgt, part) && (drift.Type == "removed" || modified):
+			dropped["target"]++
+		case incomplete(src, part) && (drift.Type == "added" || modified):
+			dropped["source"]++
+		default:
+			kept = append(kept, drift)
+		}
+	}
+	report.Drifts = kept
+
+	for _, side := range []string{"source", "target"} {
+		if dropped[side] == 0 {
+			continue
+		}
+		status := tgt
+		if side == "source" {
+			status = src
+		}
+		report.Notes = append(report.Notes, fmt.Sprintf("%s: %d drift(s) not reported because collection was incomplete on %s (%s)",
+			sec.collector, dropped[side], side, strings.Join(status.Errors, "; ")))
+	}
+}
+
+// dropExcluded removes drifts on the items a baseline was built without,
+// like /etc/hostname, which every host would otherwise report as added
+func dropExcluded(source, target *models.EnvironmentSnapshot, report *models.DriftReport) {
+	var exclude []models.Exclusion
+	for _, s := range []*models.EnvironmentSnapshot{source, target} {
+		if s.Baseline != nil {
+			exclude = append(exclude, s.Baseline.Exclude...)
+		}
+	}
+	if len(exclude) == 0 {
+		return
+	}
+	excluded, err := Excluder(exclude)
+	if err != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("baseline exclusions not applied: %v", err))
+		return
+	}
+
+	kept := report.Drifts[:0]
+	dropped := 0
+	for _, drift := range report.Drifts {
+		if excluded(drift.Category, drift.Name) {
+			dropped++
+			continue
+		}
+		kept = append(kept, drift)
+	}
+	report.Drifts = kept
+	if dropped > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d drift(s) on host-specific items the baseline excludes not reported", dropped))
+	}
+}
+
+// Excluder tells whether an item, by category and name, matches one of the
+// exclusions
+func Excluder(exclude []models.Exclusion) (func(category, name string) bool, error) {
+	type exclusion struct {
+		category string
+		match    func(string) bool
+	}
+	var compiled []exclusion
+	for _, e := range exclude {
+		match, err := pattern.Compile(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, exclusion{e.Category, match})
+	}
+	return func(category, name string) bool {
+		for _, e := range compiled {
+			if (e.category == "" || e.category == category) && e.match(name) {
+				return true
+			}
+		}
+		return false
+	}, nil
 }
 
 func (c *Comparator) compareFiles(source, target map[string]models.FileInfo, report *models.DriftReport) {
+	var removed, added []string
 
 	// Find modified and removed files
 
 	for path, srcFile := range source {
 		if tgtFile, exists := target[path]; exists {
-			if diff := c.diffFile(srcFile, tgtFile); diff != nil {
+			changes := keyChanges(path, srcFile, tgtFile, diffFile(srcFile, tgtFile))
+			if len(changes) > 0 {
 				drift := models.DriftItem{
 					Type:      "modified",
 					Category:  "file",
 					Name:      path,
 					SourceVal: srcFile,
 					TargetVal: tgtFile,
-					Severity:  c.getFileSeverity(path),
-					Message:   fmt.Sprintf("File modified: %v", diff),
+					Changes:   changes,
+					Diff:      contentDiff(path, path, srcFile, tgtFile),
+					Message:   "File modified: " + describeChanges(changes),
 				}
 				report.Drifts = append(report.Drifts, drift)
 			}
 		} else {
-			drift := models.DriftItem{
-				Type:      "removed",
-				Category:  "file",
-				Name:      path,
-				SourceVal: srcFile,
-				Severity:  c.getFileSeverity(path),
-				Message:   "File exists in source but not in target",
-			}
-			report.Drifts = append(report.Drifts, drift)
+			removed = append(removed, path)
 		}
 	}
 
 	// Find added files
 
-	for path, tgtFile := range target {
+	for path := range target {
 		if _, exists := source[path]; !exists {
-			drift := models.DriftItem{
-				Type:      "added",
-				Category:  "file",
-				Name:      path,
-				TargetVal: tgtFile,
-				Severity:  c.getFileSeverity(path),
-				Message:   "File exists in target but not in source",
-			}
-			report.Drifts = append(report.Drifts, drift)
+			added = append(added, path)
+		}
+	}
+
+	// a file that left one path and showed up under another was moved
+	moved := make(map[string]bool)
+	for _, m := range findMoves(source, target, removed, added) {
+		report.Drifts = append(report.Drifts, movedDrift(m, source[m.from], target[m.to]))
+		moved[m.from], moved[m.to] = true, true
+	}
+
+	for _, path := range removed {
+		if moved[path] {
+			continue
 		}
+		drift := models.DriftItem{
+			Type:      "removed",
+			Category:  "file",
+			Name:      path,
+			SourceVal: source[path],
+			Diff:      contentDiff(path, path, source[path], models.FileInfo{}),
+			Message:   "File exists in source but not in target",
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	for _, path := range added {
+		if moved[path] {
+			continue
+		}
+		drift := models.DriftItem{
+			Type:      "added",
+			Category:  "file",
+			Name:      path,
+			TargetVal: target[path],
+			Diff:      contentDiff(path, path, models.FileInfo{}, target[path]),
+			Message:   "File exists in target but not in source",
+		}
+		report.Drifts = append(report.Drifts, drift)
 	}
 }
 
-func matchPattern(s, pattern string) bool {
-	// simple glob matching
-	if pattern == "*" {
-		return true
+// contentDiff diffs the captured text of a file. A side that is missing
+// counts as empty, a side whose text wasn't captured means there's no diff.
+func contentDiff(srcPath, tgtPath string, src, tgt models.FileInfo) string {
+	srcText, srcOK, err := src.Text()
+	if err != nil || !srcOK && src.Exists {
+		return ""
+	}
+	tgtText, tgtOK, err := tgt.Text()
+	if err != nil || !tgtOK && tgt.Exists || !srcOK && !tgtOK {
+		return ""
+	}
+
+	srcName, tgtName := "source"+srcPath, "target"+tgtPath
+	if !src.Exists {
+		srcName = "/dev/null"
 	}
-	return s == pattern
+	if !tgt.Exists {
+		tgtName = "/dev/null"
+	}
+	return unifiedDiff(srcText, tgtText, srcName, tgtName)
 }
 
-func (c *Comparator) getFileSeverity(path string) string {
-	for _, p := range c.severityRules.CriticalFiles {
-		if matchPattern(path, p) {
-			return "critical"
+// keyChanges swaps the hash change of a structured config file whose text
+// was captured on both sides for the keys that changed. A file that only
+// changed in layout, comments or key order then has no changes left.
+func keyChanges(path string, src, tgt models.FileInfo, changes []models.FieldChange) []models.FieldChange {
+	format := configfile.Format(path)
+	if format == "" || !hasField(changes, "hash") {
+		return changes
+	}
+
+	srcText, srcOK, err := src.Text()
+	if err != nil || !srcOK {
+		return changes
+	}
+	tgtText, tgtOK, err := tgt.Text()
+	if err != nil || !tgtOK {
+		return changes
+	}
+	srcKeys, err := configfile.Parse(format, srcText)
+	if err != nil {
+		return changes
+	}
+	tgtKeys, err := configfile.Parse(format, tgtText)
+	if err != nil {
+		return changes
+	}
+
+	// a secret masked on capture may be what changed, so the hash change
+	// stays when there is one
+	masked := src.Masked || tgt.Masked
+
+	var kept []models.FieldChange
+	for _, ch := range changes {
+		if ch.Field != "hash" || masked {
+			kept = append(kept, ch)
 		}
 	}
 
-	return "info"
+	all := make(map[string]string, len(srcKeys)+len(tgtKeys))
+	for key, value := range srcKeys {
+		all[key] = value
+	}
+	for key, value := range tgtKeys {
+		all[key] = value
+	}
+	for _, key := range configfile.Keys(all) {
+		old, inSrc := srcKeys[key]
+		new, inTgt := tgtKeys[key]
+		field := models.KeyPrefix + key
+		switch {
+		case !inSrc:
+			kept = append(kept, models.FieldChange{Field: field, New: new})
+		case !inTgt:
+			kept = append(kept, models.FieldChange{Field: field, Old: old})
+		case old != new:
+			kept = append(kept, models.FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	return kept
 }
 
-func (c *Comparator) getEnvVarSeverity(name string) string {
-	for _, p := range c.severityRules.CriticalEnvVars {
-		if matchPattern(name, p) {
-			return "critical"
+func hasField(changes []models.FieldChange, field string) bool {
+	for _, ch := range changes {
+		if ch.Field == field {
+			return true
 		}
 	}
-	return "warning"
+	return false
 }
 
-func (c *Comparator) getPackageSeverity(name string) string {
-	for _, p := range c.severityRules.CriticalPackages {
-		if matchPattern(name, p) {
-			return "critical"
+// attachTransactions finds the package manager run behind each package
+// drift in the target's history: the latest one that left the package at
+// its target version, or removed it
+func attachTransactions(source, target *models.EnvironmentSnapshot, report *models.DriftReport) {
+	if len(target.PackageHistory) == 0 {
+		return
+	}
+
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		if drift.Category != "package" {
+			continue
+		}
+
+		t := findTransaction(target.PackageHistory, drift, source.Timestamp)
+		if t == nil {
+			continue
 		}
+		drift.Transaction = t
 
+		when := t.Start.Local().Format("2006-01-02 15:04")
+		command := t.Command
+		if len(command) > 60 {
+			command = command[:57] + "..."
+		}
+		switch {
+		case command != "" && t.User != "":
+			drift.Message += fmt.Sprintf(" (%s by %s: %s)", when, t.User, command)
+		case command != "":
+			drift.Message += fmt.Sprintf(" (%s: %s)", when, command)
+		default:
+			drift.Message += fmt.Sprintf(" (%s, %s)", when, t.ID)
+		}
 	}
+}
+
+// findTransaction returns a copy of the matching transaction holding only
+// this package's change
+func findTransaction(history []models.PackageTransaction, drift *models.DriftItem, since time.Time) *models.PackageTransaction {
+	version, _ := drift.TargetVal.(string)
+	manager := packageManager(*drift)
+
+	for i := len(history) - 1; i >= 0; i-- {
+		t := history[i]
+		for _, change := range t.Changes {
+			if change.Package != drift.Name {
+				continue
+			}
+			removed := change.Action == "remove"
+			if drift.Type == "removed" && removed || drift.Type != "removed" && !removed && change.To == version {
+				found := t
+				found.Changes = []models.PackageChange{change}
+				return &found
+			}
+		}
 
-	return "warning"
+		// managers without per-package history only say when the last
+		// change happened, which tells nothing if it was before the source
+		if len(t.Changes) == 0 && t.Manager == manager && t.Start.After(since) {
+			found := t
+			return &found
+		}
+	}
+	return nil
 }
 
-func (c *Comparator) getServiceSeverity(name string) string {
-	for _, p := range c.severityRules.CriticalServices {
-		if matchPattern(name, p) {
-			return "critical"
+// explainFileDrifts links file drifts to the drift of the package that owns
+// the file, so changes made by a package transaction stand apart from ones
+// nobody accounts for
+func explainFileDrifts(report *models.DriftReport) {
+	packages := make(map[string]models.DriftItem)
+	for _, drift := range report.Drifts {
+		if drift.Category == "package" {
+			packages[drift.Name] = drift
 		}
 	}
+	if len(packages) == 0 {
+		return
+	}
+
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		if drift.Category != "file" {
+			continue
+		}
+		pkg, ok := packages[filePackage(*drift)]
+		if !ok {
+			continue
+		}
 
-	return "warning"
+		drift.CausedBy = pkg.Name
+		drift.Message += fmt.Sprintf(" (package %s %s)", pkg.Name, transactionVerb(pkg))
+	}
 }
 
-func (c *Comparator) diffFile(src, tgt models.FileInfo) map[string]interface{} {
-	diff := make(map[string]interface{})
-	if src.Hash != tgt.Hash && src.Hash != "" && tgt.Hash != "" {
-		diff["hash"] = map[string]string{"source": src.Hash, "target": tgt.Hash}
+// filePackage gives the package owning a file drift's path, preferring the
+// target side since that is the package that last wrote it
+func filePackage(drift models.DriftItem) string {
+	for _, v := range []interface{}{drift.TargetVal, drift.SourceVal} {
+		if file, ok := v.(models.FileInfo); ok && file.Package != "" {
+			return file.Package
+		}
 	}
+	return ""
+}
 
-	if src.Mode != tgt.Mode {
-		diff["mode"] = map[string]string{"source": src.Mode, "target": tgt.Mode}
+func transactionVerb(pkg models.DriftItem) string {
+	switch {
+	case pkg.Type == "added":
+		return "installed"
+	case pkg.Type == "removed":
+		return "removed"
+	case pkg.Version != nil && pkg.Version.Direction == "downgrade":
+		return "downgraded"
+	default:
+		return "upgraded"
 	}
+}
 
-	if src.Owner != tgt.Owner {
-		diff["owner"] = map[string]string{"source": src.Owner, "target": tgt.Owner}
+func diffFile(src, tgt models.FileInfo) []models.FieldChange {
+	var changes []models.FieldChange
+	// large files aren't hashed
+	if src.Hash != "" && tgt.Hash != "" {
+		changes = changed(changes, "hash", src.Hash, tgt.Hash)
 	}
+	changes = changed(changes, "mode", src.Mode, tgt.Mode)
+	changes = changed(changes, "owner", src.Owner, tgt.Owner)
+	changes = changed(changes, "group", src.Group, tgt.Group)
+	return changes
+}
 
-	if src.Group != tgt.Group {
-		diff["group"] = map[string]string{"source": src.Group, "target": tgt.Group}
+// changed appends a FieldChange when old and new differ
+func changed(changes []models.FieldChange, field string, old, new interface{}) []models.FieldChange {
+	if old == new {
+		return changes
 	}
+	return append(changes, models.FieldChange{Field: field, Old: old, New: new})
+}
 
-	if len(diff) == 0 {
-		return nil
+// describeChanges renders changes for a message. Values too long to read at
+// a glance, hashes mostly, are left to the changes list, and so is anything
+// past the first few changes.
+func describeChanges(changes []models.FieldChange) string {
+	const shown = 4
+
+	parts := make([]string, 0, shown+1)
+	for i, ch := range changes {
+		if i == shown && len(changes) > shown+1 {
+			parts = append(parts, fmt.Sprintf("and %d more", len(changes)-shown))
+			break
+		}
+		old, new := describeValue(ch.Old), describeValue(ch.New)
+		if len(old) > 24 || len(new) > 24 {
+			parts = append(parts, ch.Field+" changed")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s -> %s", ch.Field, old, new))
 	}
+	return strings.Join(parts, ", ")
+}
 
-	return diff
+// describeValue shows a missing value, such as a config key only one side
+// sets, as "(none)"
+func describeValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	return fmt.Sprint(v)
 }
 
 func (c *Comparator) compareEnvVars(source, target map[string]models.EnvVar, report *models.DriftReport) {
@@ -211,7 +656,7 @@ func (c *Comparator) compareEnvVars(source, target map[string]models.EnvVar, rep
 					Name:      name,
 					SourceVal: srcVar.Value,
 					TargetVal: tgtVar.Value,
-					Severity:  c.getEnvVarSeverity(name),
+					Changes:   changed(nil, "value", srcVar.Value, tgtVar.Value),
 					Message:   "Environment variable value changed.",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -222,7 +667,6 @@ func (c *Comparator) compareEnvVars(source, target map[string]models.EnvVar, rep
 				Category:  "envvar",
 				Name:      name,
 				SourceVal: srcVar.Value,
-				Severity:  c.getEnvVarSeverity((name)),
 				Message:   "Environment variable missing in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -236,7 +680,6 @@ func (c *Comparator) compareEnvVars(source, target map[string]models.EnvVar, rep
 				Category:  "envvar",
 				Name:      name,
 				TargetVal: tgtVar.Value,
-				Severity:  c.getEnvVarSeverity(name),
 				Message:   "Environment variable added in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -244,6 +687,44 @@ func (c *Comparator) compareEnvVars(source, target map[string]models.EnvVar, rep
 	}
 }
 
+func (c *Comparator) compareSettings(source, target map[string]string, report *models.DriftReport) {
+	for name, srcVal := range source {
+		tgtVal, exists := target[name]
+		switch {
+		case !exists:
+			report.Drifts = append(report.Drifts, models.DriftItem{
+				Type:      "removed",
+				Category:  "setting",
+				Name:      name,
+				SourceVal: srcVal,
+				Message:   "Setting not set in target",
+			})
+		case srcVal != tgtVal:
+			report.Drifts = append(report.Drifts, models.DriftItem{
+				Type:      "modified",
+				Category:  "setting",
+				Name:      name,
+				SourceVal: srcVal,
+				TargetVal: tgtVal,
+				Changes:   changed(nil, "value", srcVal, tgtVal),
+				Message:   fmt.Sprintf("Setting changed: %s -> %s", srcVal, tgtVal),
+			})
+		}
+	}
+
+	for name, tgtVal := range target {
+		if _, exists := source[name]; !exists {
+			report.Drifts = append(report.Drifts, models.DriftItem{
+				Type:      "added",
+				Category:  "setting",
+				Name:      name,
+				TargetVal: tgtVal,
+				Message:   "Setting added in target",
+			})
+		}
+	}
+}
+
 func (c *Comparator) comparePackages(source, target map[string]models.PackageInfo, report *models.DriftReport) {
 
 	for name, srcPkg := range source {
@@ -255,9 +736,16 @@ func (c *Comparator) comparePackages(source, target map[string]models.PackageInf
 					Name:      name,
 					SourceVal: srcPkg.Version,
 					TargetVal: tgtPkg.Version,
-					Severity:  c.getPackageSeverity(name),
+					Changes:   changed(nil, "version", srcPkg.Version, tgtPkg.Version),
 					Message:   fmt.Sprintf("Package version changed: %s -> %s", srcPkg.Version, tgtPkg.Version),
 				}
+				if direction, bump := version.Classify(srcPkg.Manager, srcPkg.Version, tgtPkg.Version); direction != "" {
+					drift.Version = &models.VersionChange{Direction: direction, Bump: bump}
+					drift.Message = fmt.Sprintf("Package %sd: %s -> %s", direction, srcPkg.Version, tgtPkg.Version)
+					if bump != "" {
+						drift.Message = fmt.Sprintf("Package %sd (%s): %s -> %s", direction, bump, srcPkg.Version, tgtPkg.Version)
+					}
+				}
 				report.Drifts = append(report.Drifts, drift)
 			}
 		} else {
@@ -266,7 +754,6 @@ func (c *Comparator) comparePackages(source, target map[string]models.PackageInf
 				Category:  "package",
 				Name:      name,
 				SourceVal: srcPkg.Version,
-				Severity:  c.getPackageSeverity(name),
 				Message:   "Package missing in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -280,7 +767,6 @@ func (c *Comparator) comparePackages(source, target map[string]models.PackageInf
 				Category:  "package",
 				Name:      name,
 				TargetVal: tgtPkg.Version,
-				Severity:  c.getPackageSeverity(name),
 				Message:   "Package added in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -288,29 +774,133 @@ func (c *Comparator) comparePackages(source, target map[string]models.PackageInf
 	}
 }
 
-func (c *Comparator) compareServices(source, target map[string]models.ServiceInfo, report *models.DriftReport) {
+// comparePackageIntegrity reports package-owned files that stopped matching
+// what their package installed, or match it again. A plain file hash change
+// could be an upgrade; a mismatch here can't be, since the package manager
+// updates its checksums together with the file.
+func (c *Comparator) comparePackageIntegrity(source, target *models.EnvironmentSnapshot, report *models.DriftReport) {
+	srcIssues := source.PackageIntegrity.Issues
+	tgtIssues := target.PackageIntegrity.Issues
+
+	for path, issue := range tgtIssues {
+		before, existed := srcIssues[path]
+		if existed && sameIssue(before, issue) {
+			continue
+		}
 
-	for name, srcSvc := range source {
-		if tgtSvc, exists := target[name]; exists {
-			changes := make(map[string]interface{})
+		drift := models.DriftItem{
+			Type:      "added",
+			Category:  "package_file",
+			Name:      path,
+			TargetVal: issue,
+			Changes:   issueChanges(issue),
+			Message:   integrityMessage(source, issue),
+		}
+		if existed {
+			drift.Type = "modified"
+			drift.SourceVal = before
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
 
-			if srcSvc.Status != tgtSvc.Status {
-				changes["status"] = map[string]string{"source": srcSvc.Status, "target": tgtSvc.Status}
+	for path, issue := range srcIssues {
+		if _, exists := tgtIssues[path]; !exists {
+			drift := models.DriftItem{
+				Type:      "removed",
+				Category:  "package_file",
+				Name:      path,
+				SourceVal: issue,
+				Message:   fmt.Sprintf("File no longer differs from %s", issue.Package),
 			}
+			report.Drifts = append(report.Drifts, drift)
+		}
+	}
+}
 
-			if srcSvc.Enabled != tgtSvc.Enabled {
-				changes["enabled"] = map[string]bool{"source": srcSvc.Enabled, "target": tgtSvc.Enabled}
-			}
+func sameIssue(a, b models.PackageFileIssue) bool {
+	return a.Version == b.Version &&
+		strings.Join(a.Problems, ",") == strings.Join(b.Problems, ",") &&
+		a.ActualHash == b.ActualHash &&
+		a.ActualMode == b.ActualMode &&
+		a.ActualOwner == b.ActualOwner
+}
+
+// issueChanges lists how a file differs from what its package installed,
+// one change per problem
+func issueChanges(issue models.PackageFileIssue) []models.FieldChange {
+	changes := make([]models.FieldChange, 0, len(issue.Problems))
+	expOwner, expGroup, _ := strings.Cut(issue.ExpectedOwner, ":")
+	actOwner, actGroup, _ := strings.Cut(issue.ActualOwner, ":")
+
+	for _, problem := range issue.Problems {
+		ch := models.FieldChange{Field: problem}
+		switch problem {
+		case "missing":
+			ch.Old = issue.ExpectedHash
+		case "checksum":
+			ch.Old, ch.New = issue.ExpectedHash, issue.ActualHash
+		case "mode":
+			ch.Old, ch.New = issue.ExpectedMode, issue.ActualMode
+		case "owner":
+			ch.Old, ch.New = expOwner, actOwner
+		case "group":
+			ch.Old, ch.New = expGroup, actGroup
+		}
+		changes = append(changes, ch)
+	}
+	return changes
+}
+
+// contentChanged reports whether the file itself is missing or altered, as
+// opposed to only its permissions or owner
+func contentChanged(issue models.PackageFileIssue) bool {
+	for _, p := range issue.Problems {
+		if p == "missing" || p == "checksum" {
+			return true
+		}
+	}
+	return false
+}
+
+func integrityMessage(source *models.EnvironmentSnapshot, issue models.PackageFileIssue) string {
+	problems := strings.Join(issue.Problems, ", ")
+	switch {
+	case issue.Config:
+		return fmt.Sprintf("Config file differs from the %s %s default: %s", issue.Package, issue.Version, problems)
+	case !contentChanged(issue):
+		return fmt.Sprintf("Package file %s changed (%s %s)", problems, issue.Package, issue.Version)
+	}
+
+	what := "altered"
+	if issue.Problems[0] == "missing" {
+		what = "removed"
+	}
+	if pkg, ok := source.Packages[issue.Package]; ok && pkg.Version != issue.Version {
+		verb := "upgraded"
+		if version.CompareFor(pkg.Manager, pkg.Version, issue.Version) > 0 {
+			verb = "downgraded"
+		}
+		return fmt.Sprintf("Package-managed file %s, does not match %s %s (%s from %s)", what, issue.Package, issue.Version, verb, pkg.Version)
+	}
+	return fmt.Sprintf("Package-managed file %s without a version change (%s %s)", what, issue.Package, issue.Version)
+}
+
+func (c *Comparator) compareServices(source, target map[string]models.ServiceInfo, report *models.DriftReport) {
+
+	for name, srcSvc := range source {
+		if tgtSvc, exists := target[name]; exists {
+			changes := changed(nil, "status", srcSvc.Status, tgtSvc.Status)
+			changes = changed(changes, "enabled", srcSvc.Enabled, tgtSvc.Enabled)
 
 			if len(changes) > 0 {
 				drift := models.DriftItem{
 					Type:      "modified",
-					Category:  "services",
+					Category:  "service",
 					Name:      name,
 					SourceVal: srcSvc,
 					TargetVal: tgtSvc,
-					Severity:  c.getServiceSeverity(name),
-					Message:   fmt.Sprintf("Service state changed: %v", changes),
+					Changes:   changes,
+					Message:   "Service state changed: " + describeChanges(changes),
 				}
 				report.Drifts = append(report.Drifts, drift)
 			}
@@ -320,7 +910,6 @@ func (c *Comparator) compareServices(source, target map[string]models.ServiceInf
 				Category:  "service",
 				Name:      name,
 				SourceVal: srcSvc,
-				Severity:  c.getServiceSeverity(name),
 				Message:   "Service missing in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -334,7 +923,6 @@ func (c *Comparator) compareServices(source, target map[string]models.ServiceInf
 				Category:  "service",
 				Name:      name,
 				TargetVal: tgtSvc,
-				Severity:  c.getServiceSeverity(name),
 				Message:   "service added in target",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -345,10 +933,13 @@ func (c *Comparator) compareServices(source, target map[string]models.ServiceInf
 
 func (c *Comparator) updateSummary(report *models.DriftReport) {
 
-	report.HasDrift = len(report.Drifts) > 0
-	report.Summary.TotalDrifts = len(report.Drifts)
-
 	for _, drift := range report.Drifts {
+		if drift.Accepted != nil {
+			report.Summary.AcceptedCount++
+			continue
+		}
+
+		report.Summary.TotalDrifts++
 		switch drift.Severity {
 		case "critical":
 			report.Summary.CriticalCount++
@@ -361,19 +952,22 @@ func (c *Comparator) updateSummary(report *models.DriftReport) {
 		report.Summary.ByCategory[drift.Category]++
 		report.Summary.ByType[drift.Type]++
 	}
+	report.HasDrift = report.Summary.TotalDrifts > 0
 }
 
-func (c *Comparator) compareNetworkConfig(source, target models.NetworkConfig, report *models.DriftReport) {
+func (c *Comparator) compareNetworkConfig(source, target models.NetworkConfig, macs bool, report *models.DriftReport) {
 	for name, srcIface := range source.Interfaces {
 		if tgtIface, exists := target.Interfaces[name]; exists {
-			if srcIface.MACAddress != tgtIface.MACAddress {
+			// an empty MAC wasn't collected, it isn't a change either way
+			known := srcIface.MACAddress != "" && tgtIface.MACAddress != ""
+			if macs && known && srcIface.MACAddress != tgtIface.MACAddress {
 				drift := models.DriftItem{
 					Type:      "modified",
 					Category:  "network",
 					Name:      name + " (interface)",
 					SourceVal: srcIface.MACAddress,
 					TargetVal: tgtIface.MACAddress,
-					Severity:  "warning",
+					Changes:   changed(nil, "mac_address", srcIface.MACAddress, tgtIface.MACAddress),
 					Message:   "Interface MAC address changed",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -384,7 +978,6 @@ func (c *Comparator) compareNetworkConfig(source, target models.NetworkConfig, r
 				Category:  "network",
 				Name:      name + " (interface)",
 				SourceVal: srcIface,
-				Severity:  "warning",
 				Message:   "Interface removed",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -398,7 +991,6 @@ func (c *Comparator) compareNetworkConfig(source, target models.NetworkConfig, r
 				Category:  "network",
 				Name:      name + " (interface)",
 				TargetVal: tgtIface,
-				Severity:  "warning",
 				Message:   "Interface added",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -409,14 +1001,16 @@ func (c *Comparator) compareNetworkConfig(source, target models.NetworkConfig, r
 func (c *Comparator) compareDockerConfig(source, target models.DockerConfig, report *models.DriftReport) {
 	for id, srcCont := range source.Containers {
 		if tgtCont, exists := target.Containers[id]; exists {
-			if srcCont.Status != tgtCont.Status || srcCont.State != tgtCont.State {
+			changes := changed(nil, "status", srcCont.Status, tgtCont.Status)
+			changes = changed(changes, "state", srcCont.State, tgtCont.State)
+			if len(changes) > 0 {
 				drift := models.DriftItem{
 					Type:      "modified",
 					Category:  "docker",
 					Name:      srcCont.Name,
 					SourceVal: srcCont.Status + " " + srcCont.State,
 					TargetVal: tgtCont.Status + " " + tgtCont.State,
-					Severity:  "warning",
+					Changes:   changes,
 					Message:   "Container status/state changed",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -427,7 +1021,6 @@ func (c *Comparator) compareDockerConfig(source, target models.DockerConfig, rep
 				Category:  "docker",
 				Name:      srcCont.Name,
 				SourceVal: srcCont,
-				Severity:  "info",
 				Message:   "Container removed",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -441,7 +1034,6 @@ func (c *Comparator) compareDockerConfig(source, target models.DockerConfig, rep
 				Category:  "docker",
 				Name:      tgtCont.Name,
 				TargetVal: tgtCont,
-				Severity:  "info",
 				Message:   "Container added",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -457,7 +1049,7 @@ func (c *Comparator) compareSystemResources(source, target models.SystemResource
 			Name:      "CPU cores",
 			SourceVal: source.CPU.Cores,
 			TargetVal: target.CPU.Cores,
-			Severity:  "critical",
+			Changes:   changed(nil, "cores", source.CPU.Cores, target.CPU.Cores),
 			Message:   "CPU core count changed",
 		}
 		report.Drifts = append(report.Drifts, drift)
@@ -470,7 +1062,7 @@ func (c *Comparator) compareSystemResources(source, target models.SystemResource
 			Name:      "Memory total",
 			SourceVal: source.Memory.Total,
 			TargetVal: target.Memory.Total,
-			Severity:  "critical",
+			Changes:   changed(nil, "memory", source.Memory.Total, target.Memory.Total),
 			Message:   "Total memory changed",
 		}
 		report.Drifts = append(report.Drifts, drift)
@@ -480,14 +1072,16 @@ func (c *Comparator) compareSystemResources(source, target models.SystemResource
 func (c *Comparator) compareScheduledTasks(source, target models.ScheduledTasks, report *models.DriftReport) {
 	for name, srcTask := range source.CronJobs {
 		if tgtTask, exists := target.CronJobs[name]; exists {
-			if srcTask.Schedule != tgtTask.Schedule || srcTask.Command != tgtTask.Command {
+			changes := changed(nil, "schedule", srcTask.Schedule, tgtTask.Schedule)
+			changes = changed(changes, "command", srcTask.Command, tgtTask.Command)
+			if len(changes) > 0 {
 				drift := models.DriftItem{
 					Type:      "modified",
 					Category:  "scheduled_task",
 					Name:      name + " (cron)",
 					SourceVal: srcTask.Command,
 					TargetVal: tgtTask.Command,
-					Severity:  "warning",
+					Changes:   changes,
 					Message:   "Cron job changed",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -498,7 +1092,6 @@ func (c *Comparator) compareScheduledTasks(source, target models.ScheduledTasks,
 				Category:  "scheduled_task",
 				Name:      name + " (cron)",
 				SourceVal: srcTask,
-				Severity:  "warning",
 				Message:   "Cron job removed",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -512,7 +1105,6 @@ func (c *Comparator) compareScheduledTasks(source, target models.ScheduledTasks,
 				Category:  "scheduled_task",
 				Name:      name + " (cron)",
 				TargetVal: tgtTask,
-				Severity:  "warning",
 				Message:   "Cron job added",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -530,7 +1122,7 @@ func (c *Comparator) compareCertificates(source, target map[string]models.Certif
 					Name:      path,
 					SourceVal: srcCert.Fingerprint,
 					TargetVal: tgtCert.Fingerprint,
-					Severity:  "warning",
+					Changes:   changed(nil, "fingerprint", srcCert.Fingerprint, tgtCert.Fingerprint),
 					Message:   "Certificate changed",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -542,7 +1134,7 @@ func (c *Comparator) compareCertificates(source, target map[string]models.Certif
 					Name:      path,
 					SourceVal: "valid",
 					TargetVal: "expired",
-					Severity:  "critical",
+					Changes:   changed(nil, "expired", false, true),
 					Message:   "Certificate expired",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -553,7 +1145,6 @@ func (c *Comparator) compareCertificates(source, target map[string]models.Certif
 				Category:  "certificate",
 				Name:      path,
 				SourceVal: srcCert,
-				Severity:  "warning",
 				Message:   "Certificate removed",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -567,7 +1158,6 @@ func (c *Comparator) compareCertificates(source, target map[string]models.Certif
 				Category:  "certificate",
 				Name:      path,
 				TargetVal: tgtCert,
-				Severity:  "info",
 				Message:   "Certificate added",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -585,7 +1175,7 @@ func (c *Comparator) compareUserGroupConfig(source, target models.UserGroupConfi
 					Name:      name,
 					SourceVal: srcUser.UID,
 					TargetVal: tgtUser.UID,
-					Severity:  "warning",
+					Changes:   changed(nil, "uid", srcUser.UID, tgtUser.UID),
 					Message:   "User UID changed",
 				}
 				report.Drifts = append(report.Drifts, drift)
@@ -596,7 +1186,6 @@ func (c *Comparator) compareUserGroupConfig(source, target models.UserGroupConfi
 				Category:  "user",
 				Name:      name,
 				SourceVal: srcUser,
-				Severity:  "warning",
 				Message:   "User removed",
 			}
 			report.Drifts = append(report.Drifts, drift)
@@ -610,7 +1199,6 @@ func (c *Comparator) compareUserGroupConfig(source, target models.UserGroupConfi
 				Category:  "user",
 				Name:      name,
 				TargetVal: tgtUser,
-				Severity:  "warning",
 				Message:   "User added",
 			}
 			report.Drifts = append(report.Drifts, drift)
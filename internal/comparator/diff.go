@@ -0,0 +1,192 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around a change
+const diffContext = 3
+
+// edit is one line of a line diff: ' ' kept, '-' only in a, '+' only in b
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff renders the change from a to b as a unified diff, or "" when
+// the texts are the same
+func unifiedDiff(a, b, aName, bName string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	aLine, bLine := 1, 1
+	for start := 0; start < len(edits); {
+		// find the next change
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		for _, e := range edits[start:first] {
+			aLine, bLine = advance(e, aLine, bLine)
+		}
+
+		// a hunk runs until more than two contexts' worth of unchanged
+		// lines separate it from the next change
+		from := max(first-diffContext, start)
+		end := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		to := min(end+diffContext, len(edits))
+
+		hunkA, hunkB := aLine-(first-from), bLine-(first-from)
+		var countA, countB int
+		var body strings.Builder
+		for _, e := range edits[from:to] {
+			body.WriteString(string(e.op) + e.line + "\n")
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+		}
+
+		if sb.Len() == 0 {
+			sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", aName, bName))
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(hunkA, countA), hunkRange(hunkB, countB)))
+		sb.WriteString(body.String())
+
+		for _, e := range edits[first:to] {
+			aLine, bLine = advance(e, aLine, bLine)
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+func advance(e edit, aLine, bLine int) (int, int) {
+	if e.op != '+' {
+		aLine++
+	}
+	if e.op != '-' {
+		bLine++
+	}
+	return aLine, bLine
+}
+
+// hunkRange writes a hunk's start and length the way diff -u does: an
+// empty range starts at the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// maxEdits bounds the work diffLines does, texts further apart than this
+// are shown as replaced whole
+const maxEdits = 1000
+
+// diffLines finds a shortest edit script from a to b with Myers' algorithm
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+2)
+	// trace keeps the diagonals each round started from, only the ones it
+	// could reach
+	var trace [][]int
+
+	for d := 0; d <= n+m && d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d)
+			}
+		}
+	}
+
+	edits := make([]edit, 0, n+m)
+	for _, line := range a {
+		edits = append(edits, edit{'-', line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{'+', line})
+	}
+	return edits
+}
+
+// backtrack walks the saved rounds back from the end to recover the edits
+func backtrack(trace [][]int, a, b []string, d int) []edit {
+	x, y := len(a), len(b)
+	var edits []edit
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{'+', b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		y--
+		edits = append(edits, edit{' ', a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
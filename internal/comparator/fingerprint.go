@@ -0,0 +1,96 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// comparedFields are the attributes of a structured value that make up a
+// drift's identity. Values of other categories are taken whole; a file's
+// modification time, for one, changes without the file drifting any further.
+var comparedFields = map[string][]string{
+	"file":         {"hash", "mode", "owner", "group"},
+	"service":      {"status", "enabled"},
+	"package_file": {"version", "problems", "actual_hash", "actual_mode", "actual_owner"},
+}
+
+// valueDigest digests a drift value. It gives the same result for a value
+// read back from a JSON report, where it is no longer typed.
+func valueDigest(category string, v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ""
+	}
+
+	if fields, ok := comparedFields[category]; ok {
+		if m, isMap := value.(map[string]interface{}); isMap {
+			kept := make(map[string]interface{}, len(fields))
+			for _, f := range fields {
+				kept[f] = m[f]
+			}
+			value = kept
+		}
+	}
+
+	// maps marshal with sorted keys, so this is stable
+	data, _ = json.Marshal(value)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Fingerprint identifies a drift by what changed, so the same drift gets the
+// same ID in every report that contains it. The changed fields count too,
+// the values don't always hold them all: a cron job's is only its command.
+func Fingerprint(drift models.DriftItem) string {
+	parts := []string{
+		drift.Category,
+		drift.Name,
+		drift.Type,
+		valueDigest(drift.Category, drift.SourceVal),
+		valueDigest(drift.Category, drift.TargetVal),
+	}
+	if len(drift.Changes) > 0 {
+		parts = append(parts, valueDigest("", drift.Changes))
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// sortDrifts gives every drift its fingerprint and puts the report in a
+// fixed order, since the comparisons walk maps
+func sortDrifts(report *models.DriftReport) {
+	for i := range report.Drifts {
+		report.Drifts[i].ID = Fingerprint(report.Drifts[i])
+	}
+
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		a, b := report.Drifts[i], report.Drifts[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+}
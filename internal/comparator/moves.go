@@ -0,0 +1,138 @@
+package comparator
+
+import (
+	"path"
+	"sort"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// similarMove is the share of lines two captured files need in common to
+// count as one file that was moved and edited
+const similarMove = 0.5
+
+// move is a file found under a new path on the target
+type move struct {
+	from, to string
+}
+
+// findMoves pairs files only in the source with files only in the target.
+// Files with the same content pair first, then files whose captured text is
+// mostly the same. Empty files and directories never pair, there is nothing
+// to tell them apart by.
+func findMoves(source, target map[string]models.FileInfo, removed, added []string) []move {
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	byHash := make(map[string][]string)
+	for _, p := range removed {
+		if f := source[p]; movable(f) {
+			byHash[f.Hash] = append(byHash[f.Hash], p)
+		}
+	}
+
+	paired := make(map[string]bool)
+	var moves []move
+	for _, to := range added {
+		f := target[to]
+		if !movable(f) {
+			continue
+		}
+		// with several copies, the one keeping its name is the likely one
+		var from string
+		for _, p := range byHash[f.Hash] {
+			if paired[p] {
+				continue
+			}
+			if from == "" || path.Base(p) == path.Base(to) && path.Base(from) != path.Base(to) {
+				from = p
+			}
+		}
+		if from != "" {
+			paired[from], paired[to] = true, true
+			moves = append(moves, move{from, to})
+		}
+	}
+
+	srcLines := capturedLines(source, removed, paired)
+	tgtLines := capturedLines(target, added, paired)
+	for _, to := range added {
+		b, ok := tgtLines[to]
+		if !ok {
+			continue
+		}
+		var from string
+		best := similarMove
+		for _, p := range removed {
+			a, ok := srcLines[p]
+			if !ok || paired[p] {
+				continue
+			}
+			if s := similarity(a, b); s >= best && (from == "" || s > best) {
+				from, best = p, s
+			}
+		}
+		if from != "" {
+			paired[from] = true
+			moves = append(moves, move{from, to})
+		}
+	}
+	return moves
+}
+
+func movable(f models.FileInfo) bool {
+	return f.Exists && !f.IsDirectory && f.Hash != "" && f.Size > 0
+}
+
+// capturedLines reads the text of the files in paths not yet paired
+func capturedLines(files map[string]models.FileInfo, paths []string, paired map[string]bool) map[string][]string {
+	lines := make(map[string][]string)
+	for _, p := range paths {
+		if paired[p] || !movable(files[p]) {
+			continue
+		}
+		text, ok, err := files[p].Text()
+		if err == nil && ok {
+			lines[p] = splitLines(text)
+		}
+	}
+	return lines
+}
+
+// similarity is the share of lines a and b have in common, 0 to 1
+func similarity(a, b []string) float64 {
+	total := len(a) + len(b)
+	// even if every line of the shorter one were kept it wouldn't do
+	if total == 0 || float64(2*min(len(a), len(b)))/float64(total) < similarMove {
+		return 0
+	}
+
+	kept := 0
+	for _, e := range diffLines(a, b) {
+		if e.op == ' ' {
+			kept++
+		}
+	}
+	return float64(2*kept) / float64(total)
+}
+
+// movedDrift reports a file found under a new path, along with anything
+// else about it that changed
+func movedDrift(m move, src, tgt models.FileInfo) models.DriftItem {
+	changes := keyChanges(m.to, src, tgt, diffFile(src, tgt))
+	message := "File moved from " + m.from
+	if len(changes) > 0 {
+		message += " and changed: " + describeChanges(changes)
+	}
+
+	return models.DriftItem{
+		Type:      "moved",
+		Category:  "file",
+		Name:      m.to,
+		SourceVal: src,
+		TargetVal: tgt,
+		Changes:   append([]models.FieldChange{{Field: "path", Old: m.from, New: m.to}}, changes...),
+		Diff:      contentDiff(m.from, m.to, src, tgt),
+		Message:   message,
+	}
+}
@@ -0,0 +1,308 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+)
+
+// Normalization rewrites text that differs between environments by design,
+// like "staging." against "prod." or /opt/app-v12 against /opt/app-v13,
+// before two snapshots are compared. Category and Name narrow it down as in
+// rules, Name matching the item's name before any rewriting.
+//
+// Field is what gets rewritten: "name" for the item's name or path, "value"
+// for its main value (a file's captured text, a variable's or setting's
+// value, a package's version, a service's status, a cron job's command) or
+// one of its fields, such as "home_dir". Without a field both the name and
+// the value are rewritten.
+type Normalization struct {
+	Category string `yaml:"category"`
+	Name     string `yaml:"name"`
+	Field    string `yaml:"field"`
+	Match    string `yaml:"match"`   // regular expression
+	Replace  string `yaml:"replace"` // "$1" stands for the first group of the match
+}
+
+// valueFields are the fields "value" stands for in each category
+var valueFields = map[string]string{
+	"file":           "content",
+	"envvar":         "value",
+	"setting":        "value",
+	"package":        "version",
+	"service":        "status",
+	"scheduled_task": "command",
+}
+
+type compiledNormalization struct {
+	Normalization
+	name  func(string) bool
+	match *regexp.Regexp
+}
+
+// normalizer applies normalizations to copies of snapshots
+type normalizer []compiledNormalization
+
+// Normalize sets the normalizations applied to both snapshots before every
+// comparison
+func (c *Comparator) Normalize(normalizations []Normalization) error {
+	compiled := make(normalizer, 0, len(normalizations))
+	for i, n := range normalizations {
+		if n.Match == "" {
+			return fmt.Errorf("normalization %d: no match given", i+1)
+		}
+		cn := compiledNormalization{Normalization: n}
+		var err error
+		if cn.match, err = regexp.Compile(n.Match); err != nil {
+			return fmt.Errorf("normalization %d: %w", i+1, err)
+		}
+		if n.Name != "" {
+			if cn.name, err = pattern.Compile(n.Name); err != nil {
+				return fmt.Errorf("normalization %d: %w", i+1, err)
+			}
+		}
+		compiled = append(compiled, cn)
+	}
+	c.normalize = compiled
+	return nil
+}
+
+// rewrite runs s, the field of the named item, through every normalization
+// that applies to it, in order
+func (n normalizer) rewrite(category, name, field, s string) string {
+	for _, cn := range n {
+		if cn.applies(category, name, field) {
+			s = cn.match.ReplaceAllString(s, cn.Replace)
+		}
+	}
+	return s
+}
+
+func (cn compiledNormalization) applies(category, name, field string) bool {
+	if cn.Category != "" && cn.Category != category {
+		return false
+	}
+	if cn.name != nil && !cn.name(name) {
+		return false
+	}
+	value := valueFields[category]
+	switch cn.Field {
+	case "":
+		return field == "name" || field == value
+	case "value":
+		return field == value
+	}
+	return cn.Field == field
+}
+
+// touches tells whether any normalization applies to the field of the
+// named item, whether or not it changes anything there
+func (n normalizer) touches(category, name, field string) bool {
+	for _, cn := range n {
+		if cn.applies(category, name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// wants tells whether any normalization may touch the category
+func (n normalizer) wants(category string) bool {
+	for _, cn := range n {
+		if cn.Category == "" || cn.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot gives a copy of s with the normalizations applied, and notes on
+// the items it couldn't rename. Only the parts that get rewritten are copied;
+// s itself is left alone.
+func (n normalizer) snapshot(s *models.EnvironmentSnapshot, side string) (*models.EnvironmentSnapshot, []string) {
+	if len(n) == 0 {
+		return s, nil
+	}
+	out := *s
+	var notes []string
+	rename := func(category string, keys []string, scope func(string) string) map[string]string {
+		to, clashes := n.names(category, keys, scope)
+		for _, clash := range clashes {
+			notes = append(notes, fmt.Sprintf("normalize: %s on %s would share one name, so they keep their own", clash, side))
+		}
+		return to
+	}
+	same := func(name string) string { return name }
+
+	if n.wants("file") {
+		out.Files = make(map[string]models.FileInfo, len(s.Files))
+		paths := sortedNames(s.Files)
+		to := rename("file", paths, same)
+		for _, path := range paths {
+			f := s.Files[path]
+			f.Path = to[path]
+			f.Owner = n.rewrite("file", path, "owner", f.Owner)
+			f.Group = n.rewrite("file", path, "group", f.Group)
+			out.Files[f.Path] = n.content(path, f)
+		}
+	}
+	if n.wants("envvar") {
+		out.EnvVars = make(map[string]models.EnvVar, len(s.EnvVars))
+		names := sortedNames(s.EnvVars)
+		to := rename("envvar", names, same)
+		for _, name := range names {
+			v := s.EnvVars[name]
+			v.Name = to[name]
+			v.Value = n.rewrite("envvar", name, "value", v.Value)
+			out.EnvVars[v.Name] = v
+		}
+	}
+	if n.wants("setting") && s.Settings != nil {
+		out.Settings = make(map[string]string, len(s.Settings))
+		keys := sortedNames(s.Settings)
+		to := rename("setting", keys, same)
+		for _, key := range keys {
+			out.Settings[to[key]] = n.rewrite("setting", key, "value", s.Settings[key])
+		}
+	}
+	if n.wants("package") {
+		out.Packages = make(map[string]models.PackageInfo, len(s.Packages))
+		names := sortedNames(s.Packages)
+		to := rename("package", names, same)
+		for _, name := range names {
+			pkg := s.Packages[name]
+			pkg.Version = n.rewrite("package", name, "version", pkg.Version)
+			pkg.Architecture = n.rewrite("package", name, "architecture", pkg.Architecture)
+			out.Packages[to[name]] = pkg
+		}
+	}
+	if n.wants("service") {
+		out.Services = make(map[string]models.ServiceInfo, len(s.Services))
+		names := sortedNames(s.Services)
+		to := rename("service", names, same)
+		for _, name := range names {
+			svc := s.Services[name]
+			svc.Name = to[name]
+			svc.Status = n.rewrite("service", name, "status", svc.Status)
+			out.Services[svc.Name] = svc
+		}
+	}
+	if n.wants("scheduled_task") && s.ScheduledTasks.CronJobs != nil {
+		jobs := make(map[string]models.CronJob, len(s.ScheduledTasks.CronJobs))
+		keys := sortedNames(s.ScheduledTasks.CronJobs)
+		// drifts name cron jobs "<key> (cron)", so do the scopes
+		cron := func(key string) string { return key + " (cron)" }
+		to := rename("scheduled_task", keys, cron)
+		for _, key := range keys {
+			job := s.ScheduledTasks.CronJobs[key]
+			name := cron(key)
+			job.Command = n.rewrite("scheduled_task", name, "command", job.Command)
+			job.Schedule = n.rewrite("scheduled_task", name, "schedule", job.Schedule)
+			job.User = n.rewrite("scheduled_task", name, "user", job.User)
+			jobs[to[key]] = job
+		}
+		out.ScheduledTasks.CronJobs = jobs
+	}
+	if n.wants("certificate") && s.Certificates != nil {
+		out.Certificates = make(map[string]models.Certificate, len(s.Certificates))
+		paths := sortedNames(s.Certificates)
+		to := rename("certificate", paths, same)
+		for _, path := range paths {
+			cert := s.Certificates[path]
+			cert.Path = to[path]
+			cert.Domain = n.rewrite("certificate", path, "domain", cert.Domain)
+			cert.Subject = n.rewrite("certificate", path, "subject", cert.Subject)
+			cert.Issuer = n.rewrite("certificate", path, "issuer", cert.Issuer)
+			out.Certificates[cert.Path] = cert
+		}
+	}
+	if n.wants("user") && s.UserGroupConfig.Users != nil {
+		users := make(map[string]models.UserInfo, len(s.UserGroupConfig.Users))
+		names := sortedNames(s.UserGroupConfig.Users)
+		to := rename("user", names, same)
+		for _, name := range names {
+			u := s.UserGroupConfig.Users[name]
+			u.Name = to[name]
+			u.HomeDir = n.rewrite("user", name, "home_dir", u.HomeDir)
+			u.Shell = n.rewrite("user", name, "shell", u.Shell)
+			u.Comment = n.rewrite("user", name, "comment", u.Comment)
+			users[u.Name] = u
+		}
+		out.UserGroupConfig.Users = users
+	}
+	return &out, notes
+}
+
+// names gives the items of a category, by key, the names they're rewritten
+// to. Items that would end up with the same name would overwrite each other,
+// so they keep their own and are listed in clashes instead. scope gives the
+// name normalizations match a key against.
+func (n normalizer) names(category string, keys []string, scope func(string) string) (map[string]string, []string) {
+	to := make(map[string]string, len(keys))
+	for _, key := range keys {
+		to[key] = n.rewrite(category, scope(key), "name", key)
+	}
+
+	var clashes []string
+	// keeping a name may clash with another rewritten one, so repeat until
+	// every name is taken once
+	for {
+		taken := make(map[string][]string, len(keys))
+		for _, key := range keys {
+			taken[to[key]] = append(taken[to[key]], key)
+		}
+		kept := false
+		for _, name := range sortedNames(taken) {
+			if len(taken[name]) < 2 {
+				continue
+			}
+			clashes = append(clashes, fmt.Sprintf("%s %s", category, strings.Join(taken[name], ", ")))
+			for _, key := range taken[name] {
+				if to[key] != key {
+					to[key] = key
+					kept = true
+				}
+			}
+		}
+		if !kept {
+			return to, clashes
+		}
+	}
+}
+
+// content rewrites the captured text of a file. The hash is then taken from
+// the rewritten text so files that match after normalizing compare equal;
+// text with masked secrets isn't all of the file, so its hash stays.
+func (n normalizer) content(path string, f models.FileInfo) models.FileInfo {
+	text, ok, err := f.Text()
+	if err != nil || !ok {
+		return f
+	}
+	if !n.touches("file", path, "content") {
+		return f
+	}
+	rewritten := n.rewrite("file", path, "content", text)
+	if packed, err := models.CompressContent(rewritten); err == nil {
+		f.Content = packed
+	}
+	if !f.Masked {
+		sum := sha256.Sum256([]byte(rewritten))
+		f.Hash = hex.EncodeToString(sum[:])
+	}
+	return f
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
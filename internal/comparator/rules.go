@@ -0,0 +1,215 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+)
+
+// Rule assigns a severity, tags and an owner to the drifts it matches. Every
+// condition that is set has to match; an empty rule matches everything.
+//
+// Name, Package and CausedBy take a glob ("/etc/postgresql/*/postgresql.conf",
+// "**" crosses directories), a path prefix ending in "/" or a regular
+// expression prefixed with "re:". A condition on something the drift doesn't
+// have, such as the package of an unowned file, never matches.
+//
+// Change can list several kinds, "downgrade, major", which all have to hold.
+type Rule struct {
+	Category string `yaml:"category"` // file, package, service...
+	Type     string `yaml:"type"`     // added, removed, modified, moved
+	Name     string `yaml:"name"`
+	Field    string `yaml:"field"`     // a changed attribute, file "mode" or "hash", or a config key "key:server.port"
+	Package  string `yaml:"package"`   // the package a drift belongs to, "dpkg:openssl"
+	CausedBy string `yaml:"caused_by"` // the package change that explains a file drift
+	Change   string `yaml:"change"`    // a package version change: upgrade, downgrade, major, minor, patch, rebuild
+
+	Severity string   `yaml:"severity"`
+	Tags     []string `yaml:"tags"`
+	Owner    string   `yaml:"owner"`
+}
+
+// defaultRules hold the severities drift reports with no configured rules.
+// They are checked after the configured ones.
+var defaultRules = []Rule{
+	{Category: "certificate", Field: "expired", Severity: "critical"},
+	{Category: "certificate", Type: "added", Severity: "info"},
+	{Category: "package_file", Type: "removed", Severity: "info"},
+	// config files are meant to be edited
+	{Category: "package_file", Field: "config", Severity: "warning"},
+	{Category: "package_file", Field: "checksum", Severity: "critical"},
+	{Category: "package_file", Field: "missing", Severity: "critical"},
+	{Category: "docker", Type: "modified", Severity: "warning"},
+	{Category: "docker", Severity: "info"},
+	{Category: "resources", Severity: "critical"},
+	{Category: "file", Severity: "info"},
+	{Severity: "warning"},
+}
+
+// compiledRule is a Rule with its patterns parsed
+type compiledRule struct {
+	Rule
+	name, pkg, causedBy func(string) bool
+	change              []string
+}
+
+var versionChanges = []string{"upgrade", "downgrade", "major", "minor", "patch", "rebuild"}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		switch rule.Severity {
+		case "", "critical", "warning", "info":
+		default:
+			return nil, fmt.Errorf("rule %d: unknown severity %q", i+1, rule.Severity)
+		}
+
+		cr := compiledRule{Rule: rule}
+		cr.change = strings.FieldsFunc(rule.Change, func(r rune) bool { return r == ',' || r == ' ' })
+		for _, change := range cr.change {
+			if !contains(versionChanges, change) {
+				return nil, fmt.Errorf("rule %d: unknown version change %q", i+1, change)
+			}
+		}
+
+		for _, p := range []struct {
+			pattern string
+			match   *func(string) bool
+		}{{rule.Name, &cr.name}, {rule.Package, &cr.pkg}, {rule.CausedBy, &cr.causedBy}} {
+			if p.pattern == "" {
+				continue
+			}
+			match, err := pattern.Compile(p.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			*p.match = match
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+func (r compiledRule) matches(drift models.DriftItem, fields []string) bool {
+	if r.Category != "" && r.Category != drift.Category {
+		return false
+	}
+	if r.Type != "" && r.Type != drift.Type {
+		return false
+	}
+	if r.name != nil && !r.name(drift.Name) {
+		return false
+	}
+	if r.pkg != nil {
+		pkg := driftPackage(drift)
+		if pkg == "" || !r.pkg(pkg) {
+			return false
+		}
+	}
+	if r.causedBy != nil && (drift.CausedBy == "" || !r.causedBy(drift.CausedBy)) {
+		return false
+	}
+	if len(r.change) > 0 {
+		if drift.Version == nil {
+			return false
+		}
+		for _, change := range r.change {
+			if change != drift.Version.Direction && change != drift.Version.Bump {
+				return false
+			}
+		}
+	}
+	if r.Field != "" {
+		found := false
+		for _, f := range fields {
+			if f == r.Field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRules rates every drift. The first matching rule that sets a
+// severity decides it, the same goes for the owner, and the tags of every
+// matching rule are collected.
+func (c *Comparator) applyRules(report *models.DriftReport) {
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		fields := changedFields(*drift)
+
+		drift.Severity = ""
+		drift.Owner = ""
+		drift.Tags = nil
+		for _, rule := range c.rules {
+			if !rule.matches(*drift, fields) {
+				continue
+			}
+			if drift.Severity == "" {
+				drift.Severity = rule.Severity
+			}
+			if drift.Owner == "" {
+				drift.Owner = rule.Owner
+			}
+			for _, tag := range rule.Tags {
+				if !contains(drift.Tags, tag) {
+					drift.Tags = append(drift.Tags, tag)
+				}
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// driftPackage gives the package a drift is about: the package itself, the
+// package owning a file, or the package of a modified package file
+func driftPackage(drift models.DriftItem) string {
+	switch drift.Category {
+	case "package":
+		return drift.Name
+	case "file":
+		return filePackage(drift)
+	case "package_file":
+		for _, v := range []interface{}{drift.TargetVal, drift.SourceVal} {
+			if issue, ok := v.(models.PackageFileIssue); ok {
+				return issue.Package
+			}
+		}
+	}
+	return ""
+}
+
+// changedFields lists the attributes a drift changed, for rules matching on
+// a field: a file's "mode", or a config file key like "key:server.port".
+// Package files the package marks as configuration add "config".
+func changedFields(drift models.DriftItem) []string {
+	fields := make([]string, 0, len(drift.Changes)+1)
+	for _, ch := range drift.Changes {
+		fields = append(fields, ch.Field)
+	}
+	if issue, ok := drift.TargetVal.(models.PackageFileIssue); ok && issue.Config {
+		fields = append(fields, "config")
+	}
+	// a config file's content change is listed by key, "hash" still
+	// matches it
+	src, srcOK := drift.SourceVal.(models.FileInfo)
+	tgt, tgtOK := drift.TargetVal.(models.FileInfo)
+	if srcOK && tgtOK && src.Hash != tgt.Hash && !hasField(drift.Changes, "hash") {
+		fields = append(fields, "hash")
+	}
+	return fields
+}
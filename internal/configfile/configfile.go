@@ -0,0 +1,300 @@
+// Package configfile reads structured configuration files into flat key
+// value maps, so two versions can be compared key by key rather than line by
+// line. Nested keys are joined with dots and list items get their index:
+// "server.port", "servers[1].host".
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format tells the format of a file from its name, "" when it isn't one
+// this package reads
+func Format(name string) string {
+	base := path.Base(name)
+	switch {
+	case base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env"):
+		return "env"
+	}
+
+	switch strings.ToLower(path.Ext(base)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	case ".ini":
+		return "ini"
+	case ".properties":
+		return "properties"
+	}
+	return ""
+}
+
+// Parse reads text in format into its keys. Comments, blank lines and the
+// order of keys don't show in the result.
+func Parse(format, text string) (map[string]string, error) {
+	switch format {
+	case "yaml":
+		return parseYAML(text)
+	case "json":
+		return parseJSON(text)
+	case "toml":
+		return parseTOML(text)
+	case "ini":
+		return parseINI(text), nil
+	case "properties":
+		return parseProperties(text), nil
+	case "env":
+		return parseEnv(text), nil
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// Keys lists the keys of a parsed file in order
+func Keys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func parseYAML(text string) (map[string]string, error) {
+	var docs []interface{}
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	keys := make(map[string]string)
+	if len(docs) == 1 {
+		flatten(keys, "", docs[0])
+		return keys, nil
+	}
+	// a stream of documents, such as Kubernetes manifests, numbers them
+	for i, doc := range docs {
+		flatten(keys, fmt.Sprintf("[%d]", i), doc)
+	}
+	return keys, nil
+}
+
+func parseJSON(text string) (map[string]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	flatten(keys, "", doc)
+	return keys, nil
+}
+
+// flatten adds the scalars under v to keys
+func flatten(keys map[string]string, prefix string, v interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			keys[prefix] = "{}"
+		}
+		for key, item := range val {
+			flatten(keys, join(key), item)
+		}
+	case map[interface{}]interface{}:
+		if len(val) == 0 {
+			keys[prefix] = "{}"
+		}
+		for key, item := range val {
+			flatten(keys, join(fmt.Sprint(key)), item)
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			keys[prefix] = "[]"
+		}
+		for i, item := range val {
+			flatten(keys, fmt.Sprintf("%s[%d]", prefix, i), item)
+		}
+	case nil:
+		keys[prefix] = "null"
+	default:
+		keys[prefix] = fmt.Sprint(val)
+	}
+}
+
+func parseINI(text string) map[string]string {
+	keys := make(map[string]string)
+	section := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, found := cutAny(line, "=:")
+		if !found {
+			// a flag with no value, as in my.cnf
+			key, value = line, ""
+		}
+		key = strings.TrimSpace(key)
+		if section != "" {
+			key = section + "." + key
+		}
+		keys[key] = unquote(strings.TrimSpace(value))
+	}
+	return keys
+}
+
+func parseProperties(text string) map[string]string {
+	keys := make(map[string]string)
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// a line ending in an odd number of backslashes goes on
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSpace(lines[i])
+		}
+
+		end := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if line[j] == '=' || line[j] == ':' || line[j] == ' ' || line[j] == '\t' {
+				end = j
+				break
+			}
+		}
+		key := line[:end]
+		value := strings.TrimLeft(line[end:], " \t")
+		if value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t")
+		}
+		keys[key] = value
+	}
+	return keys
+}
+
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func parseEnv(text string) map[string]string {
+	keys := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			// a quoted value ends at its closing quote, whatever follows
+			value = value[:closingQuote(value)+1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		keys[strings.TrimSpace(key)] = unquote(value)
+	}
+	return keys
+}
+
+// closingQuote finds the quote that closes the one s starts with, or the
+// end of s
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && s[0] == '"':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// cutAny splits s around the first of the separator bytes
+func cutAny(s, seps string) (before, after string, found bool) {
+	if i := strings.IndexAny(s, seps); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
+
+// unquote takes the quotes off a quoted value, reading escapes in double
+// quotes the way shells and TOML basic strings do
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1]
+	case s[0] == '"' && s[len(s)-1] == '"':
+		var out bytes.Buffer
+		inner := s[1 : len(s)-1]
+		for i := 0; i < len(inner); i++ {
+			if inner[i] != '\\' || i+1 == len(inner) {
+				out.WriteByte(inner[i])
+				continue
+			}
+			i++
+			switch inner[i] {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			default:
+				out.WriteByte(inner[i])
+			}
+		}
+		return out.String()
+	}
+	return s
+}
@@ -0,0 +1,180 @@
+package configfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseTOML reads the TOML that configuration files use: tables, arrays of
+// tables, dotted keys and values. Arrays and inline tables are kept whole as
+// one value, with the spacing inside them evened out.
+func parseTOML(text string) (map[string]string, error) {
+	keys := make(map[string]string)
+	tables := make(map[string]int) // items seen so far of each array of tables
+	prefix := ""
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]") {
+			name := tomlKey(line[2 : len(line)-2])
+			prefix = fmt.Sprintf("%s[%d]", name, tables[name])
+			tables[name]++
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			prefix = tomlKey(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, found := cutOutsideQuotes(line, '=')
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		value = strings.TrimSpace(value)
+
+		// multi-line strings and arrays run on until they are closed
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(value, quote) {
+				for strings.Count(value, quote) < 2 && i+1 < len(lines) {
+					i++
+					value += "\n" + lines[i]
+				}
+			}
+		}
+		for strings.HasPrefix(value, "[") && !balanced(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		key = tomlKey(key)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		keys[key] = tomlValue(value)
+	}
+	return keys, nil
+}
+
+// tomlKey takes the quotes and spacing out of a dotted key
+func tomlKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = unquote(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, ".")
+}
+
+func tomlValue(value string) string {
+	switch {
+	case len(value) >= 6 && (strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, `'''`)):
+		// a newline right after the opening quotes isn't part of the string
+		return strings.TrimPrefix(value[3:len(value)-3], "\n")
+	case strings.HasPrefix(value, "["), strings.HasPrefix(value, "{"):
+		return compact(value)
+	}
+	return unquote(value)
+}
+
+// stripComment cuts a line at a # that isn't inside a string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// cutOutsideQuotes splits s at the first sep that isn't inside a string
+func cutOutsideQuotes(s string, sep byte) (before, after string, found bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// balanced tells whether every bracket outside strings is closed
+func balanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// compact drops the spacing outside strings and trailing commas, so an array
+// reflowed over several lines reads the same
+func compact(s string) string {
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			out.WriteByte(c)
+			if c == '\\' && quote == '"' && i+1 < len(s) {
+				i++
+				out.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == ',':
+			out.WriteString(", ")
+			continue
+		case c == ']' || c == '}':
+			if str := out.String(); strings.HasSuffix(str, ", ") {
+				out.Reset()
+				out.WriteString(str[:len(str)-2])
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/AshitomW/Drifty/internal/plan"
+)
+
+// Ansible writes the state as a playbook. Packages are installed one
+// manager at a time; everything else gets a task of its own.
+func (s *State) Ansible(w io.Writer) error {
+	left := s.leftOut("dpkg", "rpm", "apk", "pip", "npm")
+	if _, err := io.WriteString(w, s.header("# ", left)); err != nil {
+		return err
+	}
+
+	pl := plan.Play{
+		Name:   fmt.Sprintf("Make hosts look like %s", s.Snapshot.Name),
+		Hosts:  "all",
+		Become: true,
+	}
+	add := func(name, module string, args map[string]interface{}) {
+		t := plan.Task{Name: name}
+		t.Set(module, args)
+		pl.Tasks = append(pl.Tasks, t)
+	}
+
+	for _, manager := range sortedKeys(s.Packages) {
+		pkgs := s.Packages[manager]
+		name := fmt.Sprintf("Install %s packages", manager)
+		switch manager {
+		case "dpkg":
+			add(name, "ansible.builtin.apt", map[string]interface{}{
+				"name": pinned(pkgs, "="), "state": "present", "allow_downgrade": true})
+		case "rpm":
+			add(name, "ansible.builtin.dnf", map[string]interface{}{
+				"name": pinned(pkgs, "-"), "state": "present", "allow_downgrade": true})
+		case "apk":
+			add(name, "community.general.apk", map[string]interface{}{
+				"name": pinned(pkgs, "="), "state": "present"})
+		case "pip":
+			add(name, "ansible.builtin.pip", map[string]interface{}{
+				"name": pinned(pkgs, "==")})
+		case "npm":
+			for _, pkg := range pkgs {
+				add("Install npm package "+pkg.Name, "community.general.npm", map[string]interface{}{
+					"name": pkg.Name, "version": pkg.Version, "global": true})
+			}
+		}
+	}
+
+	for _, g := range s.Groups {
+		add("Group "+g.Name, "ansible.builtin.group", map[string]interface{}{
+			"name": g.Name, "gid": g.GID})
+	}
+
+	for _, u := range s.Users {
+		args := map[string]interface{}{"name": u.Name, "uid": u.UID, "home": u.HomeDir, "shell": u.Shell}
+		if u.Group != "" {
+			args["group"] = u.Group
+		}
+		if len(u.Groups) > 0 {
+			args["groups"] = u.Groups
+			args["append"] = true
+		}
+		if u.Comment != "" {
+			args["comment"] = u.Comment
+		}
+		add("User "+u.Name, "ansible.builtin.user", args)
+	}
+
+	for _, svc := range s.Services {
+		state := "stopped"
+		if svc.Status == "running" {
+			state = "started"
+		}
+		add("Service "+svc.Name, "ansible.builtin.systemd_service", map[string]interface{}{
+			"name": svc.Name, "state": state, "enabled": svc.Enabled})
+	}
+
+	for _, cron := range s.Cron {
+		add("Cron "+cron.File+": "+cron.Line, "ansible.builtin.lineinfile", map[string]interface{}{
+			"path": cron.File, "line": cron.Line, "create": true})
+	}
+
+	for _, f := range s.Files {
+		args := map[string]interface{}{"path": f.Path, "mode": f.Mode, "owner": f.Owner, "group": f.Group}
+		// a plain file has to be put in place first, only a directory can
+		// be made without its content
+		if f.Directory {
+			args["state"] = "directory"
+		}
+		add("File "+f.Path, "ansible.builtin.file", args)
+	}
+
+	return plan.WritePlaybook(w, pl)
+}
@@ -0,0 +1,183 @@
+// Package export writes a snapshot out as a desired-state definition, an
+// Ansible playbook, a Salt state file or a policy, so new hosts can be built
+// to look like the one the snapshot was taken on.
+package export
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/plan"
+)
+
+// State is what a snapshot declares, sorted by name
+type State struct {
+	Snapshot *models.EnvironmentSnapshot
+
+	Packages map[string][]models.PackageInfo // by manager
+	Services []models.ServiceInfo
+	Groups   []models.GroupInfo
+	Users    []User
+	Cron     []plan.CronStep
+	Files    []File
+
+	Skipped []string // what couldn't be expressed, for the header
+}
+
+// User is an account with its primary group's name and the other groups it
+// belongs to
+type User struct {
+	models.UserInfo
+	Group  string
+	Groups []string
+}
+
+// File is a file's permissions and ownership. The content isn't in the
+// snapshot, only its hash.
+type File struct {
+	Path      string
+	Mode      string // octal, "0644"
+	Owner     string
+	Group     string
+	Directory bool
+}
+
+// From collects the state a snapshot declares
+func From(snapshot *models.EnvironmentSnapshot) *State {
+	s := &State{Snapshot: snapshot, Packages: make(map[string][]models.PackageInfo)}
+
+	for _, key := range sortedKeys(snapshot.Packages) {
+		pkg := snapshot.Packages[key]
+		if pkg.Exists || pkg.Version != "" {
+			s.Packages[pkg.Manager] = append(s.Packages[pkg.Manager], pkg)
+		}
+	}
+
+	for _, name := range sortedKeys(snapshot.Services) {
+		svc := snapshot.Services[name]
+		if svc.Exists {
+			svc.Name = name
+			s.Services = append(s.Services, svc)
+		}
+	}
+
+	groups := snapshot.UserGroupConfig.Groups
+	groupNames := make(map[int]string)
+	memberOf := make(map[string][]string)
+	for _, name := range sortedKeys(groups) {
+		g := groups[name]
+		s.Groups = append(s.Groups, g)
+		groupNames[g.GID] = g.Name
+		for _, member := range g.Members {
+			memberOf[member] = append(memberOf[member], g.Name)
+		}
+	}
+
+	for _, name := range sortedKeys(snapshot.UserGroupConfig.Users) {
+		u := snapshot.UserGroupConfig.Users[name]
+		s.Users = append(s.Users, User{UserInfo: u, Group: groupNames[u.GID], Groups: memberOf[u.Name]})
+	}
+
+	for _, key := range sortedKeys(snapshot.ScheduledTasks.CronJobs) {
+		file, line, ok := plan.CronLine(key, snapshot.ScheduledTasks.CronJobs[key])
+		if !ok {
+			s.Skipped = append(s.Skipped, "cron job "+key)
+			continue
+		}
+		s.Cron = append(s.Cron, plan.CronStep{File: file, Line: line})
+	}
+
+	special := make(map[string]int) // kind, files
+	for _, path := range sortedKeys(snapshot.Files) {
+		f := snapshot.Files[path]
+		if !f.Exists {
+			continue
+		}
+		if kind := fileKind(f.Mode); kind != "" {
+			special[kind]++
+			continue
+		}
+		mode, ok := plan.OctalMode(f.Mode)
+		if !ok {
+			special["file with an unknown mode"]++
+			continue
+		}
+		s.Files = append(s.Files, File{Path: path, Mode: mode, Owner: f.Owner, Group: f.Group, Directory: f.IsDirectory})
+	}
+	for _, kind := range sortedKeys(special) {
+		s.Skipped = append(s.Skipped, fmt.Sprintf("%d %s(s)", special[kind], kind))
+	}
+
+	return s
+}
+
+// fileKind names what a file that isn't a regular file or directory is,
+// from the type letters in front of its mode ("Lrwxrwxrwx" is a symbolic
+// link), or gives "" for regular files and directories
+func fileKind(mode string) string {
+	if len(mode) < 10 {
+		return "file with an unknown mode"
+	}
+	kind := strings.Trim(mode[:len(mode)-9], "-dugt") // setuid, setgid and sticky are permissions
+	switch {
+	case kind == "":
+		return ""
+	case strings.HasPrefix(kind, "L"):
+		return "symbolic link"
+	case strings.HasPrefix(kind, "D"):
+		return "device"
+	case strings.HasPrefix(kind, "p"):
+		return "named pipe"
+	case strings.HasPrefix(kind, "S"):
+		return "socket"
+	}
+	return "file that isn't a regular file or directory"
+}
+
+// header says where the definition came from and lists what it leaves out
+func (s *State) header(prefix string, left []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%sDesired state of %s (%s), from snapshot %s taken %s\n", prefix,
+		plan.OneLine(s.Snapshot.Name), plan.OneLine(s.Snapshot.Hostname), plan.OneLine(s.Snapshot.ID), s.Snapshot.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC")))
+	sb.WriteString(prefix + "Generated by drift export. File content isn't in a snapshot, only permissions and owners are.\n")
+	if len(left) > 0 {
+		sb.WriteString(fmt.Sprintf("%sLeft out (%d):\n", prefix, len(left)))
+		for _, l := range left {
+			sb.WriteString(fmt.Sprintf("%s  %s\n", prefix, plan.OneLine(l)))
+		}
+	}
+	return sb.String()
+}
+
+// leftOut adds the packages of managers a format can't install to what the
+// snapshot couldn't express
+func (s *State) leftOut(managers ...string) []string {
+	left := append([]string(nil), s.Skipped...)
+	for _, manager := range sortedKeys(s.Packages) {
+		if !slices.Contains(managers, manager) {
+			left = append(left, fmt.Sprintf("%d %s package(s)", len(s.Packages[manager]), manager))
+		}
+	}
+	return left
+}
+
+// pinned names each package at its version, joined by sep: "nginx=1.24.0-1"
+func pinned(pkgs []models.PackageInfo, sep string) []string {
+	names := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name+sep+pkg.Version)
+	}
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/AshitomW/Drifty/internal/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy writes the state as a policy for drift check. Policies have no
+// checks for groups or cron jobs, so those are left out.
+func (s *State) Policy(w io.Writer) error {
+	left := append([]string(nil), s.Skipped...)
+	if len(s.Groups) > 0 {
+		left = append(left, fmt.Sprintf("%d group(s)", len(s.Groups)))
+	}
+	if len(s.Cron) > 0 {
+		left = append(left, fmt.Sprintf("%d cron job(s)", len(s.Cron)))
+	}
+	if _, err := io.WriteString(w, s.header("# ", left)); err != nil {
+		return err
+	}
+
+	yes, no := true, false
+	p := policy.Policy{Name: s.Snapshot.Name}
+
+	for _, manager := range sortedKeys(s.Packages) {
+		for _, pkg := range s.Packages[manager] {
+			p.Checks = append(p.Checks, policy.Check{Package: manager + ":" + pkg.Name, Version: "= " + pkg.Version})
+		}
+	}
+
+	for _, u := range s.Users {
+		uid := u.UID
+		p.Checks = append(p.Checks, policy.Check{User: u.Name, UID: &uid, Home: u.HomeDir, Shell: u.Shell})
+	}
+
+	for _, svc := range s.Services {
+		running := &no
+		if svc.Status == "running" {
+			running = &yes
+		}
+		enabled := &no
+		if svc.Enabled {
+			enabled = &yes
+		}
+		p.Checks = append(p.Checks, policy.Check{Service: svc.Name, Running: running, Enabled: enabled})
+	}
+
+	for _, f := range s.Files {
+		p.Checks = append(p.Checks, policy.Check{File: f.Path, Mode: f.Mode, Owner: f.Owner, Group: f.Group})
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
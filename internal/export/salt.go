@@ -0,0 +1,139 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// saltState is one entry of an SLS file: an ID, the state function and its
+// arguments, kept in the order they were added
+type saltState struct {
+	id   string
+	fn   string
+	args []saltArg
+}
+
+type saltArg struct {
+	key   string
+	value interface{}
+}
+
+func (st *saltState) arg(key string, value interface{}) *saltState {
+	st.args = append(st.args, saltArg{key, value})
+	return st
+}
+
+// Salt writes the state as an SLS file. Salt applies states in the order
+// they're written, so packages come before the users, services and files
+// that need them.
+func (s *State) Salt(w io.Writer) error {
+	left := s.leftOut("dpkg", "rpm", "apk", "pip", "npm")
+	if _, err := io.WriteString(w, s.header("# ", left)); err != nil {
+		return err
+	}
+
+	var states []*saltState
+	add := func(id, fn string) *saltState {
+		st := &saltState{id: id, fn: fn}
+		states = append(states, st)
+		return st
+	}
+
+	for _, manager := range sortedKeys(s.Packages) {
+		pkgs := s.Packages[manager]
+		id := manager + "-packages"
+		switch manager {
+		case "dpkg", "rpm", "apk":
+			var list []map[string]string
+			for _, pkg := range pkgs {
+				list = append(list, map[string]string{pkg.Name: pkg.Version})
+			}
+			add(id, "pkg.installed").arg("pkgs", list)
+		case "pip":
+			add(id, "pip.installed").arg("pkgs", pinned(pkgs, "=="))
+		case "npm":
+			add(id, "npm.installed").arg("pkgs", pinned(pkgs, "@"))
+		}
+	}
+
+	for _, g := range s.Groups {
+		add("group-"+g.Name, "group.present").arg("name", g.Name).arg("gid", g.GID)
+	}
+
+	for _, u := range s.Users {
+		st := add("user-"+u.Name, "user.present").
+			arg("name", u.Name).arg("uid", u.UID).arg("gid", u.GID).
+			arg("home", u.HomeDir).arg("shell", u.Shell)
+		if u.Comment != "" {
+			st.arg("fullname", u.Comment)
+		}
+		if len(u.Groups) > 0 {
+			st.arg("groups", u.Groups).arg("remove_groups", false)
+		}
+	}
+
+	for _, svc := range s.Services {
+		fn := "service.dead"
+		if svc.Status == "running" {
+			fn = "service.running"
+		}
+		add("service-"+svc.Name, fn).arg("name", svc.Name).arg("enable", svc.Enabled)
+	}
+
+	for i, cron := range s.Cron {
+		add(fmt.Sprintf("cron-%d", i+1), "file.append").arg("name", cron.File).arg("text", cron.Line)
+	}
+
+	for _, f := range s.Files {
+		st := add("file-"+f.Path, "file.managed")
+		if f.Directory {
+			st.fn = "file.directory"
+		}
+		st.arg("name", f.Path).arg("mode", f.Mode).arg("user", f.Owner).arg("group", f.Group)
+		if !f.Directory {
+			// the content isn't known, so only an existing file is touched
+			st.arg("replace", false).arg("create", false)
+		}
+	}
+
+	doc, err := saltDocument(states)
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// saltDocument lays the states out the way Salt reads them:
+//
+//	id:
+//	  fn:
+//	    - key: value
+func saltDocument(states []*saltState) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, st := range states {
+		args := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, a := range st.args {
+			arg := &yaml.Node{}
+			if err := arg.Encode(map[string]interface{}{a.key: a.value}); err != nil {
+				return nil, err
+			}
+			args.Content = append(args.Content, arg)
+		}
+
+		fn := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{scalar(st.fn), args}}
+		doc.Content = append(doc.Content, scalar(st.id), fn)
+	}
+	return doc, nil
+}
+
+func scalar(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
@@ -0,0 +1,259 @@
+package fleet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/comparator"
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/google/uuid"
+)
+
+// Strategies for deciding which items make it into a baseline
+const (
+	Majority     = "majority"     // the value more than half of the hosts have
+	Intersection = "intersection" // only items every host has the same
+)
+
+// DefaultExclusions are the host-specific items no baseline should carry
+func DefaultExclusions() []models.Exclusion {
+	var exclusions []models.Exclusion
+	for _, name := range DefaultIgnore {
+		exclusions = append(exclusions, models.Exclusion{Name: name})
+	}
+	return exclusions
+}
+
+// BaselineStats counts what happened to the items of the hosts
+type BaselineStats struct {
+	Kept     int
+	Dropped  int // the hosts didn't agree enough
+	Excluded int
+}
+
+// Baseline builds a snapshot holding what the hosts agree on. It carries no
+// hostname, MAC or IP addresses, and the sections it can't speak for, like
+// docker or system resources, are marked skipped so comparisons leave them
+// alone. The exclusions are kept with the baseline, so the excluded items
+// aren't reported when a host is compared against it either.
+func Baseline(hosts []Host, strategy string, exclude []models.Exclusion) (*models.EnvironmentSnapshot, BaselineStats, error) {
+	var stats BaselineStats
+	if len(hosts) < 2 {
+		return nil, stats, fmt.Errorf("a baseline needs at least two snapshots, got %d", len(hosts))
+	}
+	if strategy != Majority && strategy != Intersection {
+		return nil, stats, fmt.Errorf("unknown strategy %q, use %s or %s", strategy, Majority, Intersection)
+	}
+
+	excluded, err := comparator.Excluder(exclude)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	names := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		names = append(names, h.Name)
+	}
+	baseline := &models.EnvironmentSnapshot{
+		ID:         uuid.New().String(),
+		Timestamp:  time.Now().UTC(),
+		Files:      make(map[string]models.FileInfo),
+		EnvVars:    make(map[string]models.EnvVar),
+		Packages:   make(map[string]models.PackageInfo),
+		Services:   make(map[string]models.ServiceInfo),
+		Collectors: make(map[string]models.CollectorStatus),
+		Baseline: &models.BaselineInfo{
+			Strategy: strategy,
+			Hosts:    names,
+			Exclude:  exclude,
+		},
+	}
+	counts := make(map[string]int) // collector, items kept
+
+	for _, cat := range categories {
+		// like Compare, hosts whose collector failed are left out, and items
+		// missing on hosts where it only partly worked don't count
+		var present []Host
+		partial := make(map[string]bool)
+		for _, h := range hosts {
+			status, ok := h.Snapshot.Collectors[cat.collector]
+			switch {
+			case !ok || status.Status == "ok":
+			case status.Status == "partial":
+				partial[h.Name] = true
+			default:
+				continue
+			}
+			present = append(present, h)
+		}
+		if len(present) == 0 {
+			continue
+		}
+		if _, ok := counts[cat.collector]; !ok {
+			counts[cat.collector] = 0
+		}
+
+		items := make(map[string]map[string]string, len(present)) // host, item, value
+		all := make(map[string]bool)
+		for _, h := range present {
+			items[h.Name] = cat.items(h.Snapshot)
+			for name := range items[h.Name] {
+				all[name] = true
+			}
+		}
+
+		for _, name := range sortedKeys(all) {
+			if excluded(cat.name, name) {
+				stats.Excluded++
+				continue
+			}
+			from, ok := agreed(name, present, items, partial, strategy)
+			if !ok {
+				stats.Dropped++
+				continue
+			}
+			cat.keep(baseline, from.Snapshot, name)
+			counts[cat.collector]++
+			stats.Kept++
+		}
+	}
+
+	for name, kept := range counts {
+		if name != "" {
+			baseline.Collectors[name] = models.CollectorStatus{Name: name, Status: "ok", ItemCount: kept}
+		}
+	}
+	for _, h := range hosts {
+		for name := range h.Snapshot.Collectors {
+			if _, ok := baseline.Collectors[name]; !ok {
+				baseline.Collectors[name] = models.CollectorStatus{Name: name, Status: "skipped", Message: "not part of a baseline built from several hosts"}
+			}
+		}
+	}
+	return baseline, stats, nil
+}
+
+// agreed finds a host holding the value the strategy settles on for an item
+func agreed(name string, hosts []Host, items map[string]map[string]string, partial map[string]bool, strategy string) (Host, bool) {
+	count := make(map[string]int)
+	first := make(map[string]Host)
+	counted := 0
+	for _, h := range hosts {
+		value, ok := items[h.Name][name]
+		if !ok {
+			// a host that may have missed the item only can't outvote it,
+			// an intersection still needs it everywhere
+			if partial[h.Name] && strategy == Majority {
+				continue
+			}
+			value = "\x00missing"
+		}
+		if _, seen := first[value]; !seen {
+			first[value] = h
+		}
+		count[value]++
+		counted++
+	}
+
+	for value, n := range count {
+		if value == "\x00missing" {
+			continue
+		}
+		if n == counted || (strategy == Majority && n*2 > counted) {
+			return first[value], true
+		}
+	}
+	return Host{}, false
+}
+
+// The keep functions copy one item, found by the name the item functions
+// gave it, from a host's snapshot into the baseline.
+
+func keepOS(dst, src *models.EnvironmentSnapshot, name string) {
+	switch name {
+	case "release":
+		dst.OS.Name, dst.OS.Version = src.OS.Name, src.OS.Version
+	case "kernel":
+		dst.OS.Kernel = src.OS.Kernel
+	case "arch":
+		dst.OS.Arch = src.OS.Arch
+	}
+}
+
+func keepPackage(dst, src *models.EnvironmentSnapshot, name string) {
+	dst.Packages[name] = src.Packages[name]
+}
+
+func keepService(dst, src *models.EnvironmentSnapshot, name string) {
+	dst.Services[name] = src.Services[name]
+}
+
+func keepFile(dst, src *models.EnvironmentSnapshot, name string) {
+	dst.Files[name] = src.Files[name]
+}
+
+func keepEnv(dst, src *models.EnvironmentSnapshot, name string) {
+	dst.EnvVars[name] = src.EnvVars[name]
+}
+
+func keepTask(dst, src *models.EnvironmentSnapshot, name string) {
+	tasks := &dst.ScheduledTasks
+	if key, ok := strings.CutSuffix(name, " (cron)"); ok {
+		if tasks.CronJobs == nil {
+			tasks.CronJobs = make(map[string]models.CronJob)
+		}
+		tasks.CronJobs[key] = src.ScheduledTasks.CronJobs[key]
+		return
+	}
+	key := strings.TrimSuffix(name, " (timer)")
+	if tasks.SystemdTimers == nil {
+		tasks.SystemdTimers = make(map[string]models.SystemdTimer)
+	}
+	tasks.SystemdTimers[key] = src.ScheduledTasks.SystemdTimers[key]
+}
+
+func keepCertificate(dst, src *models.EnvironmentSnapshot, name string) {
+	if dst.Certificates == nil {
+		dst.Certificates = make(map[string]models.Certificate)
+	}
+	dst.Certificates[name] = src.Certificates[name]
+}
+
+func keepUser(dst, src *models.EnvironmentSnapshot, name string) {
+	if dst.UserGroupConfig.Users == nil {
+		dst.UserGroupConfig.Users = make(map[string]models.UserInfo)
+	}
+	dst.UserGroupConfig.Users[name] = src.UserGroupConfig.Users[name]
+}
+
+func keepGroup(dst, src *models.EnvironmentSnapshot, name string) {
+	if dst.UserGroupConfig.Groups == nil {
+		dst.UserGroupConfig.Groups = make(map[string]models.GroupInfo)
+	}
+	dst.UserGroupConfig.Groups[name] = src.UserGroupConfig.Groups[name]
+}
+
+func keepSetting(dst, src *models.EnvironmentSnapshot, name string) {
+	if dst.Settings == nil {
+		dst.Settings = make(map[string]string)
+	}
+	dst.Settings[name] = src.Settings[name]
+}
+
+// keepNetwork drops the addresses, the comparator skips what a baseline
+// leaves empty
+func keepNetwork(dst, src *models.EnvironmentSnapshot, name string) {
+	network := &dst.NetworkConfig
+	if name == "dns" {
+		network.DNS = src.NetworkConfig.DNS
+		return
+	}
+	key := strings.TrimSuffix(name, " (interface)")
+	iface := src.NetworkConfig.Interfaces[key]
+	iface.MACAddress, iface.IPAddresses = "", nil
+	if network.Interfaces == nil {
+		network.Interfaces = make(map[string]models.NetworkInterface)
+	}
+	network.Interfaces[key] = iface
+}
@@ -0,0 +1,255 @@
+// Package fleet compares the snapshots of many hosts that should be alike.
+// Rather than comparing them in pairs, it lines up every item across all
+// hosts and points out the hosts that differ from the rest.
+package fleet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+	"github.com/google/uuid"
+)
+
+// Host is a snapshot and the name it goes by in the report
+type Host struct {
+	Name     string
+	Snapshot *models.EnvironmentSnapshot
+}
+
+// DefaultIgnore are items that differ on every host by design
+var DefaultIgnore = []string{
+	"/etc/hostname",
+	"/etc/machine-id",
+	"/var/lib/dbus/machine-id",
+	"/etc/ssh/ssh_host_*",
+	"HOSTNAME",
+}
+
+// category is a part of the snapshot lined up across hosts, with the
+// collector that fills it. keep copies an item from one snapshot to
+// another, for building baselines.
+type category struct {
+	name      string
+	collector string
+	items     func(s *models.EnvironmentSnapshot) map[string]string
+	keep      func(dst, src *models.EnvironmentSnapshot, name string)
+}
+
+var categories = []category{
+	{"os", "", osItems, keepOS},
+	{"package", "packages", packageItems, keepPackage},
+	{"service", "services", serviceItems, keepService},
+	{"file", "files", fileItems, keepFile},
+	{"envvar", "env_vars", envItems, keepEnv},
+	{"scheduled_task", "scheduled_tasks", taskItems, keepTask},
+	{"certificate", "certificates", certificateItems, keepCertificate},
+	{"user", "users_groups", userItems, keepUser},
+	{"group", "users_groups", groupItems, keepGroup},
+	{"setting", "settings", settingItems, keepSetting},
+	{"network", "network", networkItems, keepNetwork},
+}
+
+// IsCategory tells whether name is one of the categories hosts are lined up in
+func IsCategory(name string) bool {
+	for _, cat := range categories {
+		if cat.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxNamed is how many hosts a message names before counting the rest
+const maxNamed = 5
+
+// Compare lines up the hosts' snapshots. Items whose name matches one of the
+// ignore patterns are left out.
+func Compare(hosts []Host, ignore []string) (*models.FleetReport, error) {
+	if len(hosts) < 2 {
+		return nil, fmt.Errorf("a fleet needs at least two snapshots, got %d", len(hosts))
+	}
+
+	var ignored []func(string) bool
+	for _, p := range ignore {
+		match, err := pattern.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		ignored = append(ignored, match)
+	}
+	skip := func(name string) bool {
+		for _, match := range ignored {
+			if match(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	report := &models.FleetReport{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now().UTC(),
+	}
+	outliers := make(map[string]int)
+
+	for _, cat := range categories {
+		// hosts whose collector failed would look like they lack everything
+		// in the category, the ones where it only partly worked may lack
+		// some of it
+		var present []Host
+		partial := make(map[string]bool)
+		left := make(map[string][]string) // status, hosts
+		for _, h := range hosts {
+			status, ok := h.Snapshot.Collectors[cat.collector]
+			switch {
+			case !ok || status.Status == "ok":
+			case status.Status == "partial":
+				partial[h.Name] = true
+			default:
+				left[status.Status] = append(left[status.Status], h.Name)
+				continue
+			}
+			present = append(present, h)
+		}
+		for _, status := range sortedKeys(left) {
+			if status != "skipped" {
+				report.Notes = append(report.Notes, fmt.Sprintf("%s: collection %s on %s, left out", cat.name, status, hostList(left[status])))
+			}
+		}
+		if len(partial) > 0 {
+			report.Notes = append(report.Notes, fmt.Sprintf("%s: collection partial on %s, items missing there are not counted", cat.name, hostList(sortedKeys(partial))))
+		}
+		if len(present) < 2 {
+			continue
+		}
+
+		values := make(map[string]map[string]string, len(present)) // item, host, value
+		for _, h := range present {
+			for name, value := range cat.items(h.Snapshot) {
+				if skip(name) {
+					continue
+				}
+				if values[name] == nil {
+					values[name] = make(map[string]string)
+				}
+				values[name][h.Name] = value
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		score := models.FleetCategory{Category: cat.name, Items: len(values)}
+		for _, name := range sortedKeys(values) {
+			item, ok := lineUp(cat.name, name, present, values[name], partial)
+			if !ok {
+				score.Consistent++
+				continue
+			}
+			if item.Majority {
+				for _, v := range item.Values[1:] {
+					for _, host := range v.Hosts {
+						outliers[host]++
+					}
+				}
+			}
+			report.Items = append(report.Items, item)
+		}
+		score.Score = float64(score.Consistent) * 100 / float64(score.Items)
+		report.Categories = append(report.Categories, score)
+	}
+
+	for _, h := range hosts {
+		report.Hosts = append(report.Hosts, models.FleetHost{Name: h.Name, Snapshot: h.Snapshot.ID, Outliers: outliers[h.Name]})
+	}
+	return report, nil
+}
+
+// lineUp groups the hosts by the value they have for an item, biggest group
+// first. It reports false when every host has the same value.
+func lineUp(category, name string, hosts []Host, values map[string]string, partial map[string]bool) (models.FleetItem, bool) {
+	groups := make(map[string]*models.FleetVariant)
+	var order []*models.FleetVariant
+	for _, h := range hosts {
+		value, ok := values[h.Name]
+		if !ok && partial[h.Name] {
+			continue
+		}
+		key := "\x00missing"
+		if ok {
+			key = value
+		}
+		g := groups[key]
+		if g == nil {
+			g = &models.FleetVariant{Value: value, Missing: !ok}
+			groups[key] = g
+			order = append(order, g)
+		}
+		g.Hosts = append(g.Hosts, h.Name)
+	}
+	if len(order) < 2 {
+		return models.FleetItem{}, false
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return len(order[i].Hosts) > len(order[j].Hosts) })
+	item := models.FleetItem{
+		Category: category,
+		Name:     name,
+		Majority: len(order[0].Hosts) > len(order[1].Hosts),
+	}
+	for _, g := range order {
+		item.Values = append(item.Values, *g)
+	}
+	item.Message = message(item)
+	return item, true
+}
+
+// message says how the hosts differ: "web7 has 1.22, the other 11 have 1.24"
+func message(item models.FleetItem) string {
+	var parts []string
+	rest := item.Values
+	if item.Majority {
+		rest = item.Values[1:]
+	}
+	for _, v := range rest {
+		parts = append(parts, hostList(v.Hosts)+" "+has(v, len(v.Hosts)))
+	}
+	if !item.Majority {
+		return "no majority: " + strings.Join(parts, "; ")
+	}
+
+	majority := item.Values[0]
+	return fmt.Sprintf("%s, the other %d %s", strings.Join(parts, "; "), len(majority.Hosts), has(majority, 2))
+}
+
+func has(v models.FleetVariant, hosts int) string {
+	switch {
+	case v.Missing && hosts == 1:
+		return "doesn't have it"
+	case v.Missing:
+		return "don't have it"
+	case hosts == 1:
+		return "has " + v.Value
+	}
+	return "have " + v.Value
+}
+
+func hostList(hosts []string) string {
+	if len(hosts) <= maxNamed {
+		return strings.Join(hosts, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(hosts[:maxNamed], ", "), len(hosts)-maxNamed)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
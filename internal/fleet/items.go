@@ -0,0 +1,147 @@
+package fleet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// The item functions give every item of a category by name, with a value
+// that reads well in a message and is the same whenever the item is.
+// Things that always differ between hosts, like modification times, are
+// left out of the values.
+
+func osItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string)
+	if s.OS.Name != "" {
+		items["release"] = strings.TrimSpace(s.OS.Name + " " + s.OS.Version)
+	}
+	if s.OS.Kernel != "" {
+		items["kernel"] = s.OS.Kernel
+	}
+	if s.OS.Arch != "" {
+		items["arch"] = s.OS.Arch
+	}
+	return items
+}
+
+func packageItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.Packages))
+	for name, pkg := range s.Packages {
+		items[name] = pkg.Version
+	}
+	return items
+}
+
+func serviceItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.Services))
+	for name, svc := range s.Services {
+		enabled := "disabled"
+		if svc.Enabled {
+			enabled = "enabled"
+		}
+		items[name] = svc.Status + ", " + enabled
+	}
+	return items
+}
+
+func fileItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.Files))
+	for path, f := range s.Files {
+		what := "directory"
+		if !f.IsDirectory {
+			what = "content " + short(f.Hash)
+		}
+		items[path] = fmt.Sprintf("%s, %s %s:%s", what, f.Mode, f.Owner, f.Group)
+	}
+	return items
+}
+
+func envItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.EnvVars))
+	for name, v := range s.EnvVars {
+		items[name] = fmt.Sprintf("%q", v.Value)
+	}
+	return items
+}
+
+func taskItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string)
+	for key, job := range s.ScheduledTasks.CronJobs {
+		items[key+" (cron)"] = fmt.Sprintf("%q", strings.Join(strings.Fields(job.Schedule+" "+job.Command), " "))
+	}
+	for name, timer := range s.ScheduledTasks.SystemdTimers {
+		items[name+" (timer)"] = fmt.Sprintf("enabled=%v, active=%v", timer.Enabled, timer.Active)
+	}
+	return items
+}
+
+func certificateItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.Certificates))
+	for path, cert := range s.Certificates {
+		items[path] = fmt.Sprintf("%s, expires %s", short(cert.Fingerprint), cert.NotAfter.Format("2006-01-02"))
+	}
+	return items
+}
+
+func userItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.UserGroupConfig.Users))
+	for name, u := range s.UserGroupConfig.Users {
+		items[name] = fmt.Sprintf("uid %d, gid %d, %s, %s", u.UID, u.GID, u.HomeDir, u.Shell)
+	}
+	return items
+}
+
+func groupItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.UserGroupConfig.Groups))
+	for name, g := range s.UserGroupConfig.Groups {
+		members := "no members"
+		if len(g.Members) > 0 {
+			members = "members " + strings.Join(sortedKeys(setOf(g.Members)), ",")
+		}
+		items[name] = fmt.Sprintf("gid %d, %s", g.GID, members)
+	}
+	return items
+}
+
+func settingItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string, len(s.Settings))
+	for key, value := range s.Settings {
+		items[key] = value
+	}
+	return items
+}
+
+// networkItems leave out MAC and IP addresses, every host has its own
+func networkItems(s *models.EnvironmentSnapshot) map[string]string {
+	items := make(map[string]string)
+	for name, iface := range s.NetworkConfig.Interfaces {
+		state := "down"
+		if iface.IsUp {
+			state = "up"
+		}
+		items[name+" (interface)"] = fmt.Sprintf("mtu %d, %s", iface.MTU, state)
+	}
+	dns := s.NetworkConfig.DNS
+	if len(dns.Nameservers) > 0 || len(dns.SearchDomains) > 0 {
+		items["dns"] = fmt.Sprintf("nameservers %s, search %s", strings.Join(dns.Nameservers, ","), strings.Join(dns.SearchDomains, ","))
+	}
+	return items
+}
+
+// short cuts a hash down to what a person compares by eye
+func short(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func setOf(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
@@ -0,0 +1,302 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxFileSize is the largest file whose content is kept in memory. Bigger
+// files still show up with their size and mode, but can't be opened; the
+// file collector doesn't hash files that large anyway.
+const maxFileSize = 100 * 1024 * 1024
+
+const maxSymlinks = 40
+
+var (
+	errTooManyLinks = errors.New("too many levels of symbolic links")
+	errNotKept      = errors.New("file content larger than the in-memory limit")
+	errIsDir        = errors.New("is a directory")
+)
+
+// node is one entry of the flattened image filesystem
+type node struct {
+	hdr      *tar.Header
+	data     []byte
+	kept     bool
+	children map[string]*node
+}
+
+func newDir(name string) *node {
+	return &node{
+		hdr: &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name,
+			Mode:     0755,
+			ModTime:  time.Unix(0, 0),
+		},
+		kept:     true,
+		children: make(map[string]*node),
+	}
+}
+
+func (n *node) isDir() bool     { return n.hdr.Typeflag == tar.TypeDir }
+func (n *node) isSymlink() bool { return n.hdr.Typeflag == tar.TypeSymlink }
+
+// FS is the filesystem of an image with all of its layers applied. It
+// satisfies collector.FS: names are absolute paths inside the image.
+type FS struct {
+	root *node
+}
+
+func newFS() *FS {
+	return &FS{root: newDir("/")}
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+	n, err := f.lookup(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if !n.isDir() && !n.kept {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errNotKept}
+	}
+	return &file{info: fileInfo{n}, Reader: bytes.NewReader(n.data)}, nil
+}
+
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	n, err := f.lookup(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{n}, nil
+}
+
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	n, err := f.lookup(name, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return fileInfo{n}, nil
+}
+
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := f.lookup(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{child}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// lookup walks the tree to name, resolving symlinks inside the image
+func (f *FS) lookup(name string, followLast bool) (*node, error) {
+	parts := splitPath(name)
+	stack := []*node{f.root}
+	hops := 0
+
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		cur := stack[len(stack)-1]
+		if part == ".." {
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if !cur.isDir() {
+			return nil, fs.ErrNotExist
+		}
+
+		next, ok := cur.children[part]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+
+		if next.isSymlink() && (len(parts) > 0 || followLast) {
+			hops++
+			if hops > maxSymlinks {
+				return nil, errTooManyLinks
+			}
+			target := next.hdr.Linkname
+			if path.IsAbs(target) {
+				stack = stack[:1]
+			}
+			parts = append(splitPath(target), parts...)
+			continue
+		}
+
+		stack = append(stack, next)
+	}
+
+	return stack[len(stack)-1], nil
+}
+
+// parent returns the directory that should hold name, creating missing
+// directories on the way. A non-directory in the way is replaced, as a later
+// layer turning a file into a directory would.
+func (f *FS) parent(name string) (*node, string) {
+	parts := splitPath(name)
+	if len(parts) == 0 {
+		return nil, ""
+	}
+
+	cur := f.root
+	for i, part := range parts[:len(parts)-1] {
+		next, ok := cur.children[part]
+		if !ok || !next.isDir() {
+			next = newDir("/" + strings.Join(parts[:i+1], "/"))
+			cur.children[part] = next
+		}
+		cur = next
+	}
+	return cur, parts[len(parts)-1]
+}
+
+// apply adds one layer on top of the tree. Whiteout files (.wh.name) delete
+// what lower layers put at name, and an opaque whiteout (.wh..wh..opq)
+// hides everything lower layers put in its directory.
+func (f *FS) apply(r io.Reader) error {
+	tr := tar.NewReader(r)
+	added := make(map[string]bool)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := "/" + strings.Join(splitPath(hdr.Name), "/")
+		dir, base := path.Split(name)
+
+		switch {
+		case base == ".wh..wh..opq":
+			if parent, ok := f.dir(dir); ok {
+				for child := range parent.children {
+					if !added[path.Join(dir, child)] {
+						delete(parent.children, child)
+					}
+				}
+			}
+			continue
+		case strings.HasPrefix(base, ".wh."):
+			if parent, ok := f.dir(dir); ok {
+				delete(parent.children, strings.TrimPrefix(base, ".wh."))
+			}
+			continue
+		}
+
+		parent, base := f.parent(name)
+		if parent == nil {
+			continue // the root directory itself
+		}
+
+		n := &node{hdr: hdr, kept: true}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			// keep what lower layers put in it, only the metadata changes
+			if existing, ok := parent.children[base]; ok && existing.isDir() {
+				existing.hdr = hdr
+				added[name] = true
+				continue
+			}
+			n.children = make(map[string]*node)
+		case tar.TypeReg, tar.TypeRegA:
+			if hdr.Size > maxFileSize {
+				n.kept = false
+				break
+			}
+			if n.data, err = io.ReadAll(tr); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// hard links share the target's content and metadata
+			target, err := f.lookup(hdr.Linkname, false)
+			if err != nil {
+				continue
+			}
+			linked := *target.hdr
+			linked.Name = hdr.Name
+			n = &node{hdr: &linked, data: target.data, kept: target.kept}
+		case tar.TypeSymlink:
+		default:
+			// devices, fifos and the like have no content worth reading
+			n.kept = false
+		}
+
+		parent.children[base] = n
+		added[name] = true
+	}
+}
+
+func (f *FS) dir(name string) (*node, bool) {
+	n, err := f.lookup(name, false)
+	if err != nil || !n.isDir() {
+		return nil, false
+	}
+	return n, true
+}
+
+func splitPath(name string) []string {
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// fileInfo exposes a node as fs.FileInfo; Sys returns the *tar.Header so
+// callers can get at the owner uid and gid
+type fileInfo struct {
+	n *node
+}
+
+func (fi fileInfo) Name() string {
+	name := path.Base("/" + strings.Join(splitPath(fi.n.hdr.Name), "/"))
+	if name == "/" || name == "." {
+		return "/"
+	}
+	return name
+}
+
+func (fi fileInfo) Size() int64        { return fi.n.hdr.Size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.n.hdr.FileInfo().Mode() }
+func (fi fileInfo) ModTime() time.Time { return fi.n.hdr.ModTime }
+func (fi fileInfo) IsDir() bool        { return fi.n.isDir() }
+func (fi fileInfo) Sys() interface{}   { return fi.n.hdr }
+
+type file struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: errIsDir}
+	}
+	return f.Reader.Read(p)
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
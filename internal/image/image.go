@@ -0,0 +1,283 @@
+// Package image reads container images saved to disk, either as an OCI image
+// layout or a `docker save` archive, and flattens their layers into a single
+// in-memory filesystem the collectors can walk.
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// Image is a loaded image with its layers applied
+type Image struct {
+	FS           *FS
+	Source       string
+	Tags         []string
+	ID           string
+	OS           string
+	Architecture string
+	Created      time.Time
+}
+
+// Load reads an image from a tar archive or an unpacked OCI layout directory
+func Load(source string) (*Image, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var arc archive = tarArchive{path: source}
+	if info.IsDir() {
+		arc = dirArchive{dir: source}
+	}
+
+	img := &Image{FS: newFS(), Source: source}
+
+	var configName string
+	var layers []string
+
+	// docker save writes manifest.json (newer versions next to an OCI
+	// layout); anything else has to be a plain OCI layout
+	if data, err := readMember(arc, "manifest.json"); err == nil {
+		var manifest []struct {
+			Config   string
+			RepoTags []string
+			Layers   []string
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("reading manifest.json: %w", err)
+		}
+		if len(manifest) == 0 {
+			return nil, fmt.Errorf("manifest.json lists no images")
+		}
+		configName, layers, img.Tags = manifest[0].Config, manifest[0].Layers, manifest[0].RepoTags
+	} else {
+		configName, layers, img.Tags, err = readOCILayout(arc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := readMember(arc, configName)
+	if err != nil {
+		return nil, fmt.Errorf("reading image config: %w", err)
+	}
+	var imageConfig struct {
+		OS           string    `json:"os"`
+		Architecture string    `json:"architecture"`
+		Created      time.Time `json:"created"`
+	}
+	if err := json.Unmarshal(config, &imageConfig); err != nil {
+		return nil, fmt.Errorf("reading image config: %w", err)
+	}
+	img.ID = fmt.Sprintf("sha256:%x", sha256.Sum256(config))
+	img.OS = imageConfig.OS
+	img.Architecture = imageConfig.Architecture
+	img.Created = imageConfig.Created
+
+	for _, layer := range layers {
+		if err := applyLayer(img.FS, arc, layer); err != nil {
+			return nil, fmt.Errorf("applying layer %s: %w", layer, err)
+		}
+	}
+
+	return img, nil
+}
+
+// Annotate records where a snapshot collected from the image came from.
+// Images rarely have /etc/hostname, so the first tag stands in for it.
+func (img *Image) Annotate(snapshot *models.EnvironmentSnapshot) {
+	if img.OS != "" {
+		snapshot.OS.Name = img.OS
+	}
+	if img.Architecture != "" {
+		snapshot.OS.Arch = img.Architecture
+	}
+	if snapshot.Hostname == "" {
+		snapshot.Hostname = img.ID
+		if len(img.Tags) > 0 {
+			snapshot.Hostname = img.Tags[0]
+		}
+	}
+
+	if snapshot.Metadata == nil {
+		snapshot.Metadata = make(map[string]string)
+	}
+	snapshot.Metadata["image_id"] = img.ID
+	if len(img.Tags) > 0 {
+		snapshot.Metadata["image_tags"] = strings.Join(img.Tags, ",")
+	}
+	if !img.Created.IsZero() {
+		snapshot.Metadata["image_created"] = img.Created.UTC().Format(time.RFC3339)
+	}
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// readOCILayout follows index.json down to a single image manifest, picking
+// the current platform from multi-platform indexes when there is a choice
+func readOCILayout(arc archive) (config string, layers []string, tags []string, err error) {
+	data, err := readMember(arc, "index.json")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("neither manifest.json nor index.json found, not a docker-save or OCI image")
+	}
+
+	for depth := 0; depth < 8; depth++ {
+		var doc struct {
+			MediaType string       `json:"mediaType"`
+			Manifests []descriptor `json:"manifests"`
+			Config    descriptor   `json:"config"`
+			Layers    []descriptor `json:"layers"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", nil, nil, err
+		}
+
+		if doc.Config.Digest != "" {
+			for _, layer := range doc.Layers {
+				layers = append(layers, blobPath(layer.Digest))
+			}
+			return blobPath(doc.Config.Digest), layers, tags, nil
+		}
+
+		if len(doc.Manifests) == 0 {
+			return "", nil, nil, fmt.Errorf("image index lists no manifests")
+		}
+		chosen := pickManifest(doc.Manifests)
+		if ref := chosen.Annotations["org.opencontainers.image.ref.name"]; ref != "" && len(tags) == 0 {
+			tags = []string{ref}
+		}
+		if data, err = readMember(arc, blobPath(chosen.Digest)); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	return "", nil, nil, fmt.Errorf("image index nested too deeply")
+}
+
+func pickManifest(manifests []descriptor) descriptor {
+	for _, m := range manifests {
+		if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+			return m
+		}
+	}
+	for _, m := range manifests {
+		// attestation manifests in buildx indexes have an unknown platform
+		if m.Platform == nil || m.Platform.OS != "unknown" {
+			return m
+		}
+	}
+	return manifests[0]
+}
+
+func blobPath(digest string) string {
+	algo, hex, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algo, hex)
+}
+
+// applyLayer decompresses a layer if needed and applies it to fsys
+func applyLayer(fsys *FS, arc archive, name string) error {
+	rc, err := arc.open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(rc)
+	magic, _ := br.Peek(4)
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return fmt.Errorf("zstd compressed layers are not supported")
+	}
+
+	return fsys.apply(r)
+}
+
+// archive gives access to the members of a saved image by name
+type archive interface {
+	open(name string) (io.ReadCloser, error)
+}
+
+func readMember(arc archive, name string) ([]byte, error) {
+	rc, err := arc.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+type dirArchive struct {
+	dir string
+}
+
+func (a dirArchive) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(a.dir, filepath.FromSlash(path.Clean("/"+name))))
+}
+
+// tarArchive scans the archive from the start for every member. Skipping
+// over members seeks instead of reading, so this stays cheap even for large
+// images.
+type tarArchive struct {
+	path string
+}
+
+func (a tarArchive) open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	want := path.Clean("/" + name)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if path.Clean("/"+hdr.Name) == want {
+			return member{Reader: tr, Closer: f}, nil
+		}
+	}
+}
+
+type member struct {
+	io.Reader
+	io.Closer
+}
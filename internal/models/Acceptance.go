@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Acceptance records a drift someone reviewed and declared expected. It
+// covers the drift only while the target value stays the one accepted.
+type Acceptance struct {
+	Category   string    `json:"category" yaml:"category"`
+	Name       string    `json:"name" yaml:"name"`
+	Type       string    `json:"type" yaml:"type"`
+	Value      string    `json:"value,omitempty" yaml:"value,omitempty"` // digest of the accepted target value
+	Reason     string    `json:"reason" yaml:"reason"`
+	AcceptedBy string    `json:"accepted_by,omitempty" yaml:"accepted_by,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at" yaml:"accepted_at"`
+	Expires    time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
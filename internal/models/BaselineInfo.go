@@ -0,0 +1,15 @@
+package models
+
+// BaselineInfo records how a baseline snapshot was built from many hosts
+type BaselineInfo struct {
+	Strategy string      `json:"strategy" yaml:"strategy"` // majority or intersection
+	Hosts    []string    `json:"hosts" yaml:"hosts"`
+	Exclude  []Exclusion `json:"exclude,omitempty" yaml:"exclude,omitempty"` // host-specific items, comparisons against the baseline skip them too
+}
+
+// Exclusion names items to leave out. Name is a pattern as in rules; an
+// empty category matches every category.
+type Exclusion struct {
+	Category string `json:"category,omitempty" yaml:"category,omitempty"`
+	Name     string `json:"name" yaml:"name"`
+}
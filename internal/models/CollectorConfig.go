@@ -1,99 +1,154 @@
 package models
 
+import "time"
+
 // Collector config defines what to collect
 
 type CollectorConfig struct {
-	Files           FileCollectorConfig            `yaml:"files"`
-	EnvVars         EnvVarCollectorConfig          `yaml:"env_vars"`
-	ProcessEnvVars  ProcessEnvVarCollectorConfig   `yaml:"process_env_vars"`
-	Packages        PackageCollectorConfig         `yaml:"packages"`
-	Services        ServiceCollectorConfig         `yaml:"services"`
-	Network         NetworkCollectorConfig         `yaml:"network"`
-	Docker          DockerCollectorConfig          `yaml:"docker"`
-	SystemResources SystemResourcesCollectorConfig `yaml:"system_resources"`
-	ScheduledTasks  ScheduledTasksCollectorConfig  `yaml:"scheduled_tasks"`
-	Certificates    CertificateCollectorConfig     `yaml:"certificates"`
-	UsersGroups     UserGroupCollectorConfig       `yaml:"users_groups"`
+	Files            FileCollectorConfig             `yaml:"files"`
+	EnvVars          EnvVarCollectorConfig           `yaml:"env_vars"`
+	ProcessEnvVars   ProcessEnvVarCollectorConfig    `yaml:"process_env_vars"`
+	Packages         PackageCollectorConfig          `yaml:"packages"`
+	PackageIntegrity PackageIntegrityCollectorConfig `yaml:"package_integrity"`
+	Services         ServiceCollectorConfig          `yaml:"services"`
+	Network          NetworkCollectorConfig          `yaml:"network"`
+	Docker           DockerCollectorConfig           `yaml:"docker"`
+	SystemResources  SystemResourcesCollectorConfig  `yaml:"system_resources"`
+	ScheduledTasks   ScheduledTasksCollectorConfig   `yaml:"scheduled_tasks"`
+	Certificates     CertificateCollectorConfig      `yaml:"certificates"`
+	UsersGroups      UserGroupCollectorConfig        `yaml:"users_groups"`
+	Settings         SettingsCollectorConfig         `yaml:"settings"`
+
+	// Time budget for collectors that don't set their own timeout
+	DefaultTimeout time.Duration `yaml:"default_timeout"`
+
+	// Settings for third-party collectors, keyed by collector name
+	Extensions map[string]map[string]interface{} `yaml:"extensions"`
+
+	// Collect from a filesystem tree mounted at this path (a VM disk, chroot
+	// or extracted container) instead of the running host
+	Root string `yaml:"root"`
 }
 
 type FileCollectorConfig struct {
-	Enabled      bool     `yaml:"enabled"`
-	Paths        []string `yaml:"paths"`
-	ExcludePaths []string `yaml:"exclude_paths"`
-	FollowLinks  bool     `yaml:"follow_links"`
-	MaxDepth     int      `yaml:"max_depth"`
-	HashAlgo     string   `yaml:"hash_algo"` // md5 sha256
+	Enabled      bool          `yaml:"enabled"`
+	Timeout      time.Duration `yaml:"timeout"` // per-collector time budget, e.g. 30s
+	Paths        []string      `yaml:"paths"`
+	ExcludePaths []string      `yaml:"exclude_paths"`
+	FollowLinks  bool          `yaml:"follow_links"`
+	MaxDepth     int           `yaml:"max_depth"`
+	HashAlgo     string        `yaml:"hash_algo"` // md5 sha256
+
+	// Keep the text of small files so a change shows as a diff
+	CaptureContent ContentCaptureConfig `yaml:"capture_content"`
 }
 
-type EnvVarCollectorConfig struct {
+type ContentCaptureConfig struct {
 	Enabled     bool     `yaml:"enabled"`
-	Include     []string `yaml:"include"`      // regex patterns
-	Exclude     []string `yaml:"exclude"`      // regex patterns
-	MaskSecrets bool     `yaml:"mask_secrets"` // mask sensitive values
+	Paths       []string `yaml:"paths"`        // patterns as in rules, e.g. /etc/nginx/**.conf
+	MaxSize     int64    `yaml:"max_size"`     // in bytes, 64 KiB if not set
+	MaskSecrets bool     `yaml:"mask_secrets"` // mask the values of password, token and key settings
+}
+
+type EnvVarCollectorConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Include     []string      `yaml:"include"`      // regex patterns
+	Exclude     []string      `yaml:"exclude"`      // regex patterns
+	MaskSecrets bool          `yaml:"mask_secrets"` // mask sensitive values
 }
 
 type PackageCollectorConfig struct {
-	Enabled  bool     `yaml:"enabled"`
-	Managers []string `yaml:"managers"` // apt ,yum, go...
+	Enabled  bool          `yaml:"enabled"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Managers []string      `yaml:"managers"` // apt ,yum, go...
+
+	// Read the package managers' history logs so package drift can show
+	// the transaction behind it
+	History     bool `yaml:"history"`
+	HistoryDays int  `yaml:"history_days"` // how far back to read, 0 for everything
+}
+
+type PackageIntegrityCollectorConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Timeout      time.Duration `yaml:"timeout"`
+	Managers     []string      `yaml:"managers"`      // dpkg, rpm
+	Paths        []string      `yaml:"paths"`         // only verify files under these directories; all when empty
+	ExcludePaths []string      `yaml:"exclude_paths"` // regex patterns
 }
 
 type ServiceCollectorConfig struct {
-	Enabled  bool     `yaml:"enabled"`
-	Include  []string `yaml:"include"`
-	Exclude  []string `yaml:"exclude"`
-	InitType string   `yaml:"init_type"` // systemd, sysvinit, openrc
+	Enabled  bool          `yaml:"enabled"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Include  []string      `yaml:"include"`
+	Exclude  []string      `yaml:"exclude"`
+	InitType string        `yaml:"init_type"` // systemd, sysvinit, openrc
 }
 
 type ProcessEnvVarCollectorConfig struct {
-	Enabled      bool     `yaml:"enabled"`
-	Processes    []string `yaml:"processes"`     // process names to collect env vars for (e.g., "node", "php", "python")
-	MaxProcesses int      `yaml:"max_processes"` // max number of processes to collect env vars from
-	MaskSecrets  bool     `yaml:"mask_secrets"`  // mask sensitive values
-	Exclude      []string `yaml:"exclude"`       // regex patterns to exclude specific env vars
+	Enabled      bool          `yaml:"enabled"`
+	Timeout      time.Duration `yaml:"timeout"`
+	Processes    []string      `yaml:"processes"`     // process names to collect env vars for (e.g., "node", "php", "python")
+	MaxProcesses int           `yaml:"max_processes"` // max number of processes to collect env vars from
+	MaskSecrets  bool          `yaml:"mask_secrets"`  // mask sensitive values
+	Exclude      []string      `yaml:"exclude"`       // regex patterns to exclude specific env vars
 }
 
 type NetworkCollectorConfig struct {
-	Enabled       bool `yaml:"enabled"`
-	Interfaces    bool `yaml:"interfaces"`
-	Routes        bool `yaml:"routes"`
-	DNS           bool `yaml:"dns"`
-	FirewallRules bool `yaml:"firewall_rules"`
+	Enabled       bool          `yaml:"enabled"`
+	Timeout       time.Duration `yaml:"timeout"`
+	Interfaces    bool          `yaml:"interfaces"`
+	Routes        bool          `yaml:"routes"`
+	DNS           bool          `yaml:"dns"`
+	FirewallRules bool          `yaml:"firewall_rules"`
 }
 
 type DockerCollectorConfig struct {
-	Enabled    bool   `yaml:"enabled"`
-	Containers bool   `yaml:"containers"`
-	Images     bool   `yaml:"images"`
-	Volumes    bool   `yaml:"volumes"`
-	Networks   bool   `yaml:"networks"`
-	SocketPath string `yaml:"socket_path"` // e.g., /var/run/docker.sock
+	Enabled    bool          `yaml:"enabled"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Containers bool          `yaml:"containers"`
+	Images     bool          `yaml:"images"`
+	Volumes    bool          `yaml:"volumes"`
+	Networks   bool          `yaml:"networks"`
+	SocketPath string        `yaml:"socket_path"` // e.g., /var/run/docker.sock
 }
 
 type SystemResourcesCollectorConfig struct {
-	Enabled bool `yaml:"enabled"`
-	CPU     bool `yaml:"cpu"`
-	Memory  bool `yaml:"memory"`
-	Disks   bool `yaml:"disks"`
-	Load    bool `yaml:"load"`
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"`
+	CPU     bool          `yaml:"cpu"`
+	Memory  bool          `yaml:"memory"`
+	Disks   bool          `yaml:"disks"`
+	Load    bool          `yaml:"load"`
 }
 
 type ScheduledTasksCollectorConfig struct {
-	Enabled       bool `yaml:"enabled"`
-	CronJobs      bool `yaml:"cron_jobs"`
-	SystemdTimers bool `yaml:"systemd_timers"`
-	LaunchdJobs   bool `yaml:"launchd_jobs"`
+	Enabled       bool          `yaml:"enabled"`
+	Timeout       time.Duration `yaml:"timeout"`
+	CronJobs      bool          `yaml:"cron_jobs"`
+	SystemdTimers bool          `yaml:"systemd_timers"`
+	LaunchdJobs   bool          `yaml:"launchd_jobs"`
 }
 
 type CertificateCollectorConfig struct {
-	Enabled       bool     `yaml:"enabled"`
-	Paths         []string `yaml:"paths"`          // paths to scan for certificates
-	Extensions    []string `yaml:"extensions"`     // .pem, .crt, .cer, .key
-	DaysThreshold int      `yaml:"days_threshold"` // alert if expiring within X days
+	Enabled       bool          `yaml:"enabled"`
+	Timeout       time.Duration `yaml:"timeout"`
+	Paths         []string      `yaml:"paths"`          // paths to scan for certificates
+	Extensions    []string      `yaml:"extensions"`     // .pem, .crt, .cer, .key
+	DaysThreshold int           `yaml:"days_threshold"` // alert if expiring within X days
 }
 
 type UserGroupCollectorConfig struct {
-	Enabled   bool `yaml:"enabled"`
-	Users     bool `yaml:"users"`
-	Groups    bool `yaml:"groups"`
-	SudoRules bool `yaml:"sudo_rules"`
+	Enabled   bool          `yaml:"enabled"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Users     bool          `yaml:"users"`
+	Groups    bool          `yaml:"groups"`
+	SudoRules bool          `yaml:"sudo_rules"`
+}
+
+type SettingsCollectorConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"`
+	Sysctl  []string      `yaml:"sysctl"` // kernel settings to read, e.g. net.ipv4.ip_forward; a hardening set when empty
+	SSHD    bool          `yaml:"sshd"`   // the global settings of /etc/ssh/sshd_config
 }
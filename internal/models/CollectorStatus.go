@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CollectorStatus records how a single collector run went, so consumers can
+// tell missing data apart from data that could not be collected
+
+type CollectorStatus struct {
+	Name        string    `json:"name" yaml:"name"`
+	Status      string    `json:"status" yaml:"status"` // ok, partial, failed, skipped
+	Message     string    `json:"message,omitempty" yaml:"message,omitempty"`
+	StartedAt   time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	DurationMs  int64     `json:"duration_ms" yaml:"duration_ms"`
+	ItemCount   int       `json:"item_count" yaml:"item_count"`
+	TimedOut    bool      `json:"timed_out,omitempty" yaml:"timed_out,omitempty"`
+	Errors      []string  `json:"errors,omitempty" yaml:"errors,omitempty"`
+	FailedParts []string  `json:"failed_parts,omitempty" yaml:"failed_parts,omitempty"` // sources that could not be read, e.g. "dpkg" for packages
+}
@@ -1,13 +1,28 @@
 package models
 
 // Represents a single drift detection
+//
+// Changes lists the attributes that differ and is only set for modified and
+// moved items. Added and removed items carry the whole item in SourceVal or
+// TargetVal instead, so rules on a field don't fire for a file that merely
+// appeared. Package files are the exception: their problems ("checksum",
+// "mode") are listed whatever the type.
 
 type DriftItem struct {
-	Type      string      `json:"type" yaml:"type"`         // added , removed modified
-	Category  string      `json:"category" yaml:"category"` // file, environment variables (envvar), packages , services
-	Name      string      `json:"name" yaml:"name"`
-	SourceVal interface{} `json:"source_value,omitempty" yaml:"source_value,omitempty"`
-	TargetVal interface{} `json:"target_value,omitempty" yaml:"target_value,omitempty"`
-	Severity  string      `json:"severity" yaml:"severity"` // critical , warning , infromation
-	Message   string      `json:"message" yaml:"message"`
+	ID          string              `json:"id" yaml:"id"`             // fingerprint, the same for the same change in any report
+	Type        string              `json:"type" yaml:"type"`         // added , removed modified, moved for files
+	Category    string              `json:"category" yaml:"category"` // file, environment variables (envvar), packages , services
+	Name        string              `json:"name" yaml:"name"`
+	SourceVal   interface{}         `json:"source_value,omitempty" yaml:"source_value,omitempty"`
+	TargetVal   interface{}         `json:"target_value,omitempty" yaml:"target_value,omitempty"`
+	Changes     []FieldChange       `json:"changes,omitempty" yaml:"changes,omitempty"`               // the attributes that differ, see above
+	Diff        string              `json:"diff,omitempty" yaml:"diff,omitempty"`                     // unified diff of a file whose content was captured on both sides
+	Version     *VersionChange      `json:"version_change,omitempty" yaml:"version_change,omitempty"` // upgrade or downgrade of a package, and by how much
+	Severity    string              `json:"severity" yaml:"severity"`                                 // critical , warning , infromation
+	Message     string              `json:"message" yaml:"message"`
+	Tags        []string            `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Owner       string              `json:"owner,omitempty" yaml:"owner,omitempty"`             // team or person a matching rule routes the drift to
+	CausedBy    string              `json:"caused_by,omitempty" yaml:"caused_by,omitempty"`     // name of the package drift that explains this one
+	Transaction *PackageTransaction `json:"transaction,omitempty" yaml:"transaction,omitempty"` // package manager run that made a package change
+	Accepted    *Acceptance         `json:"accepted,omitempty" yaml:"accepted,omitempty"`       // set when the drift is expected and not counted as active
 }
@@ -13,4 +13,5 @@ type DriftReport struct {
 	HasDrift       bool         `json:"has_drift" yaml:"has_drift"`
 	Summary        DriftSummary `json:"summary" yaml:"summary"`
 	Drifts         []DriftItem  `json:"drifts" yaml:"drifts"`
+	Notes          []string     `json:"notes,omitempty" yaml:"notes,omitempty"` // parts of the snapshots that could not be compared
 }
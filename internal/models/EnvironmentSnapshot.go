@@ -1,24 +1,59 @@
 // Environment snapshot will represen the complete environment state
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type EnvironmentSnapshot struct {
-	ID              string                 `json:"id" yaml:"id"`
-	Name            string                 `json:"name" yaml:"name"`
-	Hostname        string                 `json:"hostname" yaml:"hostname"`
-	Timestamp       time.Time              `json:"timestamp" yaml:"timestamp"`
-	OS              OSInfo                 `json:"os" yaml:"os"`
-	Files           map[string]FileInfo    `json:"files" yaml:"files"`
-	EnvVars         map[string]EnvVar      `json:"env_vars" yaml:"env_vars"`
-	ProcessEnvVars  map[int]ProcessEnvVar  `json:"process_env_vars,omitempty" yaml:"process_env_vars,omitempty"`
-	Packages        map[string]PackageInfo `json:"packages" yaml:"packages"`
-	Services        map[string]ServiceInfo `json:"services" yaml:"services"`
-	NetworkConfig   NetworkConfig          `json:"network_config,omitempty" yaml:"network_config,omitempty"`
-	DockerConfig    DockerConfig           `json:"docker_config,omitempty" yaml:"docker_config,omitempty"`
-	SystemResources SystemResources        `json:"system_resources,omitempty" yaml:"system_resources,omitempty"`
-	ScheduledTasks  ScheduledTasks         `json:"scheduled_tasks,omitempty" yaml:"scheduled_tasks,omitempty"`
-	Certificates    map[string]Certificate `json:"certificates,omitempty" yaml:"certificates,omitempty"`
-	UserGroupConfig UserGroupConfig        `json:"user_group_config,omitempty" yaml:"user_group_config,omitempty"`
-	Metadata        map[string]string      `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	ID               string                     `json:"id" yaml:"id"`
+	Name             string                     `json:"name" yaml:"name"`
+	Hostname         string                     `json:"hostname" yaml:"hostname"`
+	Timestamp        time.Time                  `json:"timestamp" yaml:"timestamp"`
+	Labels           map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty"`
+	OS               OSInfo                     `json:"os" yaml:"os"`
+	Files            map[string]FileInfo        `json:"files" yaml:"files"`
+	EnvVars          map[string]EnvVar          `json:"env_vars" yaml:"env_vars"`
+	ProcessEnvVars   map[int]ProcessEnvVar      `json:"process_env_vars,omitempty" yaml:"process_env_vars,omitempty"`
+	Packages         map[string]PackageInfo     `json:"packages" yaml:"packages"`
+	PackageIntegrity PackageIntegrity           `json:"package_integrity,omitempty" yaml:"package_integrity,omitempty"`
+	PackageHistory   []PackageTransaction       `json:"package_history,omitempty" yaml:"package_history,omitempty"`
+	Services         map[string]ServiceInfo     `json:"services" yaml:"services"`
+	NetworkConfig    NetworkConfig              `json:"network_config,omitempty" yaml:"network_config,omitempty"`
+	DockerConfig     DockerConfig               `json:"docker_config,omitempty" yaml:"docker_config,omitempty"`
+	SystemResources  SystemResources            `json:"system_resources,omitempty" yaml:"system_resources,omitempty"`
+	ScheduledTasks   ScheduledTasks             `json:"scheduled_tasks,omitempty" yaml:"scheduled_tasks,omitempty"`
+	Certificates     map[string]Certificate     `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	UserGroupConfig  UserGroupConfig            `json:"user_group_config,omitempty" yaml:"user_group_config,omitempty"`
+	Settings         map[string]string          `json:"settings,omitempty" yaml:"settings,omitempty"` // sysctl and sshd settings, "sysctl:net.ipv4.ip_forward"
+	Metadata         map[string]string          `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Baseline         *BaselineInfo              `json:"baseline,omitempty" yaml:"baseline,omitempty"`     // set on snapshots built by "drift baseline build"
+	Extensions       map[string]interface{}     `json:"extensions,omitempty" yaml:"extensions,omitempty"` // results of third-party collectors, keyed by collector name
+	Collectors       map[string]CollectorStatus `json:"collectors,omitempty" yaml:"collectors,omitempty"`
+}
+
+// SetExtension stores a third-party collector result under name
+func (s *EnvironmentSnapshot) SetExtension(name string, value interface{}) {
+	if s.Extensions == nil {
+		s.Extensions = make(map[string]interface{})
+	}
+	s.Extensions[name] = value
+}
+
+// Extension decodes the section stored under name into v. The value is
+// re-encoded through JSON, so this works the same for freshly collected
+// snapshots and ones loaded back from JSON or YAML files. It reports false
+// if the snapshot has no such section.
+func (s *EnvironmentSnapshot) Extension(name string, v interface{}) (bool, error) {
+	raw, ok := s.Extensions[name]
+	if !ok {
+		return false, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return true, err
+	}
+	return true, json.Unmarshal(data, v)
 }
@@ -0,0 +1,14 @@
+package models
+
+// FieldChange is one attribute of an item that differs between source and
+// target, such as a file's mode or a service's status
+type FieldChange struct {
+	Field string      `json:"field" yaml:"field"`
+	Old   interface{} `json:"old" yaml:"old"`
+	New   interface{} `json:"new" yaml:"new"`
+}
+
+// KeyPrefix starts the field of a setting inside a config file,
+// "key:server.port", so a setting named "mode" isn't taken for the file's
+// permissions
+const KeyPrefix = "key:"
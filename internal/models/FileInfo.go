@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"time"
+)
 
 // FileInfo will represent the file metadata
 
@@ -14,4 +20,44 @@ type FileInfo struct {
 	Group       string    `json:"group" yaml:"group"`
 	IsDirectory bool      `json:"is_directory" yaml:"is_directory"`
 	Exists      bool      `json:"exists" yaml:"exists"`
+	Package     string    `json:"package,omitempty" yaml:"package,omitempty"` // package that installed the file, e.g. "dpkg:openssl"
+
+	// Content is the text of a file matched by capture_content, gzipped and
+	// base64-encoded. Read it with Text.
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	Masked  bool   `json:"masked,omitempty" yaml:"masked,omitempty"` // secrets in Content were masked, so it isn't all of the file
+}
+
+// CompressContent packs text the way FileInfo.Content holds it
+func CompressContent(text string) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, text); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Text unpacks the captured content. It reports false if none was captured.
+func (f FileInfo) Text() (string, bool, error) {
+	if f.Content == "" {
+		return "", false, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(f.Content)
+	if err != nil {
+		return "", true, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", true, err
+	}
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return "", true, err
+	}
+	return string(text), true, nil
 }
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// FleetReport compares the snapshots of hosts that are meant to be alike
+// and lists the items they disagree on
+type FleetReport struct {
+	ID         string          `json:"id" yaml:"id"`
+	Timestamp  time.Time       `json:"timestamp" yaml:"timestamp"`
+	Hosts      []FleetHost     `json:"hosts" yaml:"hosts"`
+	Categories []FleetCategory `json:"categories" yaml:"categories"`
+	Items      []FleetItem     `json:"items" yaml:"items"` // only the items the hosts disagree on
+	Notes      []string        `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// FleetHost is one snapshot of the fleet
+type FleetHost struct {
+	Name     string `json:"name" yaml:"name"`
+	Snapshot string `json:"snapshot" yaml:"snapshot"`
+	Outliers int    `json:"outliers" yaml:"outliers"` // items where the host is in a minority
+}
+
+// FleetCategory scores how alike the hosts are in one category
+type FleetCategory struct {
+	Category   string  `json:"category" yaml:"category"`
+	Items      int     `json:"items" yaml:"items"`
+	Consistent int     `json:"consistent" yaml:"consistent"` // items the same on every host
+	Score      float64 `json:"score" yaml:"score"`           // percentage of consistent items
+}
+
+// FleetItem is an item the hosts disagree on, with the hosts grouped by the
+// value they have. The first group is the majority, if there is one.
+type FleetItem struct {
+	Category string         `json:"category" yaml:"category"`
+	Name     string         `json:"name" yaml:"name"`
+	Majority bool           `json:"majority" yaml:"majority"` // the first group holds more hosts than any other
+	Values   []FleetVariant `json:"values" yaml:"values"`
+	Message  string         `json:"message" yaml:"message"`
+}
+
+// FleetVariant is one value of an item and the hosts that have it
+type FleetVariant struct {
+	Value   string   `json:"value,omitempty" yaml:"value,omitempty"`
+	Missing bool     `json:"missing,omitempty" yaml:"missing,omitempty"` // the hosts don't have the item at all
+	Hosts   []string `json:"hosts" yaml:"hosts"`
+}
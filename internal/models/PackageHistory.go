@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PackageTransaction is one package manager run, as recorded in its history
+// logs (apt, dpkg, dnf, yum)
+
+type PackageTransaction struct {
+	ID      string          `json:"id" yaml:"id"` // e.g. "dnf:42" or "apt:2024-03-01T10:15:02Z"
+	Manager string          `json:"manager" yaml:"manager"`
+	Start   time.Time       `json:"start" yaml:"start"`
+	End     time.Time       `json:"end,omitempty" yaml:"end,omitempty"`
+	Command string          `json:"command,omitempty" yaml:"command,omitempty"`
+	User    string          `json:"user,omitempty" yaml:"user,omitempty"`
+	Changes []PackageChange `json:"changes,omitempty" yaml:"changes,omitempty"` // empty when the manager only records that something changed
+}
+
+type PackageChange struct {
+	Package string `json:"package" yaml:"package"` // package key, e.g. "dpkg:openssl"
+	Action  string `json:"action" yaml:"action"`   // install, upgrade, downgrade, reinstall, remove
+	From    string `json:"from,omitempty" yaml:"from,omitempty"`
+	To      string `json:"to,omitempty" yaml:"to,omitempty"`
+}
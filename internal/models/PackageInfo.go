@@ -3,9 +3,13 @@
 package models
 
 type PackageInfo struct {
-	Name         string `json:"name" yaml:"name"`
-	Version      string `json:"version" yaml:"version"`
-	Architecture string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
-	Manager      string `json:"manager" yaml:"manager"` // Represens the package manager like yum , apt etc.
-	Exists       bool   `json:"exists" yaml:"exists"`
+	Name          string `json:"name" yaml:"name"`
+	Version       string `json:"version" yaml:"version"`
+	Architecture  string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Manager       string `json:"manager" yaml:"manager"` // Represens the package manager like yum , apt etc.
+	Exists        bool   `json:"exists" yaml:"exists"`
+	Source        string `json:"source,omitempty" yaml:"source,omitempty"`                 // source package it was built from
+	Status        string `json:"status,omitempty" yaml:"status,omitempty"`                 // e.g. dpkg's "install ok installed"
+	InstalledSize int64  `json:"installed_size,omitempty" yaml:"installed_size,omitempty"` // in bytes
+	Maintainer    string `json:"maintainer,omitempty" yaml:"maintainer,omitempty"`
 }
@@ -0,0 +1,24 @@
+package models
+
+// PackageIntegrity is the result of checking package-owned files against what
+// their package manager recorded at install time
+
+type PackageIntegrity struct {
+	Checked map[string]int              `json:"checked" yaml:"checked"`                   // files verified, by package manager
+	Issues  map[string]PackageFileIssue `json:"issues,omitempty" yaml:"issues,omitempty"` // keyed by path
+}
+
+// PackageFileIssue is a package-owned file that no longer matches its package
+type PackageFileIssue struct {
+	Path          string   `json:"path" yaml:"path"`
+	Package       string   `json:"package" yaml:"package"` // package key, e.g. "dpkg:coreutils"
+	Version       string   `json:"version" yaml:"version"` // package version the file was checked against
+	Config        bool     `json:"config,omitempty" yaml:"config,omitempty"`
+	Problems      []string `json:"problems" yaml:"problems"` // missing, checksum, mode, owner, group
+	ExpectedHash  string   `json:"expected_hash,omitempty" yaml:"expected_hash,omitempty"`
+	ActualHash    string   `json:"actual_hash,omitempty" yaml:"actual_hash,omitempty"`
+	ExpectedMode  string   `json:"expected_mode,omitempty" yaml:"expected_mode,omitempty"`
+	ActualMode    string   `json:"actual_mode,omitempty" yaml:"actual_mode,omitempty"`
+	ExpectedOwner string   `json:"expected_owner,omitempty" yaml:"expected_owner,omitempty"` // user:group
+	ActualOwner   string   `json:"actual_owner,omitempty" yaml:"actual_owner,omitempty"`
+}
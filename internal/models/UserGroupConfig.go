@@ -7,6 +7,10 @@ type UserInfo struct {
 	HomeDir string `json:"home_dir" yaml:"home_dir"`
 	Shell   string `json:"shell" yaml:"shell"`
 	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+
+	// Password is "set", "empty" or "locked" as /etc/shadow has it, never
+	// the hash itself. Left out when the shadow file can't be read.
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
 }
 
 type GroupInfo struct {
@@ -0,0 +1,8 @@
+package models
+
+// VersionChange is what kind of change a package version drift is, going by
+// the version ordering of the package's manager
+type VersionChange struct {
+	Direction string `json:"direction" yaml:"direction"`           // upgrade, downgrade
+	Bump      string `json:"bump,omitempty" yaml:"bump,omitempty"` // major, minor, patch, or rebuild when only the packaging changed
+}
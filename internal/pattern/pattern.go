@@ -0,0 +1,49 @@
+// Package pattern compiles the name patterns used across the configuration:
+// in rules, policies, exclusions, normalizations and content capture.
+package pattern
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Compile turns a pattern into a matcher. A pattern is a glob
+// ("/etc/postgresql/*/postgresql.conf", "**" crosses directories), a path
+// prefix ending in "/", a regular expression prefixed with "re:" or, without
+// any of those, a plain name.
+func Compile(pattern string) (func(string) bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		return func(s string) bool { return strings.HasPrefix(s, pattern) }, nil
+	}
+
+	if !strings.ContainsAny(pattern, "*?") {
+		return func(s string) bool { return s == pattern }, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString, nil
+}
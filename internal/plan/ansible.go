@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Play is an Ansible play
+type Play struct {
+	Name   string `yaml:"name"`
+	Hosts  string `yaml:"hosts"`
+	Become bool   `yaml:"become"`
+	Tasks  []Task `yaml:"tasks"`
+}
+
+// Task is an Ansible task: its name followed by the module and arguments
+type Task struct {
+	Name   string                 `yaml:"name"`
+	Module map[string]interface{} `yaml:",inline"`
+}
+
+// Ansible writes the plan as a playbook for the target's hosts
+func (p *Plan) Ansible(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Reverts %s toward %s, from drift report %s\n", OneLine(p.Report.TargetEnv), OneLine(p.Report.SourceEnv), OneLine(p.Report.ID)))
+	sb.WriteString(fmt.Sprintf("# Generated by drift remediate on %s\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	writeManual(&sb, p.Manual, "# ")
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return err
+	}
+
+	pl := Play{
+		Name:   fmt.Sprintf("Revert %s toward %s", OneLine(p.Report.TargetEnv), OneLine(p.Report.SourceEnv)),
+		Hosts:  "all",
+		Become: true,
+		Tasks:  []Task{},
+	}
+	for _, step := range p.Steps {
+		pl.Tasks = append(pl.Tasks, ansibleTask(step))
+	}
+
+	return WritePlaybook(w, pl)
+}
+
+// WritePlaybook writes plays as a playbook
+func WritePlaybook(w io.Writer, plays ...Play) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(plays); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func ansibleTask(step Step) Task {
+	t := Task{Name: describe(step.Drift)}
+
+	switch {
+	case step.Package != nil:
+		pkg := step.Package
+		switch pkg.Manager {
+		case "dpkg":
+			t.Set("ansible.builtin.apt", map[string]interface{}{
+				"name": pkg.Name + "=" + pkg.Version, "state": "present", "allow_downgrade": true})
+		case "rpm":
+			t.Set("ansible.builtin.dnf", map[string]interface{}{
+				"name": pkg.Name + "-" + pkg.Version, "state": "present", "allow_downgrade": true})
+		case "apk":
+			t.Set("community.general.apk", map[string]interface{}{
+				"name": pkg.Name + "=" + pkg.Version, "state": "present"})
+		case "pip":
+			t.Set("ansible.builtin.pip", map[string]interface{}{
+				"name": pkg.Name, "version": pkg.Version})
+		case "npm":
+			t.Set("community.general.npm", map[string]interface{}{
+				"name": pkg.Name, "version": pkg.Version, "global": true})
+		}
+
+	case step.Service != nil:
+		state := "stopped"
+		if step.Service.Running {
+			state = "started"
+		}
+		t.Set("ansible.builtin.systemd_service", map[string]interface{}{
+			"name": step.Service.Name, "state": state, "enabled": step.Service.Enabled})
+
+	case step.File != nil:
+		args := map[string]interface{}{"path": step.File.Path}
+		if step.File.Mode != "" {
+			args["mode"] = step.File.Mode
+		}
+		if step.File.Owner != "" {
+			args["owner"] = step.File.Owner
+		}
+		if step.File.Group != "" {
+			args["group"] = step.File.Group
+		}
+		t.Set("ansible.builtin.file", args)
+
+	case step.Move != nil:
+		// there is no module for moving a file on the host
+		from, to := quote(step.Move.From), quote(step.Move.To)
+		t.Set("ansible.builtin.shell", map[string]interface{}{
+			"cmd":     fmt.Sprintf("mkdir -p %s && mv %s %s", quote(path.Dir(step.Move.To)), from, to),
+			"creates": step.Move.To,
+			"removes": step.Move.From,
+		})
+
+	case step.Cron != nil:
+		args := map[string]interface{}{
+			"path":   step.Cron.File,
+			"regexp": cronRegexp(step.Cron.Line),
+			"state":  "present",
+		}
+		if step.Cron.Remove {
+			args["state"] = "absent"
+		} else {
+			args["line"] = step.Cron.Line
+			args["create"] = true
+		}
+		t.Set("ansible.builtin.lineinfile", args)
+	}
+	return t
+}
+
+// Set makes the task run module name with args
+func (t *Task) Set(name string, args map[string]interface{}) {
+	t.Module = map[string]interface{}{name: args}
+}
+
+// cronRegexp matches a cron line however its fields are spaced
+func cronRegexp(line string) string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		fields[i] = regexp.QuoteMeta(f)
+	}
+	return `^\s*` + strings.Join(fields, `\s+`) + `\s*$`
+}
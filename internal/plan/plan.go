@@ -0,0 +1,303 @@
+// Package plan turns a drift report into the steps that take the target
+// back to the source, and writes them out as a shell script or an Ansible
+// playbook.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// Plan reverts a report's target toward its source
+type Plan struct {
+	Report *models.DriftReport
+	Steps  []Step
+	Manual []Step // drifts someone has to look at, with the reason in Manual
+}
+
+// Step undoes one drift. Exactly one of the actions is set.
+type Step struct {
+	Drift models.DriftItem
+
+	Package *PackageStep
+	Service *ServiceStep
+	File    *FileStep
+	Move    *MoveStep
+	Cron    *CronStep
+	Manual  string
+}
+
+// PackageStep installs a package at the version the source has
+type PackageStep struct {
+	Manager string
+	Name    string
+	Version string
+}
+
+// ServiceStep puts a service in the state the source had it in
+type ServiceStep struct {
+	Name    string
+	Running bool
+	Enabled bool
+}
+
+// FileStep restores a file's permissions and ownership. Empty fields are
+// left alone.
+type FileStep struct {
+	Path  string
+	Mode  string // octal, "0644"
+	Owner string
+	Group string
+}
+
+// MoveStep puts a file moved on the target back where the source has it
+type MoveStep struct {
+	From string // where the file is on the target
+	To   string
+}
+
+// CronStep adds a cron line back to its file or takes one out
+type CronStep struct {
+	File   string
+	Line   string
+	Remove bool
+}
+
+// installers are the package managers a version can be pinned with
+var installers = map[string]bool{"dpkg": true, "rpm": true, "apk": true, "pip": true, "npm": true}
+
+// Build plans a step for every active drift in report. Accepted drifts are
+// expected and left as they are.
+func Build(report *models.DriftReport) *Plan {
+	p := &Plan{Report: report}
+	for _, drift := range report.Drifts {
+		if drift.Accepted != nil {
+			continue
+		}
+
+		step := plan(drift)
+		step.Drift = drift
+		if step.Manual != "" {
+			p.Manual = append(p.Manual, step)
+		} else {
+			p.Steps = append(p.Steps, step)
+		}
+	}
+	return p
+}
+
+func plan(drift models.DriftItem) Step {
+	switch drift.Category {
+	case "package":
+		return planPackage(drift)
+	case "service":
+		return planService(drift)
+	case "file":
+		return planFile(drift)
+	case "scheduled_task":
+		return planCron(drift)
+	case "package_file":
+		return Step{Manual: "changed outside the package manager, reinstalling the package restores it"}
+	}
+	return Step{Manual: fmt.Sprintf("no automatic revert for %s drifts", drift.Category)}
+}
+
+func planPackage(drift models.DriftItem) Step {
+	manager, name, _ := strings.Cut(drift.Name, ":")
+
+	if drift.Type == "added" {
+		return Step{Manual: "installed on the target only, remove it if it isn't needed"}
+	}
+	version, _ := drift.SourceVal.(string)
+	if version == "" {
+		return Step{Manual: "the source version is not in the report"}
+	}
+	if !installers[manager] {
+		return Step{Manual: fmt.Sprintf("%s packages can't be pinned to a version", manager)}
+	}
+	return Step{Package: &PackageStep{Manager: manager, Name: name, Version: version}}
+}
+
+func planService(drift models.DriftItem) Step {
+	if drift.Type != "modified" {
+		if drift.Type == "added" {
+			return Step{Manual: "the service only exists on the target"}
+		}
+		return Step{Manual: "the service doesn't exist on the target, its package may be missing"}
+	}
+
+	var svc models.ServiceInfo
+	if !decode(drift.SourceVal, &svc) {
+		return Step{Manual: "the source state is not in the report"}
+	}
+	return Step{Service: &ServiceStep{Name: drift.Name, Running: svc.Status == "running", Enabled: svc.Enabled}}
+}
+
+func planFile(drift models.DriftItem) Step {
+	switch drift.Type {
+	case "added":
+		return Step{Manual: "the file only exists on the target"}
+	case "removed":
+		return Step{Manual: "the file is missing and its content was not captured"}
+	}
+
+	var src, tgt models.FileInfo
+	if !decode(drift.SourceVal, &src) || !decode(drift.TargetVal, &tgt) {
+		return Step{Manual: "the file is not in the report"}
+	}
+	if drift.Type == "moved" {
+		return planMove(drift, src, tgt)
+	}
+	// Changes can list config keys, the files themselves say what changed
+	if src.Hash != "" && tgt.Hash != "" && src.Hash != tgt.Hash {
+		return Step{Manual: "the content changed and was not captured"}
+	}
+
+	step := &FileStep{Path: drift.Name}
+	if src.Mode != tgt.Mode {
+		mode, ok := OctalMode(src.Mode)
+		if !ok {
+			return Step{Manual: fmt.Sprintf("can't restore mode %s", src.Mode)}
+		}
+		step.Mode = mode
+	}
+	if src.Owner != tgt.Owner {
+		step.Owner = src.Owner
+	}
+	if src.Group != tgt.Group {
+		step.Group = src.Group
+	}
+	if *step == (FileStep{Path: drift.Name}) {
+		return Step{Manual: "nothing that can be restored changed"}
+	}
+	return Step{File: step}
+}
+
+// planMove moves a file back when that is all that happened to it
+func planMove(drift models.DriftItem, src, tgt models.FileInfo) Step {
+	var from string
+	for _, ch := range drift.Changes {
+		if ch.Field == "path" {
+			from, _ = ch.Old.(string)
+		}
+	}
+	if from == "" {
+		return Step{Manual: "the original path is not in the report"}
+	}
+	if src.Hash != tgt.Hash || src.Mode != tgt.Mode || src.Owner != tgt.Owner || src.Group != tgt.Group {
+		return Step{Manual: fmt.Sprintf("moved from %s and changed, move it back and restore it by hand", from)}
+	}
+	return Step{Move: &MoveStep{From: drift.Name, To: from}}
+}
+
+func planCron(drift models.DriftItem) Step {
+	key := strings.TrimSuffix(drift.Name, " (cron)")
+
+	var job models.CronJob
+	switch drift.Type {
+	case "removed":
+		decode(drift.SourceVal, &job)
+	case "added":
+		decode(drift.TargetVal, &job)
+	default:
+		return Step{Manual: "restore the cron line from the source by hand"}
+	}
+
+	file, line, ok := CronLine(key, job)
+	if !ok {
+		return Step{Manual: "the cron file is not known"}
+	}
+	return Step{Cron: &CronStep{File: file, Line: line, Remove: drift.Type == "added"}}
+}
+
+// CronLine gives the file a collected cron job lives in and the line that
+// declares it, with runs of blanks squeezed
+func CronLine(key string, job models.CronJob) (file, line string, ok bool) {
+	file, ok = cronFile(key)
+	if !ok || job.Schedule == "" {
+		return "", "", false
+	}
+
+	// /etc/crontab lines keep their user column in the command
+	line = job.Schedule + " " + job.Command
+	if file != "/etc/crontab" {
+		line = job.Schedule + " " + job.User + " " + job.Command
+	}
+	return file, strings.Join(strings.Fields(line), " "), true
+}
+
+// cronFile gives the file of a cron job key: "/etc/crontab:12", or
+// "/etc/cron.d//backup:3_backup" for files under /etc/cron.d
+func cronFile(key string) (string, bool) {
+	i := strings.LastIndex(key, ":")
+	if i <= 0 {
+		return "", false
+	}
+	return path.Clean(key[:i]), true
+}
+
+// OctalMode turns a collected mode ("-rw-r--r--", "utrwxr-xr-x") into the
+// octal form chmod takes
+func OctalMode(mode string) (string, bool) {
+	if len(mode) < 10 || strings.ContainsRune(mode[:len(mode)-9], 'L') {
+		return "", false
+	}
+
+	var perm uint32
+	for _, ch := range mode[len(mode)-9:] {
+		perm <<= 1
+		if ch != '-' {
+			perm |= 1
+		}
+	}
+	// setuid, setgid and sticky come before the permissions
+	for _, ch := range mode[:len(mode)-9] {
+		switch ch {
+		case 'u':
+			perm |= 04000
+		case 'g':
+			perm |= 02000
+		case 't':
+			perm |= 01000
+		}
+	}
+	return fmt.Sprintf("%04o", perm), true
+}
+
+// decode reads a drift value into v. Values from a report loaded from JSON
+// are plain maps, so they go through JSON either way.
+func decode(value interface{}, v interface{}) bool {
+	if value == nil {
+		return false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// describe says what a drift was, for comments and task names
+func describe(drift models.DriftItem) string {
+	s := fmt.Sprintf("%s %s %s", drift.Type, drift.Category, OneLine(drift.Name))
+	if drift.ID != "" {
+		s = "[" + drift.ID + "] " + s
+	}
+	return s
+}
+
+// OneLine quotes text from a snapshot that holds control characters. Paths
+// and names are written into comments, and a newline in one would end the
+// comment and turn the rest into a command of a script run as root.
+func OneLine(s string) string {
+	if strings.ContainsFunc(s, unicode.IsControl) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// cronHelpers compare cron lines with runs of blanks squeezed, the way the
+// collector records them. The line is passed through the environment so awk
+// doesn't interpret backslashes in it.
+const cronHelpers = `cron_normalize='{ n = $0; gsub(/[ \t]+/, " ", n); sub(/^ /, "", n); sub(/ $/, "", n) }'
+
+cron_has() {
+    [ -f "$1" ] && LINE="$2" awk "$cron_normalize"' n == ENVIRON["LINE"] { found = 1 } END { exit !found }' "$1"
+}
+
+cron_add() {
+    cron_has "$1" "$2" || printf '%s\n' "$2" >> "$1"
+}
+
+cron_remove() {
+    cron_has "$1" "$2" || return 0
+    LINE="$2" awk "$cron_normalize"' n != ENVIRON["LINE"]' "$1" > "$1.drift-tmp"
+    cat "$1.drift-tmp" > "$1"
+    rm -f "$1.drift-tmp"
+}
+`
+
+// Shell writes the plan as a POSIX shell script. Every step checks the
+// current state first, so running the script again changes nothing.
+func (p *Plan) Shell(w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(fmt.Sprintf("# Reverts %s toward %s, from drift report %s\n", OneLine(p.Report.TargetEnv), OneLine(p.Report.SourceEnv), OneLine(p.Report.ID)))
+	sb.WriteString(fmt.Sprintf("# Generated by drift remediate on %s. Safe to run more than once.\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	writeManual(&sb, p.Manual, "# ")
+	sb.WriteString("\nset -eu\n")
+
+	for _, step := range p.Steps {
+		if step.Cron != nil {
+			sb.WriteString("\n" + cronHelpers)
+			break
+		}
+	}
+
+	for _, step := range p.Steps {
+		sb.WriteString("\n# " + describe(step.Drift) + "\n")
+		switch {
+		case step.Package != nil:
+			shellPackage(&sb, step.Package)
+		case step.Service != nil:
+			shellService(&sb, step.Service)
+		case step.File != nil:
+			shellFile(&sb, step.File)
+		case step.Move != nil:
+			shellMove(&sb, step.Move)
+		case step.Cron != nil:
+			verb := "cron_add"
+			if step.Cron.Remove {
+				verb = "cron_remove"
+			}
+			sb.WriteString(fmt.Sprintf("%s %s %s\n", verb, quote(step.Cron.File), quote(step.Cron.Line)))
+		}
+	}
+
+	if len(p.Steps) == 0 {
+		sb.WriteString("\n# nothing can be reverted automatically\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeManual lists the drifts that need a person as comment lines
+func writeManual(sb *strings.Builder, manual []Step, prefix string) {
+	if len(manual) == 0 {
+		return
+	}
+	sb.WriteString(strings.TrimSpace(prefix) + "\n")
+	sb.WriteString(fmt.Sprintf("%sNeeds manual action (%d):\n", prefix, len(manual)))
+	for _, step := range manual {
+		sb.WriteString(fmt.Sprintf("%s  %s: %s\n", prefix, describe(step.Drift), OneLine(step.Manual)))
+	}
+}
+
+func shellPackage(sb *strings.Builder, pkg *PackageStep) {
+	name, version := quote(pkg.Name), quote(pkg.Version)
+
+	var current, install string
+	switch pkg.Manager {
+	case "dpkg":
+		current = fmt.Sprintf("dpkg-query -W -f='${Version}' %s 2>/dev/null", name)
+		install = fmt.Sprintf("DEBIAN_FRONTEND=noninteractive apt-get install -y --allow-downgrades %s", quote(pkg.Name+"="+pkg.Version))
+	case "rpm":
+		current = fmt.Sprintf("rpm -q --qf '%%|EPOCH?{%%{EPOCH}:}:{}|%%{VERSION}-%%{RELEASE}' %s 2>/dev/null", name)
+		nevr := quote(pkg.Name + "-" + pkg.Version)
+		install = fmt.Sprintf("dnf install -y %s || dnf downgrade -y %s", nevr, nevr)
+	case "apk":
+		current = fmt.Sprintf(`v=$(apk info -e -v %s 2>/dev/null); printf '%%s' "${v#%s}"`, name, quote(pkg.Name+"-"))
+		install = fmt.Sprintf("apk add %s", quote(pkg.Name+"="+pkg.Version))
+	case "pip":
+		current = fmt.Sprintf("pip show %s 2>/dev/null | sed -n 's/^Version: //p'", name)
+		install = fmt.Sprintf("pip install %s", quote(pkg.Name+"=="+pkg.Version))
+	case "npm":
+		current = fmt.Sprintf("npm ls -g --depth=0 %s 2>/dev/null | sed -n 's/.*@//p'", name)
+		install = fmt.Sprintf("npm install -g %s", quote(pkg.Name+"@"+pkg.Version))
+	}
+
+	sb.WriteString(fmt.Sprintf("if [ \"$(%s)\" != %s ]; then\n", current, version))
+	sb.WriteString(fmt.Sprintf("    %s\n", install))
+	sb.WriteString("fi\n")
+}
+
+func shellService(sb *strings.Builder, svc *ServiceStep) {
+	name := quote(svc.Name)
+	if svc.Enabled {
+		sb.WriteString(fmt.Sprintf("systemctl is-enabled --quiet %s || systemctl enable %s\n", name, name))
+	} else {
+		sb.WriteString(fmt.Sprintf("if systemctl is-enabled --quiet %s; then systemctl disable %s; fi\n", name, name))
+	}
+	if svc.Running {
+		sb.WriteString(fmt.Sprintf("systemctl is-active --quiet %s || systemctl start %s\n", name, name))
+	} else {
+		sb.WriteString(fmt.Sprintf("if systemctl is-active --quiet %s; then systemctl stop %s; fi\n", name, name))
+	}
+}
+
+func shellFile(sb *strings.Builder, f *FileStep) {
+	path := quote(f.Path)
+	if f.Mode != "" {
+		sb.WriteString(fmt.Sprintf("chmod %s %s\n", f.Mode, path))
+	}
+	switch {
+	case f.Owner != "" && f.Group != "":
+		sb.WriteString(fmt.Sprintf("chown %s %s\n", quote(f.Owner+":"+f.Group), path))
+	case f.Owner != "":
+		sb.WriteString(fmt.Sprintf("chown %s %s\n", quote(f.Owner), path))
+	case f.Group != "":
+		sb.WriteString(fmt.Sprintf("chgrp %s %s\n", quote(f.Group), path))
+	}
+}
+
+func shellMove(sb *strings.Builder, m *MoveStep) {
+	from, to := quote(m.From), quote(m.To)
+	sb.WriteString(fmt.Sprintf("if [ -e %s ] && [ ! -e %s ]; then\n", from, to))
+	sb.WriteString(fmt.Sprintf("    mkdir -p %s\n", quote(path.Dir(m.To))))
+	sb.WriteString(fmt.Sprintf("    mv %s %s\n", from, to))
+	sb.WriteString("fi\n")
+}
+
+// quote makes s a single shell word
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
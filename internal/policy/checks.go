@@ -0,0 +1,252 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+func (c compiledCheck) evaluate(s *models.EnvironmentSnapshot, now time.Time) []result {
+	switch c.kind {
+	case "package":
+		return c.checkPackage(s.Packages)
+	case "service":
+		return c.checkService(s.Services)
+	case "file":
+		return c.checkFiles(s.Files)
+	case "user":
+		return c.checkUser(s.UserGroupConfig.Users)
+	case "certificate":
+		return c.checkCertificates(s.Certificates, now)
+	}
+	return nil
+}
+
+// broke records an expectation that doesn't hold
+func broke(broken []models.FieldChange, field string, expected, actual interface{}) []models.FieldChange {
+	return append(broken, models.FieldChange{Field: field, Old: expected, New: actual})
+}
+
+// wantsPresent tells whether a missing subject breaks the check. Unless
+// exists says otherwise, it does whenever the check expects something of it.
+func wantsPresent(exists *bool, expectations ...bool) bool {
+	if exists != nil {
+		return *exists
+	}
+	for _, e := range expectations {
+		if e {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compiledCheck) checkPackage(packages map[string]models.PackageInfo) []result {
+	want := c.Installed == nil || *c.Installed
+
+	var keys []string
+	for key, pkg := range packages {
+		if key == c.Package || pkg.Name == c.Package {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		if want {
+			return []result{{broken: broke(nil, "installed", true, false)}}
+		}
+		return []result{{}}
+	}
+
+	results := make([]result, 0, len(keys))
+	for _, key := range keys {
+		pkg := packages[key]
+		r := result{value: pkg}
+		// installed under more than one manager, each one is checked
+		if len(keys) > 1 {
+			r.item = key
+		}
+
+		if !want {
+			r.broken = broke(r.broken, "installed", false, true)
+		} else if c.Version != "" && !c.version.CheckFor(pkg.Manager, pkg.Version) {
+			r.broken = broke(r.broken, "version", c.version.String(), pkg.Version)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func (c compiledCheck) checkService(services map[string]models.ServiceInfo) []result {
+	svc, ok := services[c.Service]
+	if !ok {
+		running := c.Running != nil && *c.Running
+		enabled := c.Enabled != nil && *c.Enabled
+		if wantsPresent(c.Exists, running, enabled, c.Running == nil && c.Enabled == nil) {
+			return []result{{broken: broke(nil, "exists", true, false)}}
+		}
+		return []result{{}}
+	}
+
+	r := result{value: svc}
+	if c.Exists != nil && !*c.Exists {
+		r.broken = broke(r.broken, "exists", false, true)
+	}
+	if c.Running != nil && *c.Running != (svc.Status == "running") {
+		r.broken = broke(r.broken, "running", *c.Running, svc.Status == "running")
+	}
+	if c.Enabled != nil && *c.Enabled != svc.Enabled {
+		r.broken = broke(r.broken, "enabled", *c.Enabled, svc.Enabled)
+	}
+	return []result{r}
+}
+
+func (c compiledCheck) checkUser(users map[string]models.UserInfo) []result {
+	u, ok := users[c.User]
+	if !ok {
+		if wantsPresent(c.Exists, true) {
+			return []result{{broken: broke(nil, "exists", true, false)}}
+		}
+		return []result{{}}
+	}
+
+	r := result{value: u}
+	if c.Exists != nil && !*c.Exists {
+		r.broken = broke(r.broken, "exists", false, true)
+	}
+	if c.Shell != "" && c.Shell != u.Shell {
+		r.broken = broke(r.broken, "shell", c.Shell, u.Shell)
+	}
+	if c.UID != nil && *c.UID != u.UID {
+		r.broken = broke(r.broken, "uid", *c.UID, u.UID)
+	}
+	if c.Home != "" && c.Home != u.HomeDir {
+		r.broken = broke(r.broken, "home", c.Home, u.HomeDir)
+	}
+	return []result{r}
+}
+
+func (c compiledCheck) checkFiles(files map[string]models.FileInfo) []result {
+	if c.single {
+		f, ok := files[c.File]
+		if !ok {
+			if wantsPresent(c.Exists, true) {
+				return []result{{broken: broke(nil, "exists", true, false)}}
+			}
+			return []result{{}}
+		}
+		return []result{{value: f, broken: c.fileBroken(f)}}
+	}
+
+	var paths []string
+	for path := range files {
+		if c.match(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	if len(paths) == 0 && c.Exists != nil && *c.Exists {
+		return []result{{broken: broke(nil, "exists", true, false)}}
+	}
+
+	var failed []result
+	for _, path := range paths {
+		if broken := c.fileBroken(files[path]); len(broken) > 0 {
+			failed = append(failed, result{item: path, value: files[path], broken: broken})
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return []result{{note: fmt.Sprintf("%d files matching %s pass", len(paths), c.File)}}
+}
+
+func (c compiledCheck) fileBroken(f models.FileInfo) []models.FieldChange {
+	var broken []models.FieldChange
+	if c.Exists != nil && !*c.Exists {
+		return broke(broken, "exists", false, true)
+	}
+
+	perm, _ := parseMode(f.Mode)
+	if c.Mode != "" {
+		if want, _ := parseMode(c.Mode); want != perm {
+			broken = broke(broken, "mode", c.Mode, f.Mode)
+		}
+	}
+	if c.Owner != "" && c.Owner != f.Owner {
+		broken = broke(broken, "owner", c.Owner, f.Owner)
+	}
+	if c.Group != "" && c.Group != f.Group {
+		broken = broke(broken, "group", c.Group, f.Group)
+	}
+	// a symlink's own mode is always rwxrwxrwx and means nothing
+	if c.WorldWritable != nil && len(f.Mode) > 0 && f.Mode[0] != 'L' {
+		if writable := perm&0002 != 0; writable != *c.WorldWritable {
+			broken = broke(broken, "world_writable", *c.WorldWritable, writable)
+		}
+	}
+	return broken
+}
+
+// parseMode gives the permission bits of an octal mode ("0644") or of a
+// mode as files are collected with ("-rw-r--r--", "drwxrwxrwt")
+func parseMode(mode string) (uint32, error) {
+	if mode != "" && mode[0] >= '0' && mode[0] <= '9' {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid mode %q", mode)
+		}
+		return uint32(perm) & 0777, nil
+	}
+
+	if len(mode) < 9 {
+		return 0, fmt.Errorf("invalid mode %q", mode)
+	}
+	var perm uint32
+	for _, ch := range mode[len(mode)-9:] {
+		perm <<= 1
+		if ch != '-' {
+			perm |= 1
+		}
+	}
+	return perm, nil
+}
+
+func (c compiledCheck) checkCertificates(certs map[string]models.Certificate, now time.Time) []result {
+	var keys []string
+	for key, cert := range certs {
+		if c.match(cert.Path) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var failed []result
+	for _, key := range keys {
+		cert := certs[key]
+		// the snapshot's days to expiry were counted when it was taken
+		days := cert.DaysToExpire
+		expired := cert.IsExpired
+		if !cert.NotAfter.IsZero() {
+			days = int(cert.NotAfter.Sub(now).Hours() / 24)
+			expired = now.After(cert.NotAfter)
+		}
+
+		if expired || days < c.ValidDays {
+			failed = append(failed, result{
+				item:   key,
+				value:  cert,
+				broken: broke(nil, "valid_days", fmt.Sprintf(">= %d", c.ValidDays), days),
+			})
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return []result{{note: fmt.Sprintf("%d certificates matching %s pass", len(keys), c.Certificate)}}
+}
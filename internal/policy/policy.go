@@ -0,0 +1,276 @@
+// Package policy checks a snapshot against a declared desired state, such as
+// "nginx >= 1.24 is installed" or "nothing under /etc is world-writable".
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AshitomW/Drifty/internal/comparator"
+	"github.com/AshitomW/Drifty/internal/models"
+	"github.com/AshitomW/Drifty/internal/pattern"
+	"github.com/AshitomW/Drifty/internal/version"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a named list of checks
+type Policy struct {
+	Name   string  `yaml:"name"`
+	Checks []Check `yaml:"checks"`
+
+	compiled []compiledCheck
+}
+
+// Check asserts the state of one thing in a snapshot. It names exactly one
+// subject (package, service, file, user or certificate) and any of the
+// expectations that apply to it. file and certificate take the same patterns
+// as rules and then hold for every match.
+type Check struct {
+	ID       string `yaml:"id,omitempty"`
+	Severity string `yaml:"severity,omitempty"` // of a failure, warning if not set
+
+	Package     string `yaml:"package,omitempty"` // "nginx", or "dpkg:nginx" for one manager
+	Service     string `yaml:"service,omitempty"`
+	File        string `yaml:"file,omitempty"`
+	User        string `yaml:"user,omitempty"`
+	Certificate string `yaml:"certificate,omitempty"` // matched against the certificate file path
+
+	// packages
+	Installed *bool  `yaml:"installed,omitempty"`
+	Version   string `yaml:"version,omitempty"` // ">= 1.24", ">= 1.24, < 2"
+
+	// services
+	Running *bool `yaml:"running,omitempty"`
+	Enabled *bool `yaml:"enabled,omitempty"`
+
+	// services, files and users
+	Exists *bool `yaml:"exists,omitempty"`
+
+	// files
+	Mode          string `yaml:"mode,omitempty"` // "-rw-r--r--" or "0644"
+	Owner         string `yaml:"owner,omitempty"`
+	Group         string `yaml:"group,omitempty"`
+	WorldWritable *bool  `yaml:"world_writable,omitempty"`
+
+	// users
+	Shell string `yaml:"shell,omitempty"`
+	UID   *int   `yaml:"uid,omitempty"`
+	Home  string `yaml:"home,omitempty"`
+
+	// certificates, the days a certificate must stay valid for
+	ValidDays int `yaml:"valid_days,omitempty"`
+}
+
+type compiledCheck struct {
+	Check
+	kind    string
+	match   func(string) bool // file and certificate subjects
+	single  bool              // a file check naming one path
+	version version.Constraint
+}
+
+// Load reads a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = path
+	}
+	if err := p.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) compile() error {
+	p.compiled = make([]compiledCheck, 0, len(p.Checks))
+	for i, check := range p.Checks {
+		cc, err := compileCheck(check)
+		if err != nil {
+			name := check.ID
+			if name == "" {
+				name = fmt.Sprintf("%d", i+1)
+			}
+			return fmt.Errorf("check %s: %w", name, err)
+		}
+		p.compiled = append(p.compiled, cc)
+	}
+	return nil
+}
+
+func compileCheck(check Check) (compiledCheck, error) {
+	cc := compiledCheck{Check: check}
+
+	subjects := 0
+	for _, s := range []struct{ kind, value string }{
+		{"package", check.Package},
+		{"service", check.Service},
+		{"file", check.File},
+		{"user", check.User},
+		{"certificate", check.Certificate},
+	} {
+		if s.value != "" {
+			cc.kind = s.kind
+			subjects++
+		}
+	}
+	if subjects != 1 {
+		return cc, fmt.Errorf("needs exactly one of package, service, file, user or certificate")
+	}
+
+	switch check.Severity {
+	case "":
+		cc.Severity = "warning"
+	case "critical", "warning", "info":
+	default:
+		return cc, fmt.Errorf("unknown severity %q", check.Severity)
+	}
+
+	if cc.ID == "" {
+		cc.ID = cc.kind + " " + cc.subject()
+	}
+
+	switch cc.kind {
+	case "package":
+		if check.Version != "" {
+			c, err := version.ParseConstraint(check.Version)
+			if err != nil {
+				return cc, err
+			}
+			cc.version = c
+		}
+	case "file", "certificate":
+		match, err := pattern.Compile(cc.subject())
+		if err != nil {
+			return cc, err
+		}
+		cc.match = match
+		cc.single = cc.kind == "file" && !strings.ContainsAny(check.File, "*?") &&
+			!strings.HasSuffix(check.File, "/") && !strings.HasPrefix(check.File, "re:")
+	}
+
+	if check.Mode != "" {
+		if _, err := parseMode(check.Mode); err != nil {
+			return cc, err
+		}
+	}
+	return cc, nil
+}
+
+func (c compiledCheck) subject() string {
+	switch c.kind {
+	case "package":
+		return c.Package
+	case "service":
+		return c.Service
+	case "file":
+		return c.File
+	case "user":
+		return c.User
+	}
+	return c.Certificate
+}
+
+// Evaluate runs every check against snapshot. Each check gives a "pass" or
+// "fail" finding in the policy category; a check on a file or certificate
+// pattern fails once per matching item that breaks it.
+func (p *Policy) Evaluate(snapshot *models.EnvironmentSnapshot, now time.Time) *models.DriftReport {
+	report := &models.DriftReport{
+		ID:             uuid.New().String(),
+		Timestamp:      now.UTC(),
+		SourceEnv:      p.Name,
+		TargetEnv:      snapshot.Name,
+		TargetSnapshot: snapshot.ID,
+		Drifts:         make([]models.DriftItem, 0, len(p.compiled)),
+		Summary: models.DriftSummary{
+			ByCategory: make(map[string]int),
+			ByType:     make(map[string]int),
+		},
+	}
+
+	for _, check := range p.compiled {
+		for _, r := range check.evaluate(snapshot, now) {
+			report.Drifts = append(report.Drifts, check.finding(r))
+		}
+	}
+
+	Finish(report)
+	return report
+}
+
+// Finish fingerprints pass/fail findings and counts them. Only failures
+// count towards the summary, so HasDrift means something failed.
+func Finish(report *models.DriftReport) {
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		drift.ID = comparator.Fingerprint(*drift)
+
+		report.Summary.ByType[drift.Type]++
+		if drift.Type != "fail" {
+			continue
+		}
+		report.Summary.TotalDrifts++
+		report.Summary.ByCategory[drift.Category]++
+		switch drift.Severity {
+		case "critical":
+			report.Summary.CriticalCount++
+		case "warning":
+			report.Summary.WarningCount++
+		case "info":
+			report.Summary.InfoCount++
+		}
+	}
+	report.HasDrift = report.Summary.TotalDrifts > 0
+}
+
+// result is the outcome of a check for one item. Broken expectations are
+// recorded as changes from the expected value (Old) to the actual one (New).
+type result struct {
+	item   string // set for the members of a file or certificate pattern
+	value  interface{}
+	broken []models.FieldChange
+	note   string // what was checked, for a passing pattern
+}
+
+func (c compiledCheck) finding(r result) models.DriftItem {
+	drift := models.DriftItem{
+		Type:      "pass",
+		Category:  "policy",
+		Name:      c.ID,
+		TargetVal: r.value,
+		Changes:   r.broken,
+	}
+
+	subject := c.kind + " " + c.subject()
+	if r.item != "" {
+		drift.Name = c.ID + " " + r.item
+		subject = c.kind + " " + r.item
+	}
+
+	if len(r.broken) == 0 {
+		drift.Message = subject + " passes"
+		if r.note != "" {
+			drift.Message = r.note
+		}
+		return drift
+	}
+
+	parts := make([]string, 0, len(r.broken))
+	for _, b := range r.broken {
+		parts = append(parts, fmt.Sprintf("%s is %v, expected %v", b.Field, b.New, b.Old))
+	}
+	drift.Type = "fail"
+	drift.Severity = c.Severity
+	drift.Message = subject + ": " + strings.Join(parts, "; ")
+	return drift
+}
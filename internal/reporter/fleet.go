@@ -0,0 +1,96 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AshitomW/Drifty/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateFleet writes a fleet report. Table and text share one layout,
+// the other formats fall back to JSON.
+func (r *Reporter) GenerateFleet(report *models.FleetReport) error {
+	switch r.format {
+	case FormatYaml:
+		encoder := yaml.NewEncoder(r.writer)
+		encoder.SetIndent(2)
+		return encoder.Encode(report)
+	case FormatTable, FormatText:
+		return r.generateFleetText(report)
+	default:
+		encoder := json.NewEncoder(r.writer)
+		encoder.SetIndent("", " ")
+		return encoder.Encode(report)
+	}
+}
+
+func (r *Reporter) generateFleetText(report *models.FleetReport) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Fleet Report: %d hosts\n", len(report.Hosts)))
+	sb.WriteString(fmt.Sprintf("Generated: %s\n", report.Timestamp.Format("2006-01-02 15:04:05 UTC")))
+
+	sb.WriteString("\nCONSISTENCY\n")
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  CATEGORY\tSCORE\tSAME ON EVERY HOST\n")
+	for _, cat := range report.Categories {
+		fmt.Fprintf(w, "  %s\t%.1f%%\t%d of %d\n", cat.Category, cat.Score, cat.Consistent, cat.Items)
+	}
+	w.Flush()
+
+	hosts := append([]models.FleetHost(nil), report.Hosts...)
+	sort.SliceStable(hosts, func(i, j int) bool { return hosts[i].Outliers > hosts[j].Outliers })
+	if len(hosts) > 0 && hosts[0].Outliers > 0 {
+		sb.WriteString("\nHOSTS THAT STAND OUT\n")
+		w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		for _, h := range hosts {
+			if h.Outliers > 0 {
+				fmt.Fprintf(w, "  %s\t%d item(s) differ from most hosts\n", h.Name, h.Outliers)
+			}
+		}
+		w.Flush()
+	}
+
+	if len(report.Notes) > 0 {
+		sb.WriteString("\nNOTES\n")
+		for _, note := range report.Notes {
+			sb.WriteString(fmt.Sprintf("  ! %s\n", note))
+		}
+	}
+
+	// items come grouped by category
+	for i := 0; i < len(report.Items); {
+		cat := report.Items[i].Category
+		end := i
+		for end < len(report.Items) && report.Items[end].Category == cat {
+			end++
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%s (%d differences)\n", categoryTitle(cat), end-i))
+		sb.WriteString(strings.Repeat("-", 60) + "\n")
+		for _, item := range report.Items[i:end] {
+			sb.WriteString(item.Name + "\n")
+			sb.WriteString(fmt.Sprintf("    %s\n", item.Message))
+		}
+		i = end
+	}
+
+	if len(report.Items) == 0 {
+		sb.WriteString("\nEvery host is the same.\n")
+	}
+
+	_, err := r.writer.Write([]byte(sb.String()))
+	return err
+}
+
+func categoryTitle(category string) string {
+	for _, cat := range categoryNames {
+		if cat.category == category {
+			return cat.title
+		}
+	}
+	return strings.ToUpper(category)
+}
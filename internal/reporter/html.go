@@ -0,0 +1,157 @@
+package reporter
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+// htmlSection is one category of drifts in the HTML report
+type htmlSection struct {
+	Title  string
+	Drifts []models.DriftItem
+}
+
+// diffLine is a line of a diff with the class that colours it
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+var htmlFuncs = template.FuncMap{
+	"format": formatValue,
+	"date": func(r *models.DriftReport) string {
+		return r.Timestamp.Format("2006-01-02 15:04:05 UTC")
+	},
+	"label": func(d models.DriftItem) string {
+		switch {
+		case d.Accepted != nil:
+			return "ACCEPTED"
+		case d.Type == "pass":
+			return "PASS"
+		}
+		return tableSeverityLabel(d.Severity)
+	},
+	"diffLines": func(diff string) []diffLine {
+		var lines []diffLine
+		for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+			class := ""
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				class = "head"
+			case strings.HasPrefix(line, "@@"):
+				class = "hunk"
+			case strings.HasPrefix(line, "+"):
+				class = "add"
+			case strings.HasPrefix(line, "-"):
+				class = "del"
+			}
+			lines = append(lines, diffLine{class, line})
+		}
+		return lines
+	},
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Drift report: {{.Report.SourceEnv}} vs {{.Report.TargetEnv}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.15em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table.meta td { padding: 0.1em 1em 0.1em 0; }
+.summary span { display: inline-block; margin-right: 1.5em; }
+.drift { border: 1px solid #ddd; border-left-width: 4px; margin: 0.6em 0; padding: 0.5em 0.8em; }
+.drift.critical { border-left-color: #d73a49; }
+.drift.warning { border-left-color: #e3a008; }
+.drift.info { border-left-color: #0366d6; }
+.drift.accepted, .drift.pass { border-left-color: #28a745; }
+.badge { font-size: 0.75em; font-weight: bold; padding: 0.1em 0.4em; border-radius: 3px; background: #eee; }
+.name { font-family: monospace; font-weight: bold; }
+.detail { color: #555; font-size: 0.9em; margin: 0.2em 0; }
+pre.diff { background: #f6f8fa; padding: 0.5em; overflow-x: auto; font-size: 0.85em; }
+pre.diff .head { color: #555; font-weight: bold; }
+pre.diff .hunk { color: #6f42c1; }
+pre.diff .add { background: #e6ffed; color: #22863a; }
+pre.diff .del { background: #ffeef0; color: #b31d28; }
+</style>
+</head>
+<body>
+<h1>Environment Drift Report</h1>
+<table class="meta">
+<tr><td>Report ID</td><td>{{.Report.ID}}</td></tr>
+<tr><td>Generated</td><td>{{date .Report}}</td></tr>
+<tr><td>Source</td><td>{{.Report.SourceEnv}} ({{.Report.SourceSnapshot}})</td></tr>
+<tr><td>Target</td><td>{{.Report.TargetEnv}} ({{.Report.TargetSnapshot}})</td></tr>
+</table>
+<p class="summary">
+<span>Total drifts: <b>{{.Report.Summary.TotalDrifts}}</b></span>
+<span>Critical: <b>{{.Report.Summary.CriticalCount}}</b></span>
+<span>Warning: <b>{{.Report.Summary.WarningCount}}</b></span>
+<span>Info: <b>{{.Report.Summary.InfoCount}}</b></span>
+{{- if .Report.Summary.AcceptedCount}}
+<span>Accepted: <b>{{.Report.Summary.AcceptedCount}}</b></span>
+{{- end}}
+</p>
+{{- if .Report.Notes}}
+<h2>Notes</h2>
+<ul>
+{{- range .Report.Notes}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}} ({{len .Drifts}})</h2>
+{{- range .Drifts}}
+<div class="drift {{if .Accepted}}accepted{{else if eq .Type "pass"}}pass{{else}}{{.Severity}}{{end}}">
+<span class="badge">{{label .}}</span> <span class="badge">{{.Type}}</span> <span class="name">{{.Name}}</span>
+<div>{{.Message}}</div>
+{{- if .ID}}<div class="detail">ID: {{.ID}}</div>{{end}}
+{{- with .Accepted}}<div class="detail">Accepted: {{.Reason}}</div>{{end}}
+{{- if .Owner}}<div class="detail">Owner: {{.Owner}}</div>{{end}}
+{{- range .Changes}}
+<div class="detail">{{.Field}}: {{format .Old}} &rarr; {{format .New}}</div>
+{{- end}}
+{{- if .Diff}}
+<pre class="diff">{{range diffLines .Diff}}<span class="{{.Class}}">{{.Text}}</span>
+{{end}}</pre>
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// generateHTML writes a standalone page, diffs included, that can be
+// attached to a ticket or opened in a browser
+func (r *Reporter) generateHTML(report *models.DriftReport) error {
+	categories := make(map[string][]models.DriftItem)
+	for _, drift := range report.Drifts {
+		categories[drift.Category] = append(categories[drift.Category], drift)
+	}
+
+	var sections []htmlSection
+	for _, cat := range categoryNames {
+		if drifts := categories[cat.category]; len(drifts) > 0 {
+			sections = append(sections, htmlSection{Title: cat.title, Drifts: drifts})
+			delete(categories, cat.category)
+		}
+	}
+	// categories from third-party collectors have no title of their own
+	for _, drift := range report.Drifts {
+		if drifts, ok := categories[drift.Category]; ok {
+			sections = append(sections, htmlSection{Title: strings.ToUpper(drift.Category), Drifts: drifts})
+			delete(categories, drift.Category)
+		}
+	}
+
+	return htmlTemplate.Execute(r.writer, struct {
+		Report   *models.DriftReport
+		Sections []htmlSection
+	}{report, sections})
+}
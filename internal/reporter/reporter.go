@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
@@ -18,6 +19,7 @@ const (
 	FormatYaml  Format = "yaml"
 	FormatTable Format = "table"
 	FormatText  Format = "text"
+	FormatHTML  Format = "html"
 )
 
 type Reporter struct {
@@ -43,6 +45,8 @@ func (r *Reporter) Generate(report *models.DriftReport) error {
 		return r.generateTable(report)
 	case FormatText:
 		return r.generateText(report)
+	case FormatHTML:
+		return r.generateHTML(report)
 	default:
 		return r.generateJSON(report)
 	}
@@ -86,12 +90,20 @@ func (r *Reporter) generateTable(report *models.DriftReport) error {
 	fmt.Fprintf(w, "Critical:\t%d\n", report.Summary.CriticalCount)
 	fmt.Fprintf(w, "Warning:\t%d\n", report.Summary.WarningCount)
 	fmt.Fprintf(w, "Info:\t%d\n", report.Summary.InfoCount)
+	if report.Summary.AcceptedCount > 0 {
+		fmt.Fprintf(w, "Accepted:\t%d\n", report.Summary.AcceptedCount)
+	}
 	fmt.Fprintf(w, "\n")
 
 	// By Category
 	fmt.Fprintf(w, "By Category:\n")
-	for cat, count := range report.Summary.ByCategory {
-		fmt.Fprintf(w, "  %s:\t%d\n", cat, count)
+	cats := make([]string, 0, len(report.Summary.ByCategory))
+	for cat := range report.Summary.ByCategory {
+		cats = append(cats, cat)
+	}
+	sort.Strings(cats)
+	for _, cat := range cats {
+		fmt.Fprintf(w, "  %s:\t%d\n", cat, report.Summary.ByCategory[cat])
 	}
 	fmt.Fprintf(w, "\n")
 
@@ -144,6 +156,11 @@ func (r *Reporter) generateTable(report *models.DriftReport) error {
 
 	for _, drift := range report.Drifts {
 		sev := tableSeverityLabel(drift.Severity)
+		if drift.Accepted != nil {
+			sev = "ACCEPTED"
+		} else if drift.Type == "pass" {
+			sev = "PASS"
+		}
 		name := padTrunc(drift.Name, wName)
 		srcStr := ""
 		tgtStr := ""
@@ -163,6 +180,27 @@ func (r *Reporter) generateTable(report *models.DriftReport) error {
 	}
 	sb.WriteString(makeSep())
 
+	// the table has no room for diffs, they follow it
+	first := true
+	for _, drift := range report.Drifts {
+		if drift.Diff == "" {
+			continue
+		}
+		if first {
+			sb.WriteString("\nDIFFS\n")
+			first = false
+		}
+		sb.WriteString("\n" + drift.Name + "\n")
+		sb.WriteString(indent(drift.Diff, "  "))
+	}
+
+	if len(report.Notes) > 0 {
+		sb.WriteString("\nNOTES\n")
+		for _, note := range report.Notes {
+			sb.WriteString("  - " + note + "\n")
+		}
+	}
+
 	if _, err := r.writer.Write([]byte(sb.String())); err != nil {
 		return err
 	}
@@ -194,58 +232,67 @@ func (r *Reporter) generateText(report *models.DriftReport) error {
 	sb.WriteString(fmt.Sprintf("│ Critical:     %-21d │\n", report.Summary.CriticalCount))
 	sb.WriteString(fmt.Sprintf("│ Warning:      %-21d │\n", report.Summary.WarningCount))
 	sb.WriteString(fmt.Sprintf("│ Info:         %-21d │\n", report.Summary.InfoCount))
+	if report.Summary.AcceptedCount > 0 {
+		sb.WriteString(fmt.Sprintf("│ Accepted:     %-21d │\n", report.Summary.AcceptedCount))
+	}
 	sb.WriteString("└" + strings.Repeat("─", 38) + "┘\n\n")
 
-	// Group drifts by category
-	categories := map[string][]models.DriftItem{
-		"file":           {},
-		"envvar":         {},
-		"package":        {},
-		"service":        {},
-		"network":        {},
-		"docker":         {},
-		"resources":      {},
-		"scheduled_task": {},
-		"certificate":    {},
-		"user":           {},
+	if len(report.Notes) > 0 {
+		sb.WriteString("NOTES\n")
+		for _, note := range report.Notes {
+			sb.WriteString(fmt.Sprintf("  ! %s\n", note))
+		}
+		sb.WriteString("\n")
 	}
 
+	// Group drifts by category
+	categories := make(map[string][]models.DriftItem)
 	for _, drift := range report.Drifts {
 		categories[drift.Category] = append(categories[drift.Category], drift)
 	}
 
-	// Print each category
-	categoryNames := map[string]string{
-		"file":           "FILES",
-		"envvar":         "ENVIRONMENT VARIABLES",
-		"package":        "PACKAGES",
-		"service":        "SERVICES",
-		"network":        "NETWORK",
-		"docker":         "DOCKER",
-		"resources":      "SYSTEM RESOURCES",
-		"scheduled_task": "SCHEDULED TASKS",
-		"certificate":    "CERTIFICATES",
-		"user":           "USERS/GROUPS",
-	}
-
-	for cat, name := range categoryNames {
-		drifts := categories[cat]
+	// Print each category, in a fixed order
+	for _, cat := range categoryNames {
+		drifts := categories[cat.category]
 		if len(drifts) == 0 {
 			continue
 		}
 
-		sb.WriteString(fmt.Sprintf("\n%s (%d drifts)\n", name, len(drifts)))
+		sb.WriteString(fmt.Sprintf("\n%s (%d drifts)\n", cat.title, len(drifts)))
 		sb.WriteString(strings.Repeat("-", 60) + "\n")
 
 		for _, drift := range drifts {
 			icon := getTypeIcon(drift.Type)
 			severityIcon := getSeverityIcon(drift.Severity)
 			sb.WriteString(fmt.Sprintf("%s %s [%s] %s\n", icon, severityIcon, drift.Type, drift.Name))
+			if drift.ID != "" {
+				sb.WriteString(fmt.Sprintf("    ID: %s\n", drift.ID))
+			}
 			sb.WriteString(fmt.Sprintf("    %s\n", drift.Message))
-			if drift.SourceVal != nil && drift.TargetVal != nil {
+			if a := drift.Accepted; a != nil {
+				until := ""
+				if !a.Expires.IsZero() {
+					until = " until " + a.Expires.Local().Format("2006-01-02")
+				}
+				sb.WriteString(fmt.Sprintf("    Accepted: %s%s\n", a.Reason, until))
+			}
+			if drift.Owner != "" {
+				sb.WriteString(fmt.Sprintf("    Owner: %s\n", drift.Owner))
+			}
+			if len(drift.Tags) > 0 {
+				sb.WriteString(fmt.Sprintf("    Tags: %s\n", strings.Join(drift.Tags, ", ")))
+			}
+			if len(drift.Changes) > 0 {
+				for _, ch := range drift.Changes {
+					sb.WriteString(fmt.Sprintf("    %s: %s -> %s\n", ch.Field, formatValue(ch.Old), formatValue(ch.New)))
+				}
+			} else if drift.SourceVal != nil && drift.TargetVal != nil {
 				sb.WriteString(fmt.Sprintf("    Source: %v\n", formatValue(drift.SourceVal)))
 				sb.WriteString(fmt.Sprintf("    Target: %v\n", formatValue(drift.TargetVal)))
 			}
+			if drift.Diff != "" {
+				sb.WriteString(indent(drift.Diff, "    "))
+			}
 			sb.WriteString("\n")
 		}
 	}
@@ -254,6 +301,26 @@ func (r *Reporter) generateText(report *models.DriftReport) error {
 	return err
 }
 
+// categoryNames orders and titles the categories in text and HTML reports
+var categoryNames = []struct{ category, title string }{
+	{"file", "FILES"},
+	{"envvar", "ENVIRONMENT VARIABLES"},
+	{"package", "PACKAGES"},
+	{"package_file", "MODIFIED PACKAGE FILES"},
+	{"service", "SERVICES"},
+	{"network", "NETWORK"},
+	{"docker", "DOCKER"},
+	{"resources", "SYSTEM RESOURCES"},
+	{"scheduled_task", "SCHEDULED TASKS"},
+	{"certificate", "CERTIFICATES"},
+	{"user", "USERS/GROUPS"},
+	{"setting", "SETTINGS"},
+	{"policy", "POLICY CHECKS"},
+	{"audit", "HARDENING CHECKS"},
+	{"os", "OPERATING SYSTEM"},
+	{"group", "GROUPS"},
+}
+
 func colorSeverity(severity string) string {
 	switch severity {
 	case "critical":
@@ -302,11 +369,26 @@ func getTypeIcon(t string) string {
 		return "➖"
 	case "modified":
 		return "✏️"
+	case "moved":
+		return "🚚"
+	case "pass":
+		return "✅"
+	case "fail":
+		return "❌"
 	default:
 		return "•"
 	}
 }
 
+// indent prefixes every line of a multi-line block such as a diff
+func indent(block, prefix string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+		sb.WriteString(prefix + line + "\n")
+	}
+	return sb.String()
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -316,13 +398,19 @@ func truncate(s string, maxLen int) string {
 
 func formatValue(v interface{}) string {
 	switch val := v.(type) {
+	case nil:
+		return "(none)"
 	case string:
 		if len(val) > 60 {
 			return val[:57] + "..."
 		}
 		return val
 	case models.FileInfo:
-		return fmt.Sprintf("hash=%s, mode=%s", val.Hash[:8], val.Mode)
+		hash := val.Hash
+		if len(hash) > 8 {
+			hash = hash[:8]
+		}
+		return fmt.Sprintf("hash=%s, mode=%s", hash, val.Mode)
 	case models.ServiceInfo:
 		return fmt.Sprintf("status=%s, enabled=%v", val.Status, val.Enabled)
 	default:
@@ -0,0 +1,298 @@
+// Package rpmdb reads installed package headers out of the rpm database
+// without the rpm binary. The sqlite backend (rpmdb.sqlite, Fedora and
+// RHEL 9+) and the ndb backend (Packages.db, SUSE) are supported; the old
+// BerkeleyDB "Packages" file is not.
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/AshitomW/Drifty/internal/sqlite"
+)
+
+// Header tags used by drift
+const (
+	TagName           = 1000
+	TagVersion        = 1001
+	TagRelease        = 1002
+	TagEpoch          = 1003
+	TagSummary        = 1004
+	TagSize           = 1009
+	TagVendor         = 1011
+	TagPackager       = 1015
+	TagArch           = 1022
+	TagFileStates     = 1029
+	TagFileModes      = 1030
+	TagFileDigests    = 1035
+	TagFileFlags      = 1037
+	TagFileUsername   = 1039
+	TagFileGroupname  = 1040
+	TagSourceRPM      = 1044
+	TagDirIndexes     = 1116
+	TagBasenames      = 1117
+	TagDirnames       = 1118
+	TagLongSize       = 5009
+	TagFileDigestAlgo = 5011
+)
+
+// FileStates values other than FileNormal mean the file was never put on
+// disk (excluded docs, other-arch files, shared network paths)
+const FileNormal = 0
+
+// FileFlags bits
+const (
+	FileConfig = 1 << 0
+	FileDoc    = 1 << 1
+	FileGhost  = 1 << 6
+)
+
+const (
+	typeChar        = 1 // file states are stored as chars
+	typeInt8        = 2
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+type entry struct {
+	typ    uint32
+	offset uint32
+	count  uint32
+}
+
+// Header is a parsed rpm header blob
+type Header struct {
+	entries map[uint32]entry
+	data    []byte
+}
+
+// ParseHeader parses a header blob as stored in the database: an index
+// count, a data length, the index entries and the data store
+func ParseHeader(blob []byte) (*Header, error) {
+	if len(blob) < 8 {
+		return nil, errors.New("header blob too short")
+	}
+	il := binary.BigEndian.Uint32(blob[0:4])
+	dl := binary.BigEndian.Uint32(blob[4:8])
+	if uint64(8)+uint64(il)*16+uint64(dl) > uint64(len(blob)) {
+		return nil, errors.New("header blob truncated")
+	}
+
+	// the check above keeps these within the blob, in uint32 they could wrap
+	n := int(il)
+	start := 8 + n*16
+	h := &Header{
+		entries: make(map[uint32]entry, n),
+		data:    blob[start : start+int(dl)],
+	}
+	for i := 0; i < n; i++ {
+		e := blob[8+i*16:]
+		tag := binary.BigEndian.Uint32(e[0:4])
+		h.entries[tag] = entry{
+			typ:    binary.BigEndian.Uint32(e[4:8]),
+			offset: binary.BigEndian.Uint32(e[8:12]),
+			count:  binary.BigEndian.Uint32(e[12:16]),
+		}
+	}
+	return h, nil
+}
+
+// String returns a string tag, or the first element of an array tag
+func (h *Header) String(tag uint32) string {
+	if s := h.Strings(tag); len(s) > 0 {
+		return s[0]
+	}
+	return ""
+}
+
+// Strings returns a string array tag
+func (h *Header) Strings(tag uint32) []string {
+	e, ok := h.entries[tag]
+	if !ok || int(e.offset) > len(h.data) {
+		return nil
+	}
+	switch e.typ {
+	case typeString, typeStringArray, typeI18NString:
+	default:
+		return nil
+	}
+
+	count := int(e.count)
+	if e.typ == typeString {
+		count = 1
+	}
+	// every string takes at least its terminator, a count past that is corrupt
+	if left := len(h.data) - int(e.offset); count > left {
+		count = left
+	}
+	out := make([]string, 0, count)
+	data := h.data[e.offset:]
+	for i := 0; i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			break
+		}
+		out = append(out, string(data[:end]))
+		data = data[end+1:]
+	}
+	return out
+}
+
+// Int returns an integer tag, or the first element of an array tag
+func (h *Header) Int(tag uint32) int64 {
+	if v := h.Ints(tag); len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+// Has reports whether the header carries tag
+func (h *Header) Has(tag uint32) bool {
+	_, ok := h.entries[tag]
+	return ok
+}
+
+// Ints returns an integer array tag of any width
+func (h *Header) Ints(tag uint32) []int64 {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+
+	var width int
+	switch e.typ {
+	case typeChar, typeInt8:
+		width = 1
+	case typeInt16:
+		width = 2
+	case typeInt32:
+		width = 4
+	case typeInt64:
+		width = 8
+	default:
+		return nil
+	}
+	if uint64(e.offset)+uint64(e.count)*uint64(width) > uint64(len(h.data)) {
+		return nil
+	}
+
+	out := make([]int64, e.count)
+	data := h.data[e.offset:]
+	for i := range out {
+		switch width {
+		case 1:
+			out[i] = int64(data[i])
+		case 2:
+			out[i] = int64(binary.BigEndian.Uint16(data[i*2:]))
+		case 4:
+			out[i] = int64(binary.BigEndian.Uint32(data[i*4:]))
+		case 8:
+			out[i] = int64(binary.BigEndian.Uint64(data[i*8:]))
+		}
+	}
+	return out
+}
+
+// Files returns the full paths of the files in the package, in header order
+func (h *Header) Files() []string {
+	basenames := h.Strings(TagBasenames)
+	dirnames := h.Strings(TagDirnames)
+	indexes := h.Ints(TagDirIndexes)
+
+	files := make([]string, 0, len(basenames))
+	for i, base := range basenames {
+		if i < len(indexes) && int(indexes[i]) < len(dirnames) {
+			files = append(files, dirnames[indexes[i]]+base)
+		}
+	}
+	return files
+}
+
+// FromSQLite reads every package header from an rpmdb.sqlite database
+func FromSQLite(data, wal []byte) ([]*Header, error) {
+	db, err := sqlite.Open(data, wal)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Rows("Packages")
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]*Header, 0, len(rows))
+	for _, row := range rows {
+		h, err := ParseHeader(row.Blob(1))
+		if err != nil {
+			return nil, fmt.Errorf("package %d: %w", row.RowID, err)
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
+
+// The ndb layout, from rpm's lib/backend/ndb/rpmpkg.c. Everything is
+// little-endian; the file starts with slot pages mapping package indexes to
+// blobs stored in 16 byte blocks.
+const (
+	ndbMagic      = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic  = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic  = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbHeaderSize = 32
+	ndbPageSize   = 4096
+	ndbSlotSize   = 16
+	ndbBlockSize  = 16
+	ndbBlobHead   = 16
+)
+
+// FromNDB reads every package header from an ndb Packages.db file
+func FromNDB(data []byte) ([]*Header, error) {
+	if len(data) < ndbHeaderSize || binary.LittleEndian.Uint32(data[0:4]) != ndbMagic {
+		return nil, errors.New("not an rpm ndb database")
+	}
+	slotPages := int(binary.LittleEndian.Uint32(data[12:16]))
+	slotsEnd := slotPages * ndbPageSize
+	if slotsEnd > len(data) {
+		return nil, errors.New("ndb slot pages past end of file")
+	}
+
+	var headers []*Header
+	// the first slots of page 0 are taken by the file header
+	for off := ndbHeaderSize; off+ndbSlotSize <= slotsEnd; off += ndbSlotSize {
+		slot := data[off : off+ndbSlotSize]
+		if binary.LittleEndian.Uint32(slot[0:4]) != ndbSlotMagic {
+			continue
+		}
+		pkgIdx := binary.LittleEndian.Uint32(slot[4:8])
+		blkOff := int(binary.LittleEndian.Uint32(slot[8:12]))
+		if pkgIdx == 0 {
+			continue // free slot
+		}
+
+		start := blkOff * ndbBlockSize
+		if start+ndbBlobHead > len(data) {
+			return nil, fmt.Errorf("package %d: blob past end of file", pkgIdx)
+		}
+		head := data[start : start+ndbBlobHead]
+		if binary.LittleEndian.Uint32(head[0:4]) != ndbBlobMagic || binary.LittleEndian.Uint32(head[4:8]) != pkgIdx {
+			return nil, fmt.Errorf("package %d: bad blob header", pkgIdx)
+		}
+		length := int(binary.LittleEndian.Uint32(head[12:16]))
+		if start+ndbBlobHead+length > len(data) {
+			return nil, fmt.Errorf("package %d: blob truncated", pkgIdx)
+		}
+
+		h, err := ParseHeader(data[start+ndbBlobHead : start+ndbBlobHead+length])
+		if err != nil {
+			return nil, fmt.Errorf("package %d: %w", pkgIdx, err)
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
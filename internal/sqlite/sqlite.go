@@ -0,0 +1,494 @@
+// Package sqlite is a small read-only reader for SQLite database files. It
+// only walks table b-trees and decodes records, which is all that's needed
+// to read package databases (rpmdb.sqlite, dnf history) without cgo or the
+// sqlite3 binary. Indexes, schemas and SQL are not interpreted.
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	headerMagic = "SQLite format 3\x00"
+
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+var ErrNoTable = errors.New("no such table")
+
+// Row is one table row. Values are int64, float64, string, []byte or nil.
+// An INTEGER PRIMARY KEY column is filled in from the RowID, since SQLite
+// stores it as NULL.
+type Row struct {
+	RowID  int64
+	Values []interface{}
+}
+
+// Int returns column i as an integer, or 0
+func (r Row) Int(i int) int64 {
+	if i < len(r.Values) {
+		if v, ok := r.Values[i].(int64); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// Text returns column i as a string, or ""
+func (r Row) Text(i int) string {
+	if i < len(r.Values) {
+		switch v := r.Values[i].(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		}
+	}
+	return ""
+}
+
+// Blob returns column i as bytes, or nil
+func (r Row) Blob(i int) []byte {
+	if i < len(r.Values) {
+		switch v := r.Values[i].(type) {
+		case []byte:
+			return v
+		case string:
+			return []byte(v)
+		}
+	}
+	return nil
+}
+
+// DB is an SQLite database held in memory
+type DB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	pages    map[uint32][]byte // newer page versions from the WAL
+}
+
+// Open parses a database file. wal is the content of the "-wal" file next to
+// it, if there is one; committed pages from it override the main file so a
+// database that hasn't been checkpointed still reads correctly.
+func Open(data, wal []byte) (*DB, error) {
+	if len(data) < 100 || string(data[:16]) != headerMagic {
+		return nil, errors.New("not an SQLite database")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+
+	db := &DB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+	}
+	if db.usable < 480 { // the least SQLite itself allows
+		return nil, fmt.Errorf("invalid usable page size %d", db.usable)
+	}
+	if len(wal) > 0 {
+		db.pages = readWAL(wal, pageSize)
+	}
+	return db, nil
+}
+
+// readWAL returns the newest committed version of every page in the WAL.
+// Frames are only trusted up to the last commit frame with the current salt.
+func readWAL(wal []byte, pageSize int) map[uint32][]byte {
+	const headerSize, frameHeaderSize = 32, 24
+
+	if len(wal) < headerSize {
+		return nil
+	}
+	magic := binary.BigEndian.Uint32(wal[0:4])
+	if magic != 0x377f0682 && magic != 0x377f0683 {
+		return nil
+	}
+	if int(binary.BigEndian.Uint32(wal[8:12])) != pageSize {
+		return nil
+	}
+	salt := wal[16:24]
+
+	committed := make(map[uint32][]byte)
+	pending := make(map[uint32][]byte)
+	for off := headerSize; off+frameHeaderSize+pageSize <= len(wal); off += frameHeaderSize + pageSize {
+		frame := wal[off : off+frameHeaderSize]
+		if !bytes.Equal(frame[8:16], salt) {
+			break
+		}
+		pgno := binary.BigEndian.Uint32(frame[0:4])
+		pending[pgno] = wal[off+frameHeaderSize : off+frameHeaderSize+pageSize]
+
+		if binary.BigEndian.Uint32(frame[4:8]) != 0 { // commit frame
+			for k, v := range pending {
+				committed[k] = v
+			}
+			pending = make(map[uint32][]byte)
+		}
+	}
+	return committed
+}
+
+func (db *DB) page(n uint32) ([]byte, error) {
+	if p, ok := db.pages[n]; ok {
+		return p, nil
+	}
+	start := int(n-1) * db.pageSize
+	if n == 0 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// Rows returns every row of the named table, in rowid order
+func (db *DB) Rows(table string) ([]Row, error) {
+	master, err := db.master(table)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.scan(uint32(master.Int(3)))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, alias, err := parseColumns(master.Text(4)); err == nil && alias >= 0 {
+		for i := range rows {
+			if alias < len(rows[i].Values) && rows[i].Values[alias] == nil {
+				rows[i].Values[alias] = rows[i].RowID
+			}
+		}
+	}
+	return rows, nil
+}
+
+// Columns returns the column names of a table in storage order, taken from
+// its CREATE TABLE statement. Columns added later with ALTER TABLE are
+// included, since SQLite rewrites the stored statement.
+func (db *DB) Columns(table string) ([]string, error) {
+	master, err := db.master(table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, _, err := parseColumns(master.Text(4))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse schema of %s", table)
+	}
+	return columns, nil
+}
+
+// parseColumns reads the column names out of a CREATE TABLE statement, and
+// which of them (if any) is an INTEGER PRIMARY KEY aliasing the rowid
+func parseColumns(sql string) ([]string, int, error) {
+	open, close := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if open < 0 || close < open {
+		return nil, -1, errors.New("no column list")
+	}
+
+	var columns []string
+	alias := -1
+	for _, def := range splitTopLevel(sql[open+1 : close]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue // table constraints, not columns
+		}
+		upper := strings.ToUpper(strings.Join(fields[1:], " "))
+		if strings.HasPrefix(upper, "INTEGER") && strings.Contains(upper, "PRIMARY KEY") {
+			alias = len(columns)
+		}
+		columns = append(columns, strings.Trim(fields[0], "\"`[]'"))
+	}
+	return columns, alias, nil
+}
+
+// master finds a table's row in sqlite_master: type, name, tbl_name,
+// rootpage, sql
+func (db *DB) master(table string) (Row, error) {
+	schema, err := db.scan(1)
+	if err != nil {
+		return Row{}, fmt.Errorf("reading schema: %w", err)
+	}
+
+	for _, row := range schema {
+		if row.Text(0) == "table" && strings.EqualFold(row.Text(1), table) {
+			return row, nil
+		}
+	}
+	return Row{}, fmt.Errorf("%w: %s", ErrNoTable, table)
+}
+
+// splitTopLevel splits column definitions on commas outside parentheses
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// scan walks the table b-tree rooted at page root
+func (db *DB) scan(root uint32) ([]Row, error) {
+	var rows []Row
+	seen := make(map[uint32]bool)
+
+	var walk func(n uint32) error
+	walk = func(n uint32) error {
+		if seen[n] {
+			return fmt.Errorf("page %d visited twice, database corrupt", n)
+		}
+		seen[n] = true
+
+		p, err := db.page(n)
+		if err != nil {
+			return err
+		}
+		hdr := 0
+		if n == 1 {
+			hdr = 100 // the database header sits in front of page 1's b-tree
+		}
+
+		// a corrupt page can claim more cells than it holds, or point
+		// them anywhere, so every offset is checked before it is used
+		if hdr+12 > len(p) {
+			return fmt.Errorf("page %d: header runs past end of page", n)
+		}
+		cells := int(binary.BigEndian.Uint16(p[hdr+3 : hdr+5]))
+		switch p[hdr] {
+		case pageInteriorTable:
+			ptrs, err := cellPointers(p, hdr+12, cells)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			for _, off := range ptrs {
+				if off+4 > len(p) {
+					return fmt.Errorf("page %d: cell runs past end of page", n)
+				}
+				if err := walk(binary.BigEndian.Uint32(p[off:])); err != nil {
+					return err
+				}
+			}
+			return walk(binary.BigEndian.Uint32(p[hdr+8:]))
+
+		case pageLeafTable:
+			ptrs, err := cellPointers(p, hdr+8, cells)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			for _, off := range ptrs {
+				row, err := db.leafCell(p, off)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, row)
+			}
+			return nil
+
+		default:
+			return fmt.Errorf("page %d is not a table b-tree page (type %#x)", n, p[hdr])
+		}
+	}
+
+	return rows, walk(root)
+}
+
+// cellPointers reads the offsets of a page's cells, which follow its header
+// at start
+func cellPointers(p []byte, start, cells int) ([]int, error) {
+	if start+cells*2 > len(p) {
+		return nil, errors.New("cell pointers run past end of page")
+	}
+	ptrs := make([]int, cells)
+	for i := range ptrs {
+		ptrs[i] = int(binary.BigEndian.Uint16(p[start+i*2:]))
+		if ptrs[i] >= len(p) {
+			return nil, errors.New("cell pointer past end of page")
+		}
+	}
+	return ptrs, nil
+}
+
+// leafCell decodes one table leaf cell, following overflow pages for
+// payloads that don't fit on the page
+func (db *DB) leafCell(p []byte, off int) (Row, error) {
+	size, n := varint(p[off:])
+	off += n
+	if off >= len(p) {
+		return Row{}, errors.New("cell runs past end of page")
+	}
+	rowid, n := varint(p[off:])
+	off += n
+	if size > uint64(len(db.data)+len(db.pages)*db.pageSize) {
+		return Row{}, fmt.Errorf("cell claims %d bytes, more than the database holds", size)
+	}
+
+	payload, err := db.payload(p, off, int(size))
+	if err != nil {
+		return Row{}, err
+	}
+
+	values, err := record(payload)
+	return Row{RowID: int64(rowid), Values: values}, err
+}
+
+func (db *DB) payload(p []byte, off, size int) ([]byte, error) {
+	u := db.usable
+	maxLocal := u - 35
+	if size <= maxLocal {
+		if off+size > len(p) {
+			return nil, errors.New("cell runs past end of page")
+		}
+		return p[off : off+size], nil
+	}
+
+	minLocal := (u-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(u-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+
+	if off+local+4 > len(p) {
+		return nil, errors.New("cell runs past end of page")
+	}
+	out := make([]byte, 0, size)
+	out = append(out, p[off:off+local]...)
+	next := binary.BigEndian.Uint32(p[off+local:])
+
+	seen := make(map[uint32]bool)
+	for len(out) < size {
+		if next == 0 {
+			return nil, errors.New("overflow chain ends early")
+		}
+		if seen[next] {
+			return nil, fmt.Errorf("overflow page %d visited twice, database corrupt", next)
+		}
+		seen[next] = true
+		page, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(page[0:4])
+		chunk := page[4:u]
+		if remaining := size - len(out); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// record decodes the SQLite record format: a header of serial types
+// followed by the values
+func record(data []byte) ([]interface{}, error) {
+	hdrSize, n := varint(data)
+	if hdrSize > uint64(len(data)) {
+		return nil, errors.New("record header runs past payload")
+	}
+
+	var types []uint64
+	for pos := n; pos < int(hdrSize); {
+		t, n := varint(data[pos:])
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]interface{}, len(types))
+	body := data[hdrSize:]
+	for i, t := range types {
+		size := serialSize(t)
+		if size < 0 || size > len(body) {
+			return nil, errors.New("record value runs past payload")
+		}
+		v := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t >= 1 && t <= 6:
+			values[i] = bigEndianInt(v)
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(v))
+		case t == 8:
+			values[i] = int64(0)
+		case t == 9:
+			values[i] = int64(1)
+		case t >= 12 && t%2 == 0:
+			values[i] = v
+		case t >= 13:
+			values[i] = string(v)
+		}
+	}
+	return values, nil
+}
+
+func serialSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return int(t)
+	case t == 5:
+		return 6
+	case t == 6 || t == 7:
+		return 8
+	case t >= 12:
+		if t > math.MaxInt32 { // no value is that long, the record is corrupt
+			return -1
+		}
+		return int(t-12) / 2
+	default:
+		return 0
+	}
+}
+
+// bigEndianInt sign-extends a 1 to 8 byte big-endian integer
+func bigEndianInt(b []byte) int64 {
+	var v int64
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// varint decodes SQLite's big-endian variable length integer
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8 && i < len(b); i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) >= 9 {
+		return v<<8 | uint64(b[8]), 9
+	}
+	return v, len(b)
+}
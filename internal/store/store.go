@@ -0,0 +1,353 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AshitomW/Drifty/internal/models"
+)
+
+const indexFile = "index.json"
+
+// Entry is the index record of a stored snapshot
+type Entry struct {
+	ID        string            `json:"id" yaml:"id"`
+	Name      string            `json:"name" yaml:"name"`
+	Hostname  string            `json:"hostname" yaml:"hostname"`
+	Timestamp time.Time         `json:"timestamp" yaml:"timestamp"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	File      string            `json:"file" yaml:"file"`
+}
+
+// Store keeps snapshots as one JSON file each in a directory, next to an
+// index so listing and resolving references doesn't load every snapshot.
+type Store struct {
+	dir string
+}
+
+// Open opens the store in dir, creating the directory if needed
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage path is not configured")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating snapshot store: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes the snapshot and adds it to the index
+func (s *Store) Save(snapshot *models.EnvironmentSnapshot) (Entry, error) {
+	if err := checkID(snapshot.ID); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		ID:        snapshot.ID,
+		Name:      snapshot.Name,
+		Hostname:  snapshot.Hostname,
+		Timestamp: snapshot.Timestamp,
+		Labels:    snapshot.Labels,
+		File:      snapshot.ID + ".json",
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return entry, err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, entry.File), data); err != nil {
+		return entry, err
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return entry, err
+	}
+
+	// a rebuilt index already picked up the file written above
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.ID != entry.ID {
+			kept = append(kept, e)
+		}
+	}
+	return entry, s.writeIndex(append(kept, entry))
+}
+
+// checkID makes sure an ID names a file inside the store. IDs come from the
+// snapshot, which may have been imported from anywhere.
+func checkID(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("snapshot has no ID")
+	case filepath.Base(id) != id, id == ".", id == "..", strings.ContainsAny(id, `/\`), strings.ContainsFunc(id, unicode.IsControl):
+		return fmt.Errorf("snapshot ID %q can't be used as a file name", id)
+	case id+".json" == indexFile:
+		return fmt.Errorf("snapshot ID %q is reserved by the store", id)
+	}
+	return nil
+}
+
+// List returns every indexed snapshot, newest first
+func (s *Store) List() ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if os.IsNotExist(err) {
+		return s.rebuildIndex()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("reading snapshot index: %w", err)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Load reads a stored snapshot by its full ID
+func (s *Store) Load(id string) (*models.EnvironmentSnapshot, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		path, err := s.path(e)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot models.EnvironmentSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, err
+		}
+		return &snapshot, nil
+	}
+
+	return nil, fmt.Errorf("snapshot %s not found", id)
+}
+
+// path gives the file of an index entry. The index is a plain file anyone may
+// have edited, so a file outside the store is refused.
+func (s *Store) path(e Entry) (string, error) {
+	if filepath.Base(e.File) != e.File || e.File == "." || e.File == ".." {
+		return "", fmt.Errorf("snapshot %s: file %q is outside the store", e.ID, e.File)
+	}
+	return filepath.Join(s.dir, e.File), nil
+}
+
+// Remove deletes the given snapshots and drops them from the index
+func (s *Store) Remove(ids ...string) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if !remove[e.ID] {
+			kept = append(kept, e)
+			continue
+		}
+		path, err := s.path(e)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return s.writeIndex(kept)
+}
+
+// Prune selects snapshots to delete. Snapshots are grouped into series by
+// name and hostname; the newest keepLast of each series are always kept, and
+// if olderThan is set only snapshots older than that are selected. With
+// dryRun nothing is deleted.
+func (s *Store) Prune(keepLast int, olderThan time.Duration, now time.Time, dryRun bool) ([]Entry, error) {
+	if keepLast <= 0 && olderThan <= 0 {
+		return nil, fmt.Errorf("prune needs --keep-last or --older-than")
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]int)
+	var pruned []Entry
+	var ids []string
+	for _, e := range entries {
+		series := e.Name + "\x00" + e.Hostname
+		seen[series]++
+
+		if keepLast > 0 && seen[series] <= keepLast {
+			continue
+		}
+		if olderThan > 0 && now.Sub(e.Timestamp) < olderThan {
+			continue
+		}
+		pruned = append(pruned, e)
+		ids = append(ids, e.ID)
+	}
+
+	if dryRun || len(ids) == 0 {
+		return pruned, nil
+	}
+	return pruned, s.Remove(ids...)
+}
+
+// Resolve turns a snapshot reference into an index entry. References are
+//
+//	@latest          the newest snapshot in the store
+//	@web1            the newest snapshot whose name or hostname is web1
+//	@env=prod        the newest snapshot carrying label env=prod
+//	@web1~2          the snapshot two before the newest one of that selector
+//	3f2a9c           the snapshot whose ID starts with 3f2a9c
+func (s *Store) Resolve(ref string) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if !strings.HasPrefix(ref, "@") {
+		var matches []Entry
+		for _, e := range entries {
+			if strings.HasPrefix(e.ID, ref) {
+				matches = append(matches, e)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return Entry{}, fmt.Errorf("no stored snapshot matches %q", ref)
+		case 1:
+			return matches[0], nil
+		default:
+			return Entry{}, fmt.Errorf("snapshot ID prefix %q is ambiguous (%d matches)", ref, len(matches))
+		}
+	}
+
+	selector := strings.TrimPrefix(ref, "@")
+	back := 0
+	if idx := strings.LastIndex(selector, "~"); idx >= 0 {
+		n, err := strconv.Atoi(selector[idx+1:])
+		if err != nil || n < 0 {
+			return Entry{}, fmt.Errorf("invalid snapshot reference %q", ref)
+		}
+		selector, back = selector[:idx], n
+	}
+
+	var matches []Entry
+	for _, e := range entries {
+		if matchSelector(e, selector) {
+			matches = append(matches, e)
+		}
+	}
+
+	if back >= len(matches) {
+		return Entry{}, fmt.Errorf("snapshot reference %q: only %d snapshot(s) match", ref, len(matches))
+	}
+	return matches[back], nil
+}
+
+func matchSelector(e Entry, selector string) bool {
+	if selector == "latest" {
+		return true
+	}
+	if key, value, ok := strings.Cut(selector, "="); ok {
+		return e.Labels[key] == value
+	}
+	return e.Name == selector || e.Hostname == selector
+}
+
+// rebuildIndex recreates the index from the snapshot files, used when the
+// index is missing (an empty or hand-populated store)
+func (s *Store) rebuildIndex() ([]Entry, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if filepath.Base(file) == indexFile {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var snapshot models.EnvironmentSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil || snapshot.ID == "" {
+			continue
+		}
+		entries = append(entries, Entry{
+			ID:        snapshot.ID,
+			Name:      snapshot.Name,
+			Hostname:  snapshot.Hostname,
+			Timestamp: snapshot.Timestamp,
+			Labels:    snapshot.Labels,
+			File:      filepath.Base(file),
+		})
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+func (s *Store) writeIndex(entries []Entry) error {
+	sortEntries(entries)
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, indexFile), data)
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Timestamp.Equal(entries[j].Timestamp) {
+			return entries[i].Timestamp.After(entries[j].Timestamp)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
+// writeFileAtomic writes through a temporary file so a crash never leaves a
+// half-written snapshot or index behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,89 @@
+package version
+
+import (
+	"strings"
+)
+
+// apkSuffixes rank the suffixes of an apk version. The ones before "" mark
+// pre-releases, the ones after it snapshots and patches of a release.
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"":    0,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// apkVersion is 1.2.3b_rc1_p2-r4 taken apart
+type apkVersion struct {
+	numbers  []string
+	letter   string
+	suffixes []apkSuffix
+	revision string
+}
+
+type apkSuffix struct {
+	name   string
+	number string
+}
+
+func parseAPK(v string) apkVersion {
+	var p apkVersion
+	if i := strings.LastIndex(v, "-r"); i >= 0 && isDigits(v[i+2:]) {
+		v, p.revision = v[:i], v[i+2:]
+	}
+
+	main, rest, _ := strings.Cut(v, "_")
+	for _, n := range strings.Split(main, ".") {
+		digits := leadingDigits(n)
+		p.numbers = append(p.numbers, digits)
+		if letter := n[len(digits):]; letter != "" {
+			p.letter = letter
+		}
+	}
+	if rest != "" {
+		for _, s := range strings.Split(rest, "_") {
+			name := strings.TrimRight(s, "0123456789")
+			p.suffixes = append(p.suffixes, apkSuffix{name, s[len(name):]})
+		}
+	}
+	return p
+}
+
+// compareAPK orders versions the way apk does: numbers, a letter, the
+// suffixes, then the -rN package revision
+func compareAPK(a, b string) int {
+	pa, pb := parseAPK(a), parseAPK(b)
+
+	for i := 0; i < len(pa.numbers) || i < len(pb.numbers); i++ {
+		// 1.2 comes before 1.2.0
+		if i >= len(pa.numbers) {
+			return -1
+		}
+		if i >= len(pb.numbers) {
+			return 1
+		}
+		if c := compareNumbers(pa.numbers[i], pb.numbers[i]); c != 0 {
+			return c
+		}
+	}
+	if c := strings.Compare(pa.letter, pb.letter); c != 0 {
+		return c
+	}
+
+	for i := 0; i < len(pa.suffixes) || i < len(pb.suffixes); i++ {
+		var sa, sb apkSuffix
+		if i < len(pa.suffixes) {
+			sa = pa.suffixes[i]
+		}
+		if i < len(pb.suffixes) {
+			sb = pb.suffixes[i]
+		}
+		if c := sign(apkSuffixes[sa.name] - apkSuffixes[sb.name]); c != 0 {
+			return c
+		}
+		if c := compareNumbers(sa.number, sb.number); c != 0 {
+			return c
+		}
+	}
+
+	return compareNumbers(pa.revision, pb.revision)
+}
@@ -0,0 +1,155 @@
+package version
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pep440Pattern is the version scheme of Python packages, spellings such as
+// "1.0-RC.1" and "1.0.post-2" included
+var pep440Pattern = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440Version is a Python version taken apart. Missing pre, post and dev
+// parts are nil so they can sort on either side of the ones present.
+type pep440Version struct {
+	epoch   string
+	release []string
+	pre     *pep440Part
+	post    *pep440Part
+	dev     *pep440Part
+	local   []string
+}
+
+type pep440Part struct {
+	rank   int // a, b, rc for pre-releases
+	number string
+}
+
+var preReleases = map[string]int{
+	"a": 0, "alpha": 0,
+	"b": 1, "beta": 1,
+	"c": 2, "rc": 2, "pre": 2, "preview": 2,
+}
+
+func parsePEP440(v string) (pep440Version, bool) {
+	m := pep440Pattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return pep440Version{}, false
+	}
+
+	p := pep440Version{epoch: m[1], release: strings.Split(m[2], ".")}
+	if m[3] != "" {
+		p.pre = &pep440Part{preReleases[m[3]], m[4]}
+	}
+	switch {
+	case m[5] != "":
+		p.post = &pep440Part{number: m[5]}
+	case m[6] != "":
+		p.post = &pep440Part{number: m[7]}
+	}
+	if m[8] != "" {
+		p.dev = &pep440Part{number: m[9]}
+	}
+	if m[10] != "" {
+		p.local = strings.FieldsFunc(m[10], func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+	return p, true
+}
+
+// comparePEP440 orders Python versions: 1.0.dev1 < 1.0a1 < 1.0 < 1.0.post1
+// < 1.0+local. Versions that don't follow the scheme fall back to dpkg's
+// ordering.
+func comparePEP440(a, b string) int {
+	pa, okA := parsePEP440(a)
+	pb, okB := parsePEP440(b)
+	if !okA || !okB {
+		return Compare(a, b)
+	}
+
+	if c := compareNumbers(pa.epoch, pb.epoch); c != 0 {
+		return c
+	}
+	if c := compareRelease(pa.release, pb.release); c != 0 {
+		return c
+	}
+
+	// a dev release of a final version comes before its pre-releases
+	preA, preB := pa.pre, pb.pre
+	if preA == nil && pa.post == nil && pa.dev != nil {
+		preA = &pep440Part{rank: -1}
+	}
+	if preB == nil && pb.post == nil && pb.dev != nil {
+		preB = &pep440Part{rank: -1}
+	}
+	if c := compareOptional(preA, preB, 1); c != 0 {
+		return c
+	}
+	if c := compareOptional(pa.post, pb.post, -1); c != 0 {
+		return c
+	}
+	if c := compareOptional(pa.dev, pb.dev, 1); c != 0 {
+		return c
+	}
+	return compareLocal(pa.local, pb.local)
+}
+
+// compareRelease compares dotted numbers, 1.0 being the same as 1.0.0
+func compareRelease(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := compareNumbers(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareOptional compares two parts, a missing one sorting after a
+// present one when missing is 1 and before it when missing is -1
+func compareOptional(a, b *pep440Part, missing int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return missing
+	case b == nil:
+		return -missing
+	}
+	if c := sign(a.rank - b.rank); c != 0 {
+		return c
+	}
+	return compareNumbers(a.number, b.number)
+}
+
+// compareLocal orders the +local labels: none first, then segment by
+// segment with numbers after words
+func compareLocal(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, bn := isDigits(a[i]), isDigits(b[i])
+		var c int
+		switch {
+		case an && bn:
+			c = compareNumbers(a[i], b[i])
+		case an:
+			c = 1
+		case bn:
+			c = -1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
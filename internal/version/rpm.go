@@ -0,0 +1,124 @@
+package version
+
+import "strings"
+
+// compareRPM orders epoch:version-release the way rpm does. A release is
+// only compared when both versions have one.
+func compareRPM(a, b string) int {
+	ae, av, ar := splitRPM(a)
+	be, bv, br := splitRPM(b)
+
+	if c := compareNumbers(ae, be); c != 0 {
+		return c
+	}
+	if c := rpmvercmp(av, bv); c != 0 {
+		return c
+	}
+	if ar == "" || br == "" {
+		return 0
+	}
+	return rpmvercmp(ar, br)
+}
+
+func splitRPM(v string) (epoch, version, release string) {
+	epoch = "0"
+	if i := strings.Index(v, ":"); i > 0 && isDigits(v[:i]) {
+		epoch, v = v[:i], v[i+1:]
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// rpmvercmp is rpm's segment comparison: runs of digits or letters compared
+// in turn, separators ignored, a number newer than letters, "~" older than
+// anything and "^" newer than the end of the version only
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for {
+		a = strings.TrimLeftFunc(a, rpmSeparator)
+		b = strings.TrimLeftFunc(b, rpmSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		numeric := isDigit(a[0])
+		segA, segB := leadingRun(a, numeric), leadingRun(b, numeric)
+		a, b = a[len(segA):], b[len(segB):]
+
+		// the segments are of different kinds: numbers are newer
+		if segB == "" {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if numeric {
+			c = compareNumbers(segA, segB)
+		} else {
+			c = strings.Compare(segA, segB)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func rpmSeparator(r rune) bool {
+	return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '~' || r == '^')
+}
+
+// leadingRun takes the digits, or the letters, s starts with
+func leadingRun(s string, digits bool) string {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if digits && !isDigit(c) || !digits && !letter {
+			break
+		}
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,165 @@
+package version
+
+import "strings"
+
+// scheme is how one package manager writes and orders its versions
+type scheme struct {
+	compare func(a, b string) int
+	parts   func(v string) parts
+}
+
+// parts are the pieces of a version that tell what kind of change it was
+type parts struct {
+	epoch    string
+	release  []string // the leading numbers: major, minor, patch...
+	upstream string   // the version the software's authors released
+	build    string   // what the packager added: revision, release, -rN
+}
+
+var schemes = map[string]scheme{
+	"dpkg": {Compare, dpkgParts},
+	"rpm":  {compareRPM, rpmParts},
+	"apk":  {compareAPK, apkParts},
+	"pip":  {comparePEP440, pep440Parts},
+	"npm":  {compareSemver, semverParts},
+	"go":   {compareSemver, semverParts},
+	"brew": {compareBrew, brewParts},
+}
+
+func schemeFor(manager string) scheme {
+	if s, ok := schemes[manager]; ok {
+		return s
+	}
+	return schemes["dpkg"]
+}
+
+// CompareFor orders two versions of a package from manager, returning -1, 0
+// or 1. Managers it doesn't know get dpkg's ordering.
+func CompareFor(manager, a, b string) int {
+	return schemeFor(manager).compare(a, b)
+}
+
+// Classify tells how a package changed going from one version to another.
+// direction is "upgrade" or "downgrade", "" when the two versions are the
+// same under the manager's ordering. bump is the part of the version that
+// changed: "major", "minor", "patch", or "rebuild" when only the
+// packaging did. It is "" when none of these fit, as from 2.0rc1 to 2.0.
+func Classify(manager, from, to string) (direction, bump string) {
+	s := schemeFor(manager)
+	switch s.compare(from, to) {
+	case -1:
+		direction = "upgrade"
+	case 1:
+		direction = "downgrade"
+	default:
+		return "", ""
+	}
+
+	a, b := s.parts(from), s.parts(to)
+	if compareNumbers(a.epoch, b.epoch) != 0 {
+		return direction, "major"
+	}
+	for i := 0; i < len(a.release) || i < len(b.release); i++ {
+		var x, y string
+		if i < len(a.release) {
+			x = a.release[i]
+		}
+		if i < len(b.release) {
+			y = b.release[i]
+		}
+		if compareNumbers(x, y) != 0 {
+			return direction, []string{"major", "minor", "patch"}[min(i, 2)]
+		}
+	}
+	if a.upstream == b.upstream && a.build != b.build {
+		return direction, "rebuild"
+	}
+	return direction, ""
+}
+
+// leadingNumbers reads the dotted numbers a version starts with, "1.1.1n"
+// giving 1, 1, 1
+func leadingNumbers(v string) []string {
+	var numbers []string
+	for _, part := range strings.Split(v, ".") {
+		digits := leadingDigits(part)
+		if digits == "" {
+			break
+		}
+		numbers = append(numbers, digits)
+		if digits != part {
+			break
+		}
+	}
+	return numbers
+}
+
+func dpkgParts(v string) parts {
+	epoch, upstream, revision := split(v)
+	return parts{epoch, leadingNumbers(upstream), upstream, revision}
+}
+
+func rpmParts(v string) parts {
+	epoch, version, release := splitRPM(v)
+	return parts{epoch, leadingNumbers(version), version, release}
+}
+
+func apkParts(v string) parts {
+	p := parseAPK(v)
+	upstream := v
+	if p.revision != "" {
+		upstream = v[:strings.LastIndex(v, "-r")]
+	}
+	return parts{"", p.numbers, upstream, p.revision}
+}
+
+// pep440Parts counts post-releases and local labels as packaging, the way
+// they are used for re-uploads and vendor builds
+func pep440Parts(v string) parts {
+	p, ok := parsePEP440(v)
+	if !ok {
+		return dpkgParts(v)
+	}
+	upstream := strings.Join(p.release, ".")
+	if p.pre != nil {
+		upstream += "pre" + string(rune('a'+p.pre.rank)) + p.pre.number
+	}
+	if p.dev != nil {
+		upstream += ".dev" + p.dev.number
+	}
+	var build string
+	if p.post != nil {
+		build = "post" + p.post.number
+	}
+	if p.local != nil {
+		build += "+" + strings.Join(p.local, ".")
+	}
+	return parts{p.epoch, p.release, upstream, build}
+}
+
+func semverParts(v string) parts {
+	core, pre, build := splitSemver(v)
+	return parts{"", strings.Split(core, "."), core + "-" + pre, build}
+}
+
+// splitBrew takes the _N revision off a Homebrew version
+func splitBrew(v string) (upstream, revision string) {
+	if i := strings.LastIndex(v, "_"); i >= 0 && isDigits(v[i+1:]) {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+func compareBrew(a, b string) int {
+	au, ar := splitBrew(a)
+	bu, br := splitBrew(b)
+	if c := compareParts(au, bu); c != 0 {
+		return c
+	}
+	return compareNumbers(ar, br)
+}
+
+func brewParts(v string) parts {
+	upstream, revision := splitBrew(v)
+	return parts{"", leadingNumbers(upstream), upstream, revision}
+}
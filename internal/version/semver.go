@@ -0,0 +1,54 @@
+package version
+
+import "strings"
+
+// splitSemver takes v1.2.3-rc.1+build apart. The leading "v" of Go module
+// versions is optional.
+func splitSemver(v string) (core, pre, build string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, build, _ = strings.Cut(v, "+")
+	core, pre, _ = strings.Cut(v, "-")
+	return core, pre, build
+}
+
+// compareSemver orders versions by semantic versioning: the numbers, then a
+// pre-release before the release itself. Build metadata doesn't count.
+func compareSemver(a, b string) int {
+	ac, ap, _ := splitSemver(a)
+	bc, bp, _ := splitSemver(b)
+
+	if c := compareRelease(strings.Split(ac, "."), strings.Split(bc, ".")); c != 0 {
+		return c
+	}
+
+	switch {
+	case ap == bp:
+		return 0
+	case ap == "":
+		return 1
+	case bp == "":
+		return -1
+	}
+
+	// pre-release identifiers: numbers compare as numbers and before words,
+	// and a shorter list that matches so far comes first
+	ai, bi := strings.Split(ap, "."), strings.Split(bp, ".")
+	for i := 0; i < len(ai) && i < len(bi); i++ {
+		an, bn := isDigits(ai[i]), isDigits(bi[i])
+		var c int
+		switch {
+		case an && bn:
+			c = compareNumbers(ai[i], bi[i])
+		case an:
+			c = -1
+		case bn:
+			c = 1
+		default:
+			c = strings.Compare(ai[i], bi[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return sign(len(ai) - len(bi))
+}
@@ -0,0 +1,181 @@
+// Package version orders package versions and checks them against
+// constraints such as ">= 1.24".
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Compare orders two versions the way dpkg does, returning -1, 0 or 1.
+// Versions are [epoch:]upstream[-revision]; "~" sorts before anything, even
+// the end of the version, so 1.0~rc1 comes before 1.0. CompareFor picks the
+// ordering of other package managers.
+func Compare(a, b string) int {
+	ae, au, ar := split(a)
+	be, bu, br := split(b)
+
+	if c := compareNumbers(ae, be); c != 0 {
+		return c
+	}
+	if c := compareParts(au, bu); c != 0 {
+		return c
+	}
+	return compareParts(ar, br)
+}
+
+// split breaks a version into epoch, upstream version and revision
+func split(v string) (epoch, upstream, revision string) {
+	epoch = "0"
+	if i := strings.Index(v, ":"); i > 0 && isDigits(v[:i]) {
+		epoch, v = v[:i], v[i+1:]
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// compareParts walks a and b alternating between non-digit runs, compared
+// character by character, and digit runs, compared as numbers
+func compareParts(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := order(a), order(b)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		an, bn := leadingDigits(a), leadingDigits(b)
+		if c := compareNumbers(an, bn); c != 0 {
+			return c
+		}
+		a, b = a[len(an):], b[len(bn):]
+	}
+	return 0
+}
+
+// order weighs the first character of s: "~" before the end of the string,
+// the end and digits before letters, letters before everything else
+func order(s string) int {
+	if s == "" {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return sign(len(a) - len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isDigits(s string) bool {
+	return s != "" && leadingDigits(s) == s
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a list of comparisons a version has to satisfy, written as
+// ">= 1.24, < 2". A bare version means "=".
+type Constraint struct {
+	text  string
+	terms []term
+}
+
+type term struct {
+	op      string
+	version string
+}
+
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseConstraint reads a constraint
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		op := "="
+		for _, o := range operators {
+			if rest, ok := strings.CutPrefix(part, o); ok {
+				op, part = o, strings.TrimSpace(rest)
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+		if part == "" || strings.ContainsAny(part, " <>=!") {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q", s)
+		}
+		c.terms = append(c.terms, term{op, part})
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies every comparison
+func (c Constraint) Check(v string) bool {
+	return c.CheckFor("dpkg", v)
+}
+
+// CheckFor is Check with the version ordering of a package manager
+func (c Constraint) CheckFor(manager, v string) bool {
+	for _, t := range c.terms {
+		r := CompareFor(manager, v, t.version)
+		var ok bool
+		switch t.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	return c.text
+}